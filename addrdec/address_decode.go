@@ -1,7 +1,10 @@
 package addrdec
 
 import (
+	"fmt"
+
 	"github.com/blocktree/go-owcdrivers/addressEncoder"
+	"github.com/blocktree/openwallet/openwallet"
 )
 
 const (
@@ -9,26 +12,45 @@ const (
 )
 
 var (
-	VLX_mainnetAddressP2PKH         = addressEncoder.AddressType{EncodeType: "base58", Alphabet: btcAlphabet, ChecksumType: "doubleSHA256", HashType: "h160", HashLen: 20, Prefix: []byte{15, 244}, Suffix: nil}
-	VLX_testnetAddressP2PKH         = addressEncoder.AddressType{EncodeType: "base58", Alphabet: btcAlphabet, ChecksumType: "doubleSHA256", HashType: "h160", HashLen: 20, Prefix: []byte{15, 244}, Suffix: nil}
-	VLX_mainnetPrivateWIFCompressed = addressEncoder.AddressType{EncodeType: "base58", Alphabet: btcAlphabet, ChecksumType: "doubleSHA256", HashType: "", HashLen: 32, Prefix: []byte{}, Suffix: nil}
-	VLX_testnetPrivateWIFCompressed = addressEncoder.AddressType{EncodeType: "base58", Alphabet: btcAlphabet, ChecksumType: "doubleSHA256", HashType: "", HashLen: 32, Prefix: []byte{}, Suffix: nil}
+	VLX_mainnetAddressP2PKH         = MainNetParams.AddressType()
+	VLX_testnetAddressP2PKH         = TestNetParams.AddressType()
+	VLX_devnetAddressP2PKH          = DevNetParams.AddressType()
+	VLX_mainnetPrivateWIFCompressed = MainNetParams.WIFType()
+	VLX_testnetPrivateWIFCompressed = TestNetParams.WIFType()
+	VLX_devnetPrivateWIFCompressed  = DevNetParams.WIFType()
 
-	Default = AddressDecoderV2{}
+	//Default 主网地址解析器
+	Default = NewAddressDecoderV2(&MainNetParams)
 )
 
-//AddressDecoderV2
+//AddressDecoderV2 地址解析器，网络参数在创建时确定且不可修改，可并发使用
 type AddressDecoderV2 struct {
-	IsTestNet bool
+	openwallet.AddressDecoderV2Base
+	params ChainParams
+}
+
+//NewAddressDecoderV2 创建指定网络的地址解析器，网络参数会被复制
+func NewAddressDecoderV2(params *ChainParams) *AddressDecoderV2 {
+	dec := AddressDecoderV2{params: copyChainParams(params)}
+	return &dec
+}
+
+//ChainParams 网络参数的副本
+func (dec *AddressDecoderV2) ChainParams() ChainParams {
+	return copyChainParams(&dec.params)
+}
+
+func copyChainParams(params *ChainParams) ChainParams {
+	p := *params
+	p.AddressPrefix = append([]byte{}, params.AddressPrefix...)
+	p.WIFPrefix = append([]byte{}, params.WIFPrefix...)
+	return p
 }
 
 //AddressDecode 地址解析
 func (dec *AddressDecoderV2) AddressDecode(addr string, opts ...interface{}) ([]byte, error) {
 
-	cfg := VLX_mainnetAddressP2PKH
-	if dec.IsTestNet {
-		cfg = VLX_testnetAddressP2PKH
-	}
+	cfg := dec.params.AddressType()
 
 	if len(opts) > 0 {
 		for _, opt := range opts {
@@ -44,10 +66,7 @@ func (dec *AddressDecoderV2) AddressDecode(addr string, opts ...interface{}) ([]
 //AddressEncode 地址编码
 func (dec *AddressDecoderV2) AddressEncode(hash []byte, opts ...interface{}) (string, error) {
 
-	cfg := VLX_mainnetAddressP2PKH
-	if dec.IsTestNet {
-		cfg = VLX_testnetAddressP2PKH
-	}
+	cfg := dec.params.AddressType()
 
 	if len(opts) > 0 {
 		for _, opt := range opts {
@@ -60,3 +79,23 @@ func (dec *AddressDecoderV2) AddressEncode(hash []byte, opts ...interface{}) (st
 	address := addressEncoder.AddressEncode(hash, cfg)
 	return address, nil
 }
+
+//PrivateKeyToWIF 私钥转WIF，使用解析器的网络参数
+func (dec *AddressDecoderV2) PrivateKeyToWIF(priv []byte, isTestnet bool) (string, error) {
+	return addressEncoder.AddressEncode(priv, dec.params.WIFType()), nil
+}
+
+//PublicKeyToAddress 公钥转地址，使用解析器的网络参数
+func (dec *AddressDecoderV2) PublicKeyToAddress(pub []byte, isTestnet bool) (string, error) {
+	return dec.AddressEncode(pub)
+}
+
+//WIFToPrivateKey WIF转私钥，使用解析器的网络参数
+func (dec *AddressDecoderV2) WIFToPrivateKey(wif string, isTestnet bool) ([]byte, error) {
+	return addressEncoder.AddressDecode(wif, dec.params.WIFType())
+}
+
+//RedeemScriptToAddress 多重签名赎回脚本转地址
+func (dec *AddressDecoderV2) RedeemScriptToAddress(pubs [][]byte, required uint64, isTestnet bool) (string, error) {
+	return "", fmt.Errorf("RedeemScriptToAddress is not supported")
+}
@@ -0,0 +1,80 @@
+package addrdec
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+//旧版地址为ed25519公钥的HASH160，0x地址为secp256k1公钥的keccak哈希，两者不能互相转换，
+//转换后的地址没有对应的私钥，转入的资金无法花费
+
+const (
+	FormatBase58 = "base58" //V开头的旧版地址
+	FormatHex    = "hex"    //0x开头的EVM地址
+
+	hexAddressLen = 20
+)
+
+//DetectAddressFormat 根据地址外观判断格式，不做校验，无法识别时返回空
+func DetectAddressFormat(addr string) string {
+	if strings.HasPrefix(addr, "0x") || strings.HasPrefix(addr, "0X") {
+		return FormatHex
+	}
+	if len(addr) > 0 {
+		return FormatBase58
+	}
+	return ""
+}
+
+//ValidateHexAddress 校验0x地址，全小写或全大写时不校验大小写，混合大小写时按EIP-55校验，返回20字节哈希
+func ValidateHexAddress(addr string) ([]byte, error) {
+
+	if len(strings.TrimSpace(addr)) == 0 {
+		return nil, &AddressError{Address: addr, Reason: ReasonEmpty, Detail: "address is empty"}
+	}
+
+	if DetectAddressFormat(addr) != FormatHex {
+		return nil, &AddressError{Address: addr, Reason: ReasonInvalidPrefix, Detail: "hex address must start with 0x"}
+	}
+
+	body := addr[2:]
+	if len(body) != hexAddressLen*2 {
+		return nil, &AddressError{Address: addr, Reason: ReasonInvalidLength, Detail: fmt.Sprintf("hex length is %d, want %d", len(body), hexAddressLen*2)}
+	}
+
+	hash, err := hex.DecodeString(body)
+	if err != nil {
+		return nil, &AddressError{Address: addr, Reason: ReasonInvalidCharacter, Detail: "address is not hex encoded"}
+	}
+
+	if body != strings.ToLower(body) && body != strings.ToUpper(body) && addr != ChecksumHexAddress(hash) {
+		return nil, &AddressError{Address: addr, Reason: ReasonInvalidChecksum, Detail: "EIP-55 checksum mismatch"}
+	}
+
+	return hash, nil
+}
+
+//ChecksumHexAddress 20字节哈希编码为EIP-55大小写校验的0x地址
+func ChecksumHexAddress(hash []byte) string {
+	lower := hex.EncodeToString(hash)
+
+	sha := sha3.NewLegacyKeccak256()
+	sha.Write([]byte(lower))
+	digest := sha.Sum(nil)
+
+	result := []byte(lower)
+	for i := range result {
+		//哈希对应半字节大于等于8时，字母大写
+		nibble := digest[i/2]
+		if i%2 == 0 {
+			nibble = nibble >> 4
+		}
+		if result[i] > '9' && nibble&0x0f >= 8 {
+			result[i] -= 'a' - 'A'
+		}
+	}
+	return "0x" + string(result)
+}
@@ -0,0 +1,115 @@
+package addrdec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+)
+
+const (
+	NetworkMainnet = "mainnet"
+	NetworkTestnet = "testnet"
+	NetworkDevnet  = "devnet"
+
+	PayloadTypeP2PKH = "p2pkh"
+
+	checksumLen = 4
+)
+
+//AddressErrorReason 地址无效的原因
+type AddressErrorReason string
+
+const (
+	ReasonEmpty            AddressErrorReason = "empty"             //地址为空
+	ReasonInvalidCharacter AddressErrorReason = "invalid_character" //包含base58字母表以外的字符
+	ReasonInvalidLength    AddressErrorReason = "invalid_length"    //解码后长度错误
+	ReasonInvalidPrefix    AddressErrorReason = "invalid_prefix"    //前缀不是当前网络的地址前缀
+	ReasonInvalidChecksum  AddressErrorReason = "invalid_checksum"  //校验和错误
+	ReasonWrongFormat      AddressErrorReason = "wrong_format"      //0x格式的EVM地址，不能作为旧版地址使用
+)
+
+//AddressError 地址校验错误
+type AddressError struct {
+	Address string
+	Reason  AddressErrorReason
+	Detail  string
+}
+
+func (e *AddressError) Error() string {
+	return fmt.Sprintf("invalid address %q: %s, %s", e.Address, e.Reason, e.Detail)
+}
+
+//AddressInfo 地址校验结果，内置网络的地址编码相同，无法从地址判断所属网络
+type AddressInfo struct {
+	Address     string
+	PayloadType string //地址类型
+	Hash        []byte //公钥哈希
+}
+
+//ValidateAddress 校验地址的前缀、长度及校验和，返回地址类型；无效时返回*AddressError，Reason给出具体原因
+//主网、测试网及开发网的地址编码相同，其他网络的地址同样有效，只有前缀不同的私有链地址返回ReasonInvalidPrefix
+func (dec *AddressDecoderV2) ValidateAddress(addr string) (*AddressInfo, error) {
+
+	if len(strings.TrimSpace(addr)) == 0 {
+		return nil, &AddressError{Address: addr, Reason: ReasonEmpty, Detail: "address is empty"}
+	}
+
+	if DetectAddressFormat(addr) == FormatHex {
+		return nil, &AddressError{Address: addr, Reason: ReasonWrongFormat, Detail: "0x address is an EVM address, use a V address of the legacy chain"}
+	}
+
+	for i, c := range addr {
+		if !strings.ContainsRune(btcAlphabet, c) {
+			return nil, &AddressError{Address: addr, Reason: ReasonInvalidCharacter, Detail: fmt.Sprintf("character %q at %d is not in base58 alphabet", c, i)}
+		}
+	}
+
+	data := base58.Decode(addr)
+
+	cfg := dec.params.AddressType()
+	expectLen := len(cfg.Prefix) + int(cfg.HashLen) + checksumLen
+	if len(data) != expectLen {
+		return nil, &AddressError{Address: addr, Reason: ReasonInvalidLength, Detail: fmt.Sprintf("decoded length is %d, want %d", len(data), expectLen)}
+	}
+
+	if !bytes.HasPrefix(data, cfg.Prefix) {
+		return nil, &AddressError{Address: addr, Reason: ReasonInvalidPrefix, Detail: fmt.Sprintf("prefix %x is not the address prefix %x of %s", data[:len(cfg.Prefix)], cfg.Prefix, dec.params.Name)}
+	}
+
+	payload, checksum := data[:len(data)-checksumLen], data[len(data)-checksumLen:]
+	if !bytes.Equal(doubleSHA256(payload)[:checksumLen], checksum) {
+		return nil, &AddressError{Address: addr, Reason: ReasonInvalidChecksum, Detail: "checksum mismatch"}
+	}
+
+	info := &AddressInfo{
+		Address:     addr,
+		PayloadType: PayloadTypeP2PKH,
+		Hash:        payload[len(cfg.Prefix):],
+	}
+
+	return info, nil
+}
+
+//AddressVerify 地址校验，地址有效且属于当前网络时返回true
+func (dec *AddressDecoderV2) AddressVerify(address string, opts ...interface{}) bool {
+	_, err := dec.ValidateAddress(address)
+	return err == nil
+}
+
+//IsAddressError 判断错误是否为地址校验错误，并返回原因
+func IsAddressError(err error) (AddressErrorReason, bool) {
+	e, ok := err.(*AddressError)
+	if !ok {
+		return "", false
+	}
+	return e.Reason, true
+}
+
+func doubleSHA256(data []byte) []byte {
+	h := sha256.Sum256(data)
+	h = sha256.Sum256(h[:])
+	return h[:]
+}
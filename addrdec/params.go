@@ -0,0 +1,98 @@
+package addrdec
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/blocktree/go-owcdrivers/addressEncoder"
+)
+
+//ChainParams 网络参数
+type ChainParams struct {
+	Name          string //网络名称
+	Symbol        string //币种
+	Decimals      int32  //精度
+	DefaultFee    string //默认固定手续费
+	NodeURL       string //默认节点地址
+	AddressPrefix []byte //地址前缀
+	WIFPrefix     []byte //WIF私钥前缀
+}
+
+var (
+	//MainNetParams 主网参数，WIF前缀为空以兼容已导出的私钥
+	MainNetParams = ChainParams{
+		Name:          NetworkMainnet,
+		Symbol:        "VLX",
+		Decimals:      8,
+		DefaultFee:    "0.001",
+		NodeURL:       "https://mainnet.velas.website",
+		AddressPrefix: []byte{15, 244},
+		WIFPrefix:     []byte{},
+	}
+
+	//TestNetParams 测试网参数，测试网节点与主网使用相同的地址及WIF编码，无法从地址区分网络，构建交易单时不能拒绝主网地址
+	TestNetParams = ChainParams{
+		Name:          NetworkTestnet,
+		Symbol:        "VLX",
+		Decimals:      8,
+		DefaultFee:    "0.001",
+		NodeURL:       "https://testnet.velas.website",
+		AddressPrefix: []byte{15, 244},
+		WIFPrefix:     []byte{},
+	}
+
+	//DevNetParams 开发网参数，编码与主网相同，无法从地址区分网络
+	DevNetParams = ChainParams{
+		Name:          NetworkDevnet,
+		Symbol:        "VLX",
+		Decimals:      8,
+		DefaultFee:    "0.001",
+		NodeURL:       "http://127.0.0.1:1005",
+		AddressPrefix: []byte{15, 244},
+		WIFPrefix:     []byte{},
+	}
+
+	//Networks 已知网络
+	Networks = []*ChainParams{&MainNetParams, &TestNetParams, &DevNetParams}
+)
+
+//ParamsByName 根据网络名称获取网络参数
+func ParamsByName(name string) (*ChainParams, error) {
+	for _, params := range Networks {
+		if strings.EqualFold(params.Name, name) {
+			return params, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown network: %s", name)
+}
+
+//WithPrefix 返回使用hex编码的地址前缀及WIF前缀的网络参数副本，为空时保留原前缀，用于编码与主网不同的私有链
+func (p *ChainParams) WithPrefix(addressPrefix, wifPrefix string) (*ChainParams, error) {
+	params := copyChainParams(p)
+	if len(addressPrefix) > 0 {
+		prefix, err := hex.DecodeString(addressPrefix)
+		if err != nil || len(prefix) == 0 {
+			return nil, fmt.Errorf("invalid address prefix: %s", addressPrefix)
+		}
+		params.AddressPrefix = prefix
+	}
+	if len(wifPrefix) > 0 {
+		prefix, err := hex.DecodeString(wifPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid wif prefix: %s", wifPrefix)
+		}
+		params.WIFPrefix = prefix
+	}
+	return &params, nil
+}
+
+//AddressType 地址编码参数
+func (p *ChainParams) AddressType() addressEncoder.AddressType {
+	return addressEncoder.AddressType{EncodeType: "base58", Alphabet: btcAlphabet, ChecksumType: "doubleSHA256", HashType: "h160", HashLen: 20, Prefix: p.AddressPrefix, Suffix: nil}
+}
+
+//WIFType WIF私钥编码参数
+func (p *ChainParams) WIFType() addressEncoder.AddressType {
+	return addressEncoder.AddressType{EncodeType: "base58", Alphabet: btcAlphabet, ChecksumType: "doubleSHA256", HashType: "", HashLen: 32, Prefix: p.WIFPrefix, Suffix: nil}
+}
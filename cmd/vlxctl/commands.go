@@ -0,0 +1,368 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/assetsadapterstore/velas-adapter/rpc"
+	"github.com/assetsadapterstore/velas-adapter/velas"
+	"github.com/blocktree/openwallet/common"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+//批量查询交易的数量
+const historyBatchSize = 100
+
+//command 子命令
+type command struct {
+	args  int
+	usage string
+	run   func(args []string) (*result, error)
+}
+
+//controller 子命令的执行者
+type controller struct {
+	client *rpc.Client
+	stdin  io.Reader
+}
+
+func newController(server string, stdin io.Reader) *controller {
+	return &controller{client: rpc.NewClient(strings.TrimSuffix(server, "/")), stdin: stdin}
+}
+
+func (ctl *controller) commands() map[string]*command {
+	return map[string]*command{
+		"info":     {0, "", ctl.info},
+		"block":    {1, "<height|hash>", ctl.block},
+		"tx":       {1, "<hash>", ctl.tx},
+		"balance":  {1, "<address>", ctl.balance},
+		"unspent":  {1, "<address>", ctl.unspent},
+		"history":  {1, "<address>", ctl.history},
+		"decode":   {1, "<rawhex|->", ctl.decode},
+		"validate": {1, "<rawhex|->", ctl.validate},
+		"publish":  {1, "<rawhex|->", ctl.publish},
+		"reserves": {1, "<report.json|->", ctl.reserves},
+	}
+}
+
+func (ctl *controller) info(args []string) (*result, error) {
+	node, err := ctl.client.NodeInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	r := newResult(node, "FIELD", "VALUE")
+	if node.P2PInfo != nil {
+		r.add("id", node.P2PInfo.ID)
+		r.add("name", node.P2PInfo.Name)
+		r.add("addr", node.P2PInfo.Addr)
+	}
+	if node.Blockchain != nil {
+		r.add("height", strconv.Itoa(node.Blockchain.Height))
+		r.add("current hash", node.Blockchain.CurrentHash)
+		r.add("current epoch", node.Blockchain.CurrentEpoch)
+	}
+	r.add("synced", strconv.FormatBool(node.IsSync))
+	if p := node.Progress; p != nil {
+		progress := "100.00%"
+		if p.HighestBlock > 0 {
+			progress = fmt.Sprintf("%.2f%%", float64(p.CurrentBlock)*100/float64(p.HighestBlock))
+		}
+		r.add("sync progress", fmt.Sprintf("%s (%d/%d)", progress, p.CurrentBlock, p.HighestBlock))
+	}
+	r.add("peers", strconv.Itoa(len(node.P2PPeers)))
+	for _, peer := range node.P2PPeers {
+		r.add("peer", fmt.Sprintf("%s %s %s", peer.ID, peer.Name, peer.Addr))
+	}
+	return r, nil
+}
+
+func (ctl *controller) block(args []string) (*result, error) {
+	var (
+		block *rpc.BlockResponse
+		err   error
+	)
+	if height, parseErr := strconv.ParseUint(args[0], 10, 32); parseErr == nil {
+		block, err = ctl.client.Block.GetByHeight(uint32(height))
+	} else {
+		block, err = ctl.client.Block.GetByHash(args[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+	if block.Header == nil {
+		return nil, fmt.Errorf("block %s not found", args[0])
+	}
+
+	h := block.Header
+	r := newResult(block, "FIELD", "VALUE")
+	r.add("hash", h.Hash)
+	r.add("height", strconv.FormatUint(uint64(h.Height), 10))
+	r.add("prev block", h.PrevBlock)
+	r.add("merkle root", h.MerkleRoot)
+	if err := velas.VerifyBlock(block, uint64(h.Height), ""); err != nil {
+		r.add("verified", err.Error())
+	} else {
+		r.add("verified", "true")
+	}
+	r.add("time", formatTime(h.Timestamp))
+	r.add("size", strconv.FormatUint(h.Size, 10))
+	r.add("txs", strconv.Itoa(len(block.Transactions)))
+	for _, trx := range block.Transactions {
+		r.add("tx", hex.EncodeToString(trx.Hash[:]))
+	}
+	return r, nil
+}
+
+func (ctl *controller) tx(args []string) (*result, error) {
+	txs, err := ctl.client.Tx.GetByHashList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(txs) == 0 || txs[0].Tx == nil {
+		return nil, fmt.Errorf("transaction %s not found", args[0])
+	}
+
+	tx := txs[0]
+	r := newResult(tx, "FIELD", "VALUE")
+	r.add("hash", hex.EncodeToString(tx.Hash[:]))
+	r.add("block", tx.Block)
+	r.add("confirmed", strconv.FormatUint(uint64(tx.Confirmed), 10))
+	r.add("time", formatTime(tx.ConfirmedTimestamp))
+	r.add("size", strconv.FormatUint(uint64(tx.Size), 10))
+	addTxRows(r, tx.Tx)
+	return r, nil
+}
+
+func (ctl *controller) balance(args []string) (*result, error) {
+	amount, err := ctl.client.Wallet.GetBalance(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	balance := struct {
+		Address string `json:"address"`
+		Amount  uint64 `json:"amount"`
+		Balance string `json:"balance"`
+	}{args[0], amount, formatAmount(amount)}
+
+	r := newResult(balance, "ADDRESS", "BALANCE")
+	r.add(balance.Address, balance.Balance+" "+velas.Symbol)
+	return r, nil
+}
+
+func (ctl *controller) unspent(args []string) (*result, error) {
+	unspents, err := ctl.client.Wallet.GetUnspent(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	r := newResult(unspents, "HASH", "INDEX", "AMOUNT")
+	total := uint64(0)
+	for _, u := range unspents {
+		total += u.Value
+		r.add(hex.EncodeToString(u.Hash[:]), strconv.FormatUint(uint64(u.Index), 10), formatAmount(u.Value))
+	}
+	r.add("total", strconv.Itoa(len(unspents)), formatAmount(total))
+	return r, nil
+}
+
+func (ctl *controller) history(args []string) (*result, error) {
+	hashes, err := ctl.client.Tx.GetHashListByAddress(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	txs := make([]rpc.TxResponse, 0, len(hashes))
+	for i := 0; i < len(hashes); i += historyBatchSize {
+		end := i + historyBatchSize
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+		batch, err := ctl.client.Tx.GetByHashList(hashes[i:end]...)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, batch...)
+	}
+
+	address := base58.Decode(args[0])
+	r := newResult(txs, "HASH", "TIME", "CONFIRMED", "CHANGE")
+	for _, tx := range txs {
+		if tx.Tx == nil {
+			continue
+		}
+		r.add(hex.EncodeToString(tx.Hash[:]), formatTime(tx.ConfirmedTimestamp), strconv.FormatUint(uint64(tx.Confirmed), 10), addressChange(tx.Tx, address))
+	}
+	return r, nil
+}
+
+func (ctl *controller) decode(args []string) (*result, error) {
+	trx, err := ctl.readTx(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	r := newResult(trx, "FIELD", "VALUE")
+	r.add("hash", hex.EncodeToString(trx.Hash[:]))
+	r.add("hash valid", strconv.FormatBool(trx.GenerateHash() == trx.Hash))
+	r.add("size", strconv.Itoa(trx.Size()))
+	addTxRows(r, trx)
+	return r, nil
+}
+
+func (ctl *controller) validate(args []string) (*result, error) {
+	trx, err := ctl.readTx(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctl.client.Tx.Validate(*trx)
+	if err != nil {
+		return nil, fmt.Errorf("transaction is invalid: %v", err)
+	}
+
+	r := newResult(map[string]interface{}{"hash": hex.EncodeToString(trx.Hash[:]), "valid": true}, "HASH", "VALID")
+	r.add(hex.EncodeToString(trx.Hash[:]), "true")
+	return r, nil
+}
+
+func (ctl *controller) publish(args []string) (*result, error) {
+	trx, err := ctl.readTx(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := ctl.client.Tx.Publish(*trx)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newResult(map[string]interface{}{"hash": hex.EncodeToString(trx.Hash[:]), "result": resp.Result}, "HASH", "RESULT")
+	r.add(hex.EncodeToString(trx.Hash[:]), resp.Result)
+	return r, nil
+}
+
+//reserves 验证储备证明报告的签名及金额，并向节点核对区块及未花
+func (ctl *controller) reserves(args []string) (*result, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if args[0] == "-" {
+		data, err = ioutil.ReadAll(ctl.stdin)
+	} else {
+		data, err = ioutil.ReadFile(args[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var report velas.ReserveReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("invalid report: %v", err)
+	}
+
+	wm := velas.NewWalletManager()
+	wm.WalletClient = ctl.client
+	if err := wm.CheckReserveReport(&report); err != nil {
+		return nil, fmt.Errorf("report is invalid: %v", err)
+	}
+
+	r := newResult(map[string]interface{}{"blockHeight": report.BlockHeight, "blockHash": report.BlockHash, "total": report.Total, "valid": true}, "ADDRESS", "BALANCE")
+	for _, a := range report.Addresses {
+		r.add(a.Address, a.Balance+" "+velas.Symbol)
+	}
+	r.add("block", fmt.Sprintf("%d %s", report.BlockHeight, report.BlockHash))
+	r.add("total", report.Total+" "+velas.Symbol)
+	return r, nil
+}
+
+//readTx 解析交易单，支持hex编码的JSON或直接的JSON，"-"时从标准输入读取
+func (ctl *controller) readTx(arg string) (*crypto.Tx, error) {
+	raw := []byte(arg)
+	if arg == "-" {
+		data, err := ioutil.ReadAll(ctl.stdin)
+		if err != nil {
+			return nil, err
+		}
+		raw = data
+	}
+
+	raw = []byte(strings.TrimSpace(string(raw)))
+	if !strings.HasPrefix(string(raw), "{") {
+		decoded, err := hex.DecodeString(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid raw hex: %v", err)
+		}
+		raw = decoded
+	}
+
+	var trx crypto.Tx
+	if err := json.Unmarshal(raw, &trx); err != nil {
+		return nil, fmt.Errorf("invalid transaction: %v", err)
+	}
+	return &trx, nil
+}
+
+func addTxRows(r *result, trx *crypto.Tx) {
+	for i, in := range trx.Inputs {
+		r.add(fmt.Sprintf("input %d", i), fmt.Sprintf("%s:%d %s %s", hex.EncodeToString(in.PreviousOutput.Hash[:]), in.PreviousOutput.Index, base58.Encode(in.WalletAddress), formatAmount(in.PreviousOutput.Value)))
+	}
+	for _, out := range trx.Outputs {
+		to := "commission"
+		if len(out.Script) > 0 {
+			to = base58.Encode(out.Script)
+		}
+		r.add(fmt.Sprintf("output %d", out.Index), fmt.Sprintf("%s %s", to, formatAmount(out.Value)))
+	}
+}
+
+//addressChange 交易对地址余额的影响
+func addressChange(trx *crypto.Tx, address []byte) string {
+	change := int64(0)
+	for _, in := range trx.Inputs {
+		if string(in.WalletAddress) == string(address) {
+			change -= int64(in.PreviousOutput.Value)
+		}
+	}
+	for _, out := range trx.Outputs {
+		if string(out.Script) == string(address) {
+			change += int64(out.Value)
+		}
+	}
+	return common.IntToDecimals(change, velas.Decimals).String()
+}
+
+func formatAmount(value uint64) string {
+	return common.IntToDecimals(int64(value), velas.Decimals).StringFixed(velas.Decimals)
+}
+
+func formatTime(timestamp uint32) string {
+	if timestamp == 0 {
+		return ""
+	}
+	return time.Unix(int64(timestamp), 0).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+//vlxctl 查询Velas节点及操作交易单的命令行工具
+//
+//用法：
+//
+//	vlxctl [-server url] [-output json|table] <command> [args]
+//
+//命令：
+//
+//	info                     节点信息、同步进度及连接节点
+//	block <height|hash>      按高度或哈希查询区块
+//	tx <hash>                按哈希查询交易
+//	balance <address>        地址余额
+//	unspent <address>        地址未花列表
+//	history <address>        地址交易记录
+//	decode <rawhex>          解析交易单（crypto.Tx的JSON再hex编码）
+//	validate <rawhex>        节点验证已签名交易单
+//	publish <rawhex>         广播已签名交易单
+//	reserves <report.json>   验证储备证明报告（velas.ReserveReport）
+//
+//rawhex及report.json为"-"时从标准输入读取
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const defaultServer = "http://127.0.0.1:1005"
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout)
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+
+	fs := flag.NewFlagSet("vlxctl", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	server := fs.String("server", envOrDefault("VLX_SERVER", defaultServer), "node api url, or env VLX_SERVER")
+	output := fs.String("output", "table", "output format: json or table")
+	fs.Usage = func() {
+		fmt.Fprintln(stdout, "usage: vlxctl [-server url] [-output json|table] <command> [args]")
+		fmt.Fprintln(stdout, "commands: info, block, tx, balance, unspent, history, decode, validate, publish, reserves")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("command is required")
+	}
+
+	printer, err := newPrinter(*output, stdout)
+	if err != nil {
+		return err
+	}
+
+	ctl := newController(*server, stdin)
+	name, cmdArgs := fs.Arg(0), fs.Args()[1:]
+	cmd, ok := ctl.commands()[name]
+	if !ok {
+		fs.Usage()
+		return fmt.Errorf("unknown command: %s", name)
+	}
+
+	if len(cmdArgs) != cmd.args {
+		return fmt.Errorf("usage: vlxctl %s %s", name, cmd.usage)
+	}
+
+	result, err := cmd.run(cmdArgs)
+	if err != nil {
+		return err
+	}
+
+	return printer.print(result)
+}
+
+func envOrDefault(key, value string) string {
+	if v := os.Getenv(key); len(v) > 0 {
+		return v
+	}
+	return value
+}
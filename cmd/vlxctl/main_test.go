@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+)
+
+func TestRun(t *testing.T) {
+
+	addr := "VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/info":
+			w.Write([]byte(`{"p2p_info":{"id":"node1"},"p2p_peers":[{"id":"peer1"}],"blockchain":{"height":100},"is_sync":false,"progress":{"current_block":50,"highest_block":200}}`))
+		case "/api/v1/wallet/balance/" + addr:
+			w.Write([]byte(`{"amount":150000000}`))
+		case "/api/v1/wallet/unspent/" + addr:
+			json.NewEncoder(w).Encode([]*crypto.TransactionInputOutpoint{{Hash: [32]byte{1}, Index: 2, Value: 100}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"status":"not found","error":"not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	trx := &crypto.Tx{Version: 1, Outputs: []crypto.TransactionOutput{{Index: 0, Value: 1000}}}
+	trx.Hash = trx.GenerateHash()
+	txJSON, _ := trx.MarshalJSON()
+
+	tests := []struct {
+		args  []string
+		stdin string
+		want  []string
+	}{
+		{[]string{"info"}, "", []string{"node1", "25.00% (50/200)", "peer1"}},
+		{[]string{"-output", "json", "balance", addr}, "", []string{`"balance": "1.50000000"`}},
+		{[]string{"balance", addr}, "", []string{"1.50000000 VLX"}},
+		{[]string{"unspent", addr}, "", []string{"0.00000100"}},
+		{[]string{"decode", hex.EncodeToString(txJSON)}, "", []string{"hash valid  true", "commission 0.00001000"}},
+		{[]string{"decode", "-"}, string(txJSON), []string{"hash valid  true"}},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := run(append([]string{"-server", server.URL}, tt.args...), strings.NewReader(tt.stdin), &out)
+		if err != nil {
+			t.Errorf("run(%v) unexpected error = %v", tt.args, err)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("run(%v) output does not contain %q:\n%s", tt.args, want, out.String())
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	if err := run([]string{"-server", server.URL, "tx", "00"}, nil, &out); err == nil {
+		t.Errorf("run(tx) want error for unknown transaction")
+	}
+
+	report := `{"network":"mainnet","blockHeight":1,"blockHash":"block1","challenge":"audit","message":"audit","total":"0.00000000"}`
+	if err := run([]string{"-server", server.URL, "reserves", "-"}, strings.NewReader(report), &out); err == nil || !strings.Contains(err.Error(), "message") {
+		t.Errorf("run(reserves) error = %v, want message mismatch", err)
+	}
+}
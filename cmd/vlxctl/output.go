@@ -0,0 +1,74 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	outputJSON  = "json"
+	outputTable = "table"
+)
+
+//result 命令结果，data用于JSON输出，header及rows用于表格输出
+type result struct {
+	data   interface{}
+	header []string
+	rows   [][]string
+}
+
+func newResult(data interface{}, header ...string) *result {
+	return &result{data: data, header: header}
+}
+
+func (r *result) add(cols ...string) {
+	r.rows = append(r.rows, cols)
+}
+
+//printer 按输出格式打印命令结果
+type printer struct {
+	format string
+	w      io.Writer
+}
+
+func newPrinter(format string, w io.Writer) (*printer, error) {
+	switch format {
+	case outputJSON, outputTable:
+		return &printer{format: format, w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+func (p *printer) print(r *result) error {
+	if p.format == outputJSON {
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r.data)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(r.header, "\t"))
+	for _, row := range r.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,484 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/assetsadapterstore/velas-adapter/hdwallet"
+	"github.com/assetsadapterstore/velas-adapter/keystore"
+	"github.com/assetsadapterstore/velas-adapter/txsigner"
+	"github.com/assetsadapterstore/velas-adapter/velas"
+	"github.com/blocktree/go-owcrypt"
+	"github.com/shopspring/decimal"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const defaultFees = "0.001"
+
+//scrypt参数，测试时可调低
+var (
+	scryptN = keystore.StandardScryptN
+	scryptP = keystore.StandardScryptP
+)
+
+func newKeyStore(dir string, decoder *addrdec.AddressDecoderV2) *keystore.KeyStore {
+	return keystore.NewKeyStore(dir, decoder, scryptN, scryptP)
+}
+
+//networkFlags 网络参数，编码与主网不同的私有链可指定hex编码的前缀
+type networkFlags struct {
+	network       *string
+	addressPrefix *string
+	wifPrefix     *string
+}
+
+func addNetworkFlags(fs *flag.FlagSet) *networkFlags {
+	return &networkFlags{
+		network:       fs.String("network", addrdec.NetworkMainnet, "network: mainnet, testnet or devnet"),
+		addressPrefix: fs.String("address-prefix", "", "hex address prefix, the network default if empty"),
+		wifPrefix:     fs.String("wif-prefix", "", "hex WIF prefix, the network default if empty"),
+	}
+}
+
+//decoder 网络的地址解析器
+func (f *networkFlags) decoder() (*addrdec.AddressDecoderV2, error) {
+	params, err := addrdec.ParamsByName(*f.network)
+	if err != nil {
+		return nil, err
+	}
+	params, err = params.WithPrefix(*f.addressPrefix, *f.wifPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return addrdec.NewAddressDecoderV2(params), nil
+}
+
+//command 子命令的执行者
+type command struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+//recipients 可重复的-to参数
+type recipients []string
+
+func (r *recipients) String() string {
+	return strings.Join(*r, ",")
+}
+
+func (r *recipients) Set(value string) error {
+	*r = append(*r, value)
+	return nil
+}
+
+//signedTx sign命令的输出
+type signedTx struct {
+	TxID string          `json:"txid"`
+	Tx   json.RawMessage `json:"tx"`
+	Hex  string          `json:"hex"`
+}
+
+func (cmd *command) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("vlxsign "+name, flag.ContinueOnError)
+	fs.SetOutput(cmd.stderr)
+	return fs
+}
+
+func (cmd *command) keygen(args []string) error {
+	fs := cmd.flagSet("keygen")
+	dir := fs.String("keystore", "keystore", "keystore directory")
+	network := addNetworkFlags(fs)
+	passfile := fs.String("passfile", "", "file containing the passphrase")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	decoder, err := network.decoder()
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := cmd.passphrase(*passfile, true)
+	if err != nil {
+		return err
+	}
+
+	info, err := newKeyStore(*dir, decoder).NewAccount(passphrase)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.stdout, "address: %s\nkey file: %s\n", info.Address, info.Path)
+	return nil
+}
+
+func (cmd *command) importKey(args []string) error {
+	fs := cmd.flagSet("import")
+	dir := fs.String("keystore", "keystore", "keystore directory")
+	network := addNetworkFlags(fs)
+	passfile := fs.String("passfile", "", "file containing the passphrase")
+	mnemonic := fs.Bool("mnemonic", false, "read BIP-39 mnemonic of the official wallet instead of private key")
+	account := fs.Uint("account", 0, "account of mnemonic derivation path")
+	index := fs.Uint("index", 0, "address index of mnemonic derivation path")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	decoder, err := network.decoder()
+	if err != nil {
+		return err
+	}
+
+	line, err := bufio.NewReader(cmd.stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	line = strings.TrimSpace(line)
+
+	passphrase, err := cmd.passphrase(*passfile, true)
+	if err != nil {
+		return err
+	}
+
+	ks := newKeyStore(*dir, decoder)
+	var info *keystore.KeyInfo
+	if *mnemonic {
+		//按官方钱包的路径派生，助记词密码为空
+		key, deriveErr := hdwallet.DeriveKey(line, "", uint32(*account), uint32(*index))
+		if deriveErr != nil {
+			return deriveErr
+		}
+		fmt.Fprintf(cmd.stdout, "path: %s\n", key.Path)
+		info, err = ks.Import(key.PrivateKey(), passphrase)
+	} else if priv, decodeErr := hex.DecodeString(line); decodeErr == nil && len(priv) == keystore.PrivateKeyLen {
+		info, err = ks.Import(priv, passphrase)
+	} else {
+		info, err = ks.ImportWIF(line, passphrase)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.stdout, "address: %s\nkey file: %s\n", info.Address, info.Path)
+	return nil
+}
+
+func (cmd *command) list(args []string) error {
+	fs := cmd.flagSet("list")
+	dir := fs.String("keystore", "keystore", "keystore directory")
+	network := addNetworkFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	decoder, err := network.decoder()
+	if err != nil {
+		return err
+	}
+
+	list, err := newKeyStore(*dir, decoder).List()
+	if err != nil {
+		return err
+	}
+
+	for _, info := range list {
+		fmt.Fprintf(cmd.stdout, "%s\t%s\t%s\n", info.Address, info.PublicKey, info.Path)
+	}
+	return nil
+}
+
+func (cmd *command) export(args []string) error {
+	fs := cmd.flagSet("export")
+	dir := fs.String("keystore", "keystore", "keystore directory")
+	network := addNetworkFlags(fs)
+	from := fs.String("address", "", "address of key")
+	passfile := fs.String("passfile", "", "file containing the passphrase")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	decoder, err := network.decoder()
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := cmd.passphrase(*passfile, false)
+	if err != nil {
+		return err
+	}
+
+	keyfile, err := newKeyStore(*dir, decoder).Export(*from, passphrase, passphrase)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(cmd.stdout, string(keyfile))
+	return err
+}
+
+func (cmd *command) sign(args []string) error {
+	var to recipients
+
+	fs := cmd.flagSet("sign")
+	dir := fs.String("keystore", "keystore", "keystore directory")
+	network := addNetworkFlags(fs)
+	from := fs.String("from", "", "address of key, may be omitted if keystore has only one key")
+	utxoPath := fs.String("utxos", "", "unspent outputs JSON file, - for stdin")
+	fees := fs.String("fee", defaultFees, "fees of transaction")
+	change := fs.String("change", "", "change address, the key address by default")
+	passfile := fs.String("passfile", "", "file containing the passphrase")
+	fs.Var(&to, "to", "recipient as addr:amount, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	decoder, err := network.decoder()
+	if err != nil {
+		return err
+	}
+
+	if len(to) == 0 {
+		return fmt.Errorf("recipient is required")
+	}
+
+	ks := newKeyStore(*dir, decoder)
+	address := *from
+	if len(address) == 0 {
+		list, err := ks.List()
+		if err != nil {
+			return err
+		}
+		if len(list) != 1 {
+			return fmt.Errorf("keystore has %d keys, -from is required", len(list))
+		}
+		address = list[0].Address
+	}
+
+	changeAddress := *change
+	if len(changeAddress) == 0 {
+		changeAddress = address
+	}
+	if _, err := decoder.ValidateAddress(changeAddress); err != nil {
+		return fmt.Errorf("invalid change address %s: %v", changeAddress, err)
+	}
+
+	utxos, err := cmd.readUTXO(*utxoPath, address)
+	if err != nil {
+		return err
+	}
+
+	outputs, totalSend, err := parseRecipients(to, decoder)
+	if err != nil {
+		return err
+	}
+
+	commission, err := parseAmount(*fees)
+	if err != nil {
+		return fmt.Errorf("invalid fees: %v", err)
+	}
+
+	totalIn := uint64(0)
+	for _, u := range utxos {
+		totalIn += u.Value
+	}
+	if totalIn < totalSend+commission {
+		return fmt.Errorf("utxo amount %s is not enough to send %s and pay fees %s", formatAmount(totalIn), formatAmount(totalSend), formatAmount(commission))
+	}
+
+	trx, err := crypto.NewTransaction(utxos, outputs, changeAddress, commission)
+	if err != nil {
+		return err
+	}
+
+	//签名前输出摘要，供人工核对
+	fmt.Fprintf(cmd.stderr, "from %s, %d utxo, total %s %s\n", address, len(utxos), formatAmount(totalIn), velas.Symbol)
+	for addr, amount := range outputs {
+		fmt.Fprintf(cmd.stderr, "send %s %s to %s\n", formatAmount(amount), velas.Symbol, addr)
+	}
+	fmt.Fprintf(cmd.stderr, "fees %s %s, change %s %s to %s\n", formatAmount(commission), velas.Symbol, formatAmount(totalIn-totalSend-commission), velas.Symbol, changeAddress)
+
+	passphrase, err := cmd.passphrase(*passfile, false)
+	if err != nil {
+		return err
+	}
+	key, err := ks.GetKey(address, passphrase)
+	if err != nil {
+		return err
+	}
+
+	sigPub := make([]txsigner.SigPub, 0, len(trx.Inputs))
+	for _, in := range trx.Inputs {
+		msg := trx.MsgForSign(in.PreviousOutput.Hash, in.PreviousOutput.Index)
+		signature, err := txsigner.Default.SignTransactionHash(msg, key.PrivateKey, owcrypt.ECC_CURVE_ED25519)
+		if err != nil {
+			return err
+		}
+		sigPub = append(sigPub, txsigner.SigPub{Signature: signature, Pubkey: key.PublicKey})
+	}
+
+	emptyTrans, err := trx.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	pass, signedHex, err := txsigner.Default.VerifyAndCombineTransaction(string(emptyTrans), sigPub)
+	if !pass {
+		return fmt.Errorf("transaction verify failed: %v", err)
+	}
+
+	signedJSON, err := hex.DecodeString(signedHex)
+	if err != nil {
+		return err
+	}
+
+	var signed crypto.Tx
+	if err := json.Unmarshal(signedJSON, &signed); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(cmd.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&signedTx{
+		TxID: hex.EncodeToString(signed.Hash[:]),
+		Tx:   signedJSON,
+		Hex:  signedHex,
+	})
+}
+
+//readUTXO 读取Wallet.GetUnspent返回的JSON，utxo必须属于私钥地址
+func (cmd *command) readUTXO(path, address string) ([]*crypto.TransactionInputOutpoint, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if path == "-" {
+		data, err = ioutil.ReadAll(cmd.stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	utxos := make([]*crypto.TransactionInputOutpoint, 0)
+	if err := json.Unmarshal(data, &utxos); err != nil {
+		return nil, fmt.Errorf("invalid utxo JSON: %v", err)
+	}
+	if len(utxos) == 0 {
+		return nil, fmt.Errorf("utxo is empty")
+	}
+
+	for _, u := range utxos {
+		if len(u.Address) == 0 {
+			u.Address = address
+		}
+		if u.Address != address {
+			return nil, fmt.Errorf("utxo %x:%d belongs to %s, not key address %s", u.Hash, u.Index, u.Address, address)
+		}
+	}
+	return utxos, nil
+}
+
+//passphrase 读取密码，优先-passfile，其次环境变量VLX_PASSPHRASE，否则在终端输入
+func (cmd *command) passphrase(passfile string, confirm bool) (string, error) {
+	if len(passfile) > 0 {
+		data, err := ioutil.ReadFile(passfile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	if env := os.Getenv("VLX_PASSPHRASE"); len(env) > 0 {
+		return env, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return "", fmt.Errorf("passphrase is required, use -passfile or VLX_PASSPHRASE")
+	}
+
+	fmt.Fprint(cmd.stderr, "Passphrase: ")
+	pass, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(cmd.stderr)
+	if err != nil {
+		return "", err
+	}
+
+	if confirm {
+		fmt.Fprint(cmd.stderr, "Repeat passphrase: ")
+		repeat, err := terminal.ReadPassword(fd)
+		fmt.Fprintln(cmd.stderr)
+		if err != nil {
+			return "", err
+		}
+		if string(repeat) != string(pass) {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+
+	return string(pass), nil
+}
+
+//parseRecipients 解析addr:amount格式的接收地址，地址须为decoder网络的旧版地址
+func parseRecipients(to []string, decoder *addrdec.AddressDecoderV2) (map[string]uint64, uint64, error) {
+	outputs := make(map[string]uint64)
+	total := uint64(0)
+	for _, r := range to {
+		parts := strings.SplitN(r, ":", 2)
+		if len(parts) != 2 {
+			return nil, 0, fmt.Errorf("invalid recipient %s, want addr:amount", r)
+		}
+		address := parts[0]
+		if _, err := decoder.ValidateAddress(address); err != nil {
+			return nil, 0, fmt.Errorf("invalid recipient address %s: %v", parts[0], err)
+		}
+		amount, err := parseAmount(parts[1])
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid amount of %s: %v", parts[0], err)
+		}
+		if amount == 0 {
+			return nil, 0, fmt.Errorf("amount of %s is zero", parts[0])
+		}
+		outputs[address] += amount
+		total += amount
+	}
+	return outputs, total, nil
+}
+
+//parseAmount 金额转为最小单位
+func parseAmount(s string) (uint64, error) {
+	amount, err := decimal.NewFromString(s)
+	if err != nil {
+		return 0, err
+	}
+	units := amount.Shift(velas.Decimals)
+	if units.IsNegative() || !units.Equal(units.Truncate(0)) {
+		return 0, fmt.Errorf("amount %s is negative or has more than %d decimals", s, velas.Decimals)
+	}
+	return uint64(units.IntPart()), nil
+}
+
+func formatAmount(value uint64) string {
+	return decimal.New(int64(value), -velas.Decimals).StringFixed(velas.Decimals)
+}
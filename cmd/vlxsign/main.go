@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+//vlxsign 离线构建及签名交易单的命令行工具，不访问网络，用于冷钱包
+//
+//用法：
+//
+//	vlxsign keygen -keystore dir [-passfile file]
+//	vlxsign import -keystore dir [-passfile file] < private key hex or WIF
+//	vlxsign import -keystore dir -mnemonic [-account n] [-index n] [-passfile file] < mnemonic
+//	vlxsign list   -keystore dir
+//	vlxsign export -keystore dir -address addr [-passfile file]
+//	vlxsign sign   -keystore dir [-from addr] -utxos file -to addr:amount [-to addr:amount ...] [-fee amount] [-change addr] [-passfile file]
+//
+//私钥以scrypt及AES-256-GCM加密保存在keystore目录，格式见keystore包
+//各命令可用-network选择网络（默认mainnet），-address-prefix、-wif-prefix指定编码不同的私有链的hex前缀
+//内置网络的地址编码相同，无法拒绝其他内置网络的地址，只有前缀不同的私有链地址校验失败
+//助记词按官方钱包的路径m/44'/5655640'/account'/0'/index'派生，见hdwallet包
+//utxos为Wallet.GetUnspent返回的JSON，"-"时从标准输入读取
+//sign输出签名后的交易单JSON及hex，可通过vlxctl publish广播
+//密码优先从-passfile读取，其次为环境变量VLX_PASSPHRASE，否则在终端输入
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+
+	if len(args) == 0 {
+		usage(stderr)
+		return fmt.Errorf("command is required")
+	}
+
+	cmd := &command{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	switch args[0] {
+	case "keygen":
+		return cmd.keygen(args[1:])
+	case "import":
+		return cmd.importKey(args[1:])
+	case "list":
+		return cmd.list(args[1:])
+	case "export":
+		return cmd.export(args[1:])
+	case "sign":
+		return cmd.sign(args[1:])
+	case "help", "-h", "-help":
+		usage(stdout)
+		return nil
+	default:
+		usage(stderr)
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage:")
+	fmt.Fprintln(w, "  vlxsign keygen -keystore dir [-passfile file]")
+	fmt.Fprintln(w, "  vlxsign import -keystore dir [-passfile file] < private key hex or WIF")
+	fmt.Fprintln(w, "  vlxsign import -keystore dir -mnemonic [-account n] [-index n] [-passfile file] < mnemonic")
+	fmt.Fprintln(w, "  vlxsign list   -keystore dir")
+	fmt.Fprintln(w, "  vlxsign export -keystore dir -address addr [-passfile file]")
+	fmt.Fprintln(w, "  vlxsign sign   -keystore dir [-from addr] -utxos file -to addr:amount [-fee amount] [-change addr] [-passfile file]")
+	fmt.Fprintln(w, "every command accepts -network mainnet|testnet|devnet [-address-prefix hex] [-wif-prefix hex]")
+}
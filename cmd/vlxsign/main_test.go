@@ -0,0 +1,194 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/assetsadapterstore/velas-adapter/hdwallet"
+	"github.com/assetsadapterstore/velas-adapter/keystore"
+)
+
+func TestRun_Sign(t *testing.T) {
+
+	scryptN, scryptP = keystore.LightScryptN, keystore.LightScryptP
+
+	dir, err := ioutil.TempDir("", "vlxsign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	passfile := filepath.Join(dir, "pass")
+	ioutil.WriteFile(passfile, []byte("secret\n"), 0600)
+
+	key, _ := keystore.NewKey(addrdec.Default)
+	priv, address := key.PrivateKey, key.Address
+
+	var out, errOut bytes.Buffer
+	err = run([]string{"import", "-keystore", dir, "-passfile", passfile}, strings.NewReader(hex.EncodeToString(priv)), &out, &errOut)
+	if err != nil {
+		t.Fatalf("import unexpected error = %v", err)
+	}
+	if !strings.Contains(out.String(), address) {
+		t.Fatalf("import output does not contain address %s:\n%s", address, out.String())
+	}
+
+	out.Reset()
+	if err := run([]string{"list", "-keystore", dir}, nil, &out, &errOut); err != nil || !strings.HasPrefix(out.String(), address) {
+		t.Fatalf("list output = %s, error = %v, want address %s", out.String(), err, address)
+	}
+
+	receiver := "VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty"
+	utxos, _ := json.Marshal([]*crypto.TransactionInputOutpoint{
+		{Hash: [32]byte{1}, Index: 0, Value: 100000000},
+		{Hash: [32]byte{2}, Index: 3, Value: 50000000},
+	})
+
+	out.Reset()
+	err = run([]string{"sign", "-keystore", dir, "-utxos", "-", "-to", receiver + ":1.2", "-passfile", passfile}, bytes.NewReader(utxos), &out, &errOut)
+	if err != nil {
+		t.Fatalf("sign unexpected error = %v", err)
+	}
+
+	var result signedTx
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("sign output is invalid: %v", err)
+	}
+	var trx crypto.Tx
+	if err := json.Unmarshal(result.Tx, &trx); err != nil {
+		t.Fatalf("signed tx is invalid: %v", err)
+	}
+	if trx.GenerateHash() != trx.Hash || hex.EncodeToString(trx.Hash[:]) != result.TxID {
+		t.Errorf("signed tx hash mismatch")
+	}
+	for i, in := range trx.Inputs {
+		if len(in.Script) == 0 || len(in.PublicKey) == 0 {
+			t.Errorf("input %d is not signed", i)
+		}
+	}
+	if len(trx.Outputs) != 3 {
+		t.Errorf("signed tx outputs = %d, want commission, receiver and change", len(trx.Outputs))
+	}
+
+	err = run([]string{"sign", "-keystore", dir, "-utxos", "-", "-to", receiver + ":2", "-passfile", passfile}, bytes.NewReader(utxos), &out, &errOut)
+	if err == nil {
+		t.Errorf("sign with insufficient utxo want error")
+	}
+
+	wrongPass := filepath.Join(dir, "wrong")
+	ioutil.WriteFile(wrongPass, []byte("wrong"), 0600)
+	err = run([]string{"sign", "-keystore", dir, "-utxos", "-", "-to", receiver + ":1", "-passfile", wrongPass}, bytes.NewReader(utxos), &out, &errOut)
+	if err == nil {
+		t.Errorf("sign with wrong passphrase want error")
+	}
+}
+
+func TestRun_Network(t *testing.T) {
+
+	scryptN, scryptP = keystore.LightScryptN, keystore.LightScryptP
+
+	dir, err := ioutil.TempDir("", "vlxsign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	passfile := filepath.Join(dir, "pass")
+	ioutil.WriteFile(passfile, []byte("secret\n"), 0600)
+
+	params, _ := addrdec.DevNetParams.WithPrefix("1446", "f0")
+	devnet := addrdec.NewAddressDecoderV2(params)
+	key, _ := keystore.NewKey(devnet)
+	wif, _ := devnet.PrivateKeyToWIF(key.PrivateKey, false)
+	network := []string{"-keystore", dir, "-network", "devnet", "-address-prefix", "1446", "-wif-prefix", "f0", "-passfile", passfile}
+
+	var out, errOut bytes.Buffer
+	if err := run(append([]string{"import"}, network...), strings.NewReader(wif), &out, &errOut); err != nil || !strings.Contains(out.String(), key.Address) {
+		t.Fatalf("import output = %s, error = %v, want address %s", out.String(), err, key.Address)
+	}
+
+	receiver, _ := devnet.AddressEncode([]byte{2})
+	utxos, _ := json.Marshal([]*crypto.TransactionInputOutpoint{{Hash: [32]byte{1}, Index: 0, Value: 100000000}})
+
+	out.Reset()
+	err = run(append([]string{"sign", "-utxos", "-", "-to", receiver + ":0.5"}, network...), bytes.NewReader(utxos), &out, &errOut)
+	if err != nil {
+		t.Fatalf("sign on devnet unexpected error = %v", err)
+	}
+	var result signedTx
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil || len(result.Hex) == 0 {
+		t.Fatalf("sign output = %s, error = %v", out.String(), err)
+	}
+
+	//主网地址不属于该网络
+	mainnet := "VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty"
+	err = run(append([]string{"sign", "-utxos", "-", "-to", mainnet + ":0.5"}, network...), bytes.NewReader(utxos), &out, &errOut)
+	if err == nil {
+		t.Errorf("sign to mainnet address on devnet want error")
+	}
+
+	//主网密钥库不列出该网络的密钥
+	out.Reset()
+	if err := run([]string{"list", "-keystore", dir}, nil, &out, &errOut); err != nil || out.Len() != 0 {
+		t.Errorf("list of mainnet = %s, error = %v, want empty", out.String(), err)
+	}
+
+	if err := run([]string{"list", "-keystore", dir, "-network", "regtest"}, nil, &out, &errOut); err == nil {
+		t.Errorf("list of unknown network want error")
+	}
+}
+
+func TestRun_ImportMnemonic(t *testing.T) {
+
+	scryptN, scryptP = keystore.LightScryptN, keystore.LightScryptP
+
+	dir, err := ioutil.TempDir("", "vlxsign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	passfile := filepath.Join(dir, "pass")
+	ioutil.WriteFile(passfile, []byte("secret\n"), 0600)
+
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	key, _ := hdwallet.DeriveKey(mnemonic, "", 0, 1)
+	address, _ := key.Address(addrdec.Default)
+
+	var out, errOut bytes.Buffer
+	err = run([]string{"import", "-keystore", dir, "-passfile", passfile, "-mnemonic", "-index", "1"}, strings.NewReader(mnemonic+"\n"), &out, &errOut)
+	if err != nil {
+		t.Fatalf("import unexpected error = %v", err)
+	}
+	if !strings.Contains(out.String(), "m/44'/5655640'/0'/0'/1'") || !strings.Contains(out.String(), address) {
+		t.Errorf("import output = %s, want address %s", out.String(), address)
+	}
+
+	err = run([]string{"import", "-keystore", dir, "-passfile", passfile, "-mnemonic"}, strings.NewReader("abandon abandon\n"), &out, &errOut)
+	if err == nil {
+		t.Errorf("import with invalid mnemonic want error")
+	}
+}
@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	"fmt"
+)
+
+// MerkleRoot return root of merkle tree of hashes, each node is DHASH(left | right) and the last hash
+// of a level with odd count is paired with itself. The root of a single hash is the hash, of none is empty hash.
+// The rule is not checked against a recorded mainnet block until velas TestVerifyBlock_Mainnet passes.
+func MerkleRoot(hashes [][32]byte) [32]byte {
+	if len(hashes) == 0 {
+		return [32]byte{}
+	}
+
+	level := hashes
+	for len(level) > 1 {
+		level = merkleParents(level)
+	}
+	return level[0]
+}
+
+// merkleParents return the parent level of a merkle tree level, which is not modified
+func merkleParents(level [][32]byte) [][32]byte {
+	parents := make([][32]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		right := level[i]
+		if i+1 < len(level) {
+			right = level[i+1]
+		}
+		parents = append(parents, DHASH(append(level[i][:], right[:]...)))
+	}
+	return parents
+}
+
+// TxMerkleRoot return merkle root of transactions, the hash of each transaction is regenerated from its content
+func TxMerkleRoot(txs []*Tx) [32]byte {
+	hashes := make([][32]byte, 0, len(txs))
+	for _, tx := range txs {
+		hashes = append(hashes, tx.GenerateHash())
+	}
+	return MerkleRoot(hashes)
+}
+
+// MerkleBranch return hashes of the siblings on the path from the leaf at index to the root
+func MerkleBranch(hashes [][32]byte, index int) ([][32]byte, error) {
+	if index < 0 || index >= len(hashes) {
+		return nil, fmt.Errorf("index %d out of range of %d hashes", index, len(hashes))
+	}
+
+	branch := make([][32]byte, 0)
+	level := hashes
+	for len(level) > 1 {
+		sibling := index ^ 1
+		if sibling == len(level) {
+			sibling = index
+		}
+		branch = append(branch, level[sibling])
+		level = merkleParents(level)
+		index /= 2
+	}
+	return branch, nil
+}
+
+// MerkleRootFromBranch return the root computed from the leaf at index of count hashes and its branch.
+// The branch must have the depth of the tree, and a sibling which is a duplicated last hash must equal the node itself,
+// so that a branch can not prove a leaf at a position beyond count. Hashes a, b, c and a, b, c, c have the same root,
+// count must be the transaction count of the header which the root is checked against.
+func MerkleRootFromBranch(leaf [32]byte, index, count int, branch [][32]byte) ([32]byte, error) {
+	if index < 0 || index >= count {
+		return [32]byte{}, fmt.Errorf("index %d out of range of %d hashes", index, count)
+	}
+
+	depth := 0
+	for n := count; n > 1; n = (n + 1) / 2 {
+		depth++
+	}
+	if len(branch) != depth {
+		return [32]byte{}, fmt.Errorf("branch has %d hashes, want %d", len(branch), depth)
+	}
+
+	node := leaf
+	n := count
+	for _, sibling := range branch {
+		if index%2 == 0 {
+			if index == n-1 && sibling != node {
+				return [32]byte{}, fmt.Errorf("last node of level is not paired with itself")
+			}
+			node = DHASH(append(node[:], sibling[:]...))
+		} else {
+			node = DHASH(append(sibling[:], node[:]...))
+		}
+		index /= 2
+		n = (n + 1) / 2
+	}
+	return node, nil
+}
@@ -0,0 +1,81 @@
+package crypto
+
+import (
+	"testing"
+)
+
+func TestMerkleRoot(t *testing.T) {
+	a, b, c := [32]byte{1}, [32]byte{2}, [32]byte{3}
+	pair := func(l, r [32]byte) [32]byte { return DHASH(append(l[:], r[:]...)) }
+
+	tests := []struct {
+		name   string
+		hashes [][32]byte
+		want   [32]byte
+	}{
+		{"empty", nil, [32]byte{}},
+		{"single", [][32]byte{a}, a},
+		{"pair", [][32]byte{a, b}, pair(a, b)},
+		{"odd", [][32]byte{a, b, c}, pair(pair(a, b), pair(c, c))},
+	}
+
+	for _, tt := range tests {
+		if got := MerkleRoot(tt.hashes); got != tt.want {
+			t.Errorf("MerkleRoot(%s) = %x, want %x", tt.name, got, tt.want)
+		}
+	}
+
+	//input is not modified when the last hash is duplicated
+	hashes := [][32]byte{a, b, c}
+	MerkleRoot(hashes[:3:3])
+	if len(hashes) != 3 || hashes[2] != c {
+		t.Errorf("MerkleRoot() modified input %x", hashes)
+	}
+
+	txs := []*Tx{{Version: 1}, {Version: 1, LockTime: 1}}
+	if got, want := TxMerkleRoot(txs), pair(txs[0].GenerateHash(), txs[1].GenerateHash()); got != want {
+		t.Errorf("TxMerkleRoot() = %x, want %x", got, want)
+	}
+}
+
+func TestMerkleBranch(t *testing.T) {
+	for count := 1; count <= 9; count++ {
+		hashes := make([][32]byte, count)
+		for i := range hashes {
+			hashes[i] = [32]byte{byte(i + 1)}
+		}
+		root := MerkleRoot(hashes)
+
+		for index := range hashes {
+			branch, err := MerkleBranch(hashes, index)
+			if err != nil {
+				t.Fatalf("MerkleBranch(%d, %d) unexpected error = %v", count, index, err)
+			}
+			got, err := MerkleRootFromBranch(hashes[index], index, count, branch)
+			if err != nil || got != root {
+				t.Errorf("MerkleRootFromBranch(%d, %d) = %x, error = %v, want %x", count, index, got, err, root)
+			}
+			//the same branch does not prove the leaf at another position
+			if count > 1 {
+				other := (index + 1) % count
+				if got, _ := MerkleRootFromBranch(hashes[index], other, count, branch); got == root {
+					t.Errorf("MerkleRootFromBranch(%d, %d) proves leaf %d at %d", count, index, index, other)
+				}
+			}
+		}
+	}
+
+	//the last leaf of an odd level must be paired with itself
+	hashes := [][32]byte{{1}, {2}, {3}}
+	branch, _ := MerkleBranch(hashes, 2)
+	forged := append([][32]byte{{4}}, branch[1:]...)
+	if _, err := MerkleRootFromBranch(hashes[2], 2, 3, forged); err == nil {
+		t.Errorf("MerkleRootFromBranch() with sibling of last leaf want error")
+	}
+	if _, err := MerkleRootFromBranch(hashes[2], 3, 3, branch); err == nil {
+		t.Errorf("MerkleRootFromBranch() with index out of range want error")
+	}
+	if _, err := MerkleBranch(hashes, 3); err == nil {
+		t.Errorf("MerkleBranch() with index out of range want error")
+	}
+}
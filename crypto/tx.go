@@ -10,6 +10,17 @@ import (
 	"github.com/go-errors/errors"
 )
 
+const (
+	PublicKeyLen = 32 // ed25519 public key
+	SignatureLen = 64 // ed25519 signature
+	AddressLen   = 26 // prefix 2 bytes, ripemd160 20 bytes, checksum 4 bytes
+
+	txHeaderLen        = 8                                                 // version + lock time
+	signedTxInLen      = txInOutpointLen + 4 + PublicKeyLen + SignatureLen // outpoint + sequence + public key + signature
+	commissionTxOutLen = 12                                                // index + value
+	addressTxOutLen    = commissionTxOutLen + AddressLen                   // index + value + script
+)
+
 type Tx struct {
 	Hash     [32]byte            `json:"hash"`
 	Version  uint32              `json:"version"`
@@ -100,6 +111,35 @@ func (tx *Tx) MsgForSign(hash [32]byte, index uint32) []byte {
 
 // GenerateHash return generated hash
 func (tx *Tx) GenerateHash() [32]byte {
+	return DHASH(tx.serialize())
+}
+
+// Size return size of serialized transaction
+func (tx *Tx) Size() int {
+	return len(tx.serialize())
+}
+
+// SignedSize return size of serialized transaction after all inputs are signed
+func (tx *Tx) SignedSize() int {
+	size := tx.Size()
+	for _, txIn := range tx.Inputs {
+		if len(txIn.PublicKey) == 0 {
+			size += PublicKeyLen
+		}
+		if len(txIn.Script) == 0 {
+			size += SignatureLen
+		}
+	}
+	return size
+}
+
+// EstimateSize return estimated size of signed transaction with given count of inputs and address outputs,
+// the commission output is always included
+func EstimateSize(inputs, outputs int) int {
+	return txHeaderLen + inputs*signedTxInLen + commissionTxOutLen + outputs*addressTxOutLen
+}
+
+func (tx *Tx) serialize() []byte {
 	txInSlices := make([][]byte, 0)
 	for _, txIn := range tx.Inputs {
 		txInSlices = append(txInSlices, txIn.forBlkHash())
@@ -119,8 +159,7 @@ func (tx *Tx) GenerateHash() [32]byte {
 		txOutSlice,
 	}
 
-	msg := helpers.ConcatByteArray(txSlices)
-	return DHASH(msg)
+	return helpers.ConcatByteArray(txSlices)
 }
 
 func DHASH(data []byte) [32]byte {
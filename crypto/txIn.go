@@ -8,6 +8,8 @@ import (
 	"github.com/btcsuite/btcutil/base58"
 )
 
+const txInOutpointLen = 44 // hash 32 bytes + index 4 bytes + value 8 bytes
+
 type TransactionInput struct {
 	// The previous output transaction reference, as an OutPoint structure
 	PreviousOutput TransactionInputOutpoint `json:"previous_output"`
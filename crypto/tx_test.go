@@ -0,0 +1,36 @@
+package crypto
+
+import (
+	"testing"
+)
+
+func TestTx_SignedSize(t *testing.T) {
+	unspents := []*TransactionInputOutpoint{
+		{Hash: [32]byte{1}, Index: 0, Value: 100000000, Address: "VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty"},
+		{Hash: [32]byte{2}, Index: 1, Value: 50000000, Address: "VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty"},
+	}
+	to := map[string]uint64{
+		"VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty": 120000000,
+	}
+	tx, err := NewTransaction(unspents, to, "VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty", 100000)
+	if err != nil {
+		t.Fatalf("NewTransaction() error = %v", err)
+	}
+
+	// commission output + recipient + change
+	if len(tx.Outputs) != 3 {
+		t.Fatalf("unexpected outputs count: %d", len(tx.Outputs))
+	}
+
+	if got, want := tx.SignedSize(), EstimateSize(len(tx.Inputs), len(tx.Outputs)-1); got != want {
+		t.Errorf("SignedSize() = %d, EstimateSize() = %d", got, want)
+	}
+
+	for i := range tx.Inputs {
+		tx.Inputs[i].PublicKey = make([]byte, PublicKeyLen)
+		tx.Inputs[i].Script = make([]byte, SignatureLen)
+	}
+	if tx.Size() != tx.SignedSize() {
+		t.Errorf("Size() = %d, SignedSize() = %d after signing", tx.Size(), tx.SignedSize())
+	}
+}
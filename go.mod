@@ -14,6 +14,8 @@ require (
 	github.com/ethereum/go-ethereum v1.9.9
 	github.com/go-errors/errors v1.0.1
 	github.com/shopspring/decimal v0.0.0-20200105231215-408a2507e114
+	github.com/tyler-smith/go-bip39 v1.0.2
+	golang.org/x/crypto v0.0.0-20191227163750-53104e6ec876
 	gopkg.in/resty.v1 v1.12.0
 )
 
@@ -0,0 +1,166 @@
+package hdwallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/ed25519"
+)
+
+const (
+	// CoinType is the SLIP-0044 coin type of Velas
+	CoinType = 5655640
+
+	// HardenedOffset is added to the index of a hardened child
+	HardenedOffset = 0x80000000
+
+	// PathTemplate is the derivation path of the official Velas wallet, every level is hardened
+	// because SLIP-0010 ed25519 does not support normal derivation
+	PathTemplate = "m/44'/5655640'/%d'/0'/%d'"
+
+	masterSecret = "ed25519 seed"
+)
+
+var (
+	// ErrNotHardened is returned when a path contains a normal (non-hardened) index
+	ErrNotHardened = errors.New("ed25519 only supports hardened derivation")
+)
+
+// ExtendedKey is a SLIP-0010 ed25519 key with its chain code
+type ExtendedKey struct {
+	Key       []byte // 32 bytes ed25519 seed
+	ChainCode []byte
+	Depth     uint8
+	Path      string
+}
+
+// NewSeed validates the words and checksum of BIP-39 mnemonic and derives the seed with passphrase
+func NewSeed(mnemonic, passphrase string) ([]byte, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	// IsMnemonicValid only checks the words, the checksum is verified when decoding the entropy
+	if _, err := bip39.EntropyFromMnemonic(mnemonic); err != nil {
+		return nil, fmt.Errorf("invalid mnemonic: %v", err)
+	}
+	return bip39.NewSeed(mnemonic, passphrase), nil
+}
+
+// NewMasterKey creates the master key of seed
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, fmt.Errorf("invalid seed length %d", len(seed))
+	}
+	mac := hmac.New(sha512.New, []byte(masterSecret))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	return &ExtendedKey{Key: sum[:32], ChainCode: sum[32:], Path: "m"}, nil
+}
+
+// Child derives the hardened child of index, the index may be given with or without HardenedOffset
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	if index < HardenedOffset {
+		index += HardenedOffset
+	}
+
+	data := make([]byte, 0, 37)
+	data = append(data, 0)
+	data = append(data, k.Key...)
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[33:], index)
+
+	mac := hmac.New(sha512.New, k.ChainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	return &ExtendedKey{
+		Key:       sum[:32],
+		ChainCode: sum[32:],
+		Depth:     k.Depth + 1,
+		Path:      fmt.Sprintf("%s/%d'", k.Path, index-HardenedOffset),
+	}, nil
+}
+
+// Derive derives the key of path from the master key, such as m/44'/5655640'/0'/0'/0'
+func (k *ExtendedKey) Derive(path string) (*ExtendedKey, error) {
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if k.Depth != 0 {
+		return nil, fmt.Errorf("path must be derived from the master key")
+	}
+	key := k
+	for _, index := range indexes {
+		key, err = key.Child(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// PublicKey returns the 32 bytes ed25519 public key
+func (k *ExtendedKey) PublicKey() []byte {
+	return []byte(ed25519.NewKeyFromSeed(k.Key).Public().(ed25519.PublicKey))
+}
+
+// PrivateKey returns the clamped scalar of the key, which is the private key format used by owcrypt and keystore
+func (k *ExtendedKey) PrivateKey() []byte {
+	h := sha512.Sum512(k.Key)
+	scalar := make([]byte, 32)
+	copy(scalar, h[:32])
+	scalar[0] &= 248
+	scalar[31] &= 63
+	scalar[31] |= 64
+	return scalar
+}
+
+// Address returns the address of the key on the network of decoder
+func (k *ExtendedKey) Address(decoder *addrdec.AddressDecoderV2) (string, error) {
+	return decoder.PublicKeyToAddress(k.PublicKey(), false)
+}
+
+// ParsePath parses a derivation path, every index must be hardened and marked by ' or h
+func ParsePath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if len(parts) == 0 || parts[0] != "m" {
+		return nil, fmt.Errorf("path %q must start with m", path)
+	}
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") || strings.HasSuffix(part, "H")
+		if !hardened {
+			return nil, fmt.Errorf("path %q: %v", path, ErrNotHardened)
+		}
+		index, err := strconv.ParseUint(part[:len(part)-1], 10, 32)
+		if err != nil || index >= HardenedOffset {
+			return nil, fmt.Errorf("path %q: invalid index %s", path, part)
+		}
+		indexes = append(indexes, uint32(index)+HardenedOffset)
+	}
+	return indexes, nil
+}
+
+// AccountPath returns the derivation path of the address at index of account
+func AccountPath(account, index uint32) string {
+	return fmt.Sprintf(PathTemplate, account, index)
+}
+
+// DeriveKey derives the key of account and index from mnemonic
+func DeriveKey(mnemonic, passphrase string, account, index uint32) (*ExtendedKey, error) {
+	seed, err := NewSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	master, err := NewMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	return master.Derive(AccountPath(account, index))
+}
@@ -0,0 +1,113 @@
+package hdwallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/assetsadapterstore/velas-adapter/keystore"
+	owcrypt "github.com/blocktree/go-owcrypt"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+// SLIP-0010 test vector 1 for ed25519
+func TestSLIP10Vectors(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path      string
+		chainCode string
+		key       string
+		publicKey string
+	}{
+		{"m", "90046a93de5380a72b5e45010748567d5ea02bbf6522f979e05c0d8d8ca9fffb", "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7", "a4b2856bfec510abab89753fac1ac0e1112364e7d250545963f135f2a33188ed"},
+		{"m/0'", "8b59aa11380b624e81507a27fedda59fea6d0b779a778918a2fd3590e16e9c69", "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3", "8c8a13df77a28f3445213a0f432fde644acaa215fc72dcdf300d5efaa85d350c"},
+		{"m/0H/1H", "a320425f77d1b5c2505a6b1b27382b37368ee640e3557c315416801243552f14", "b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2", "1932a5270f335bed617d5b935c80aedb1a35bd9fc1e31acafd5372c30f5c1187"},
+	}
+
+	for _, test := range tests {
+		key, err := master.Derive(test.path)
+		if err != nil {
+			t.Fatalf("Derive(%s) unexpected error = %v", test.path, err)
+		}
+		if hex.EncodeToString(key.ChainCode) != test.chainCode || hex.EncodeToString(key.Key) != test.key || hex.EncodeToString(key.PublicKey()) != test.publicKey {
+			t.Errorf("Derive(%s) = chain code %x, key %x, public key %x", test.path, key.ChainCode, key.Key, key.PublicKey())
+		}
+	}
+}
+
+func TestNewSeed(t *testing.T) {
+	seed, err := NewSeed(testMnemonic, "TREZOR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"
+	if hex.EncodeToString(seed) != want {
+		t.Errorf("NewSeed() = %x, want %s", seed, want)
+	}
+
+	if _, err := NewSeed("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", ""); err == nil {
+		t.Errorf("NewSeed() with invalid checksum want error")
+	}
+}
+
+// vectors are cross-checked with an independent implementation of SLIP-0010 and ed25519
+func TestDeriveKey(t *testing.T) {
+
+	tests := []struct {
+		index     uint32
+		path      string
+		publicKey string
+	}{
+		{0, "m/44'/5655640'/0'/0'/0'", "dd27434d3298be650d292143b0cd7dfab6374bc851873035885d774d09649343"},
+		{1, "m/44'/5655640'/0'/0'/1'", "3cb1e0f834f5634401e72132925d36bec4127b6663de672b54b072983d010eb0"},
+	}
+
+	for _, test := range tests {
+		key, err := DeriveKey(testMnemonic, "", 0, test.index)
+		if err != nil {
+			t.Fatalf("DeriveKey() unexpected error = %v", err)
+		}
+		if key.Path != test.path || hex.EncodeToString(key.PublicKey()) != test.publicKey {
+			t.Errorf("DeriveKey(0, %d) = %s %x, want %s %s", test.index, key.Path, key.PublicKey(), test.path, test.publicKey)
+		}
+
+		//the private key is usable by owcrypt and keystore, and gives the same public key and address
+		pub, ret := owcrypt.GenPubkey(key.PrivateKey(), owcrypt.ECC_CURVE_ED25519)
+		if ret != owcrypt.SUCCESS || !bytes.Equal(pub, key.PublicKey()) {
+			t.Errorf("owcrypt.GenPubkey() = %x, want %x", pub, key.PublicKey())
+		}
+		ksKey, err := keystore.NewKeyFromPrivateKey(key.PrivateKey(), addrdec.Default)
+		if err != nil {
+			t.Fatalf("NewKeyFromPrivateKey() unexpected error = %v", err)
+		}
+		address, err := key.Address(addrdec.Default)
+		if err != nil || address != ksKey.Address {
+			t.Errorf("Address() = %s, error = %v, want %s", address, err, ksKey.Address)
+		}
+	}
+
+	key, _ := DeriveKey(testMnemonic, "", 0, 0)
+	if key.PrivateKey()[0] != 0xc8 {
+		t.Errorf("PrivateKey() = %x", key.PrivateKey())
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	if _, err := ParsePath("m/44'/5655640'/0'/0/0"); err == nil {
+		t.Errorf("ParsePath() with normal index want error")
+	}
+	if _, err := ParsePath("44'/0'"); err == nil {
+		t.Errorf("ParsePath() without m want error")
+	}
+	indexes, err := ParsePath("m/44'/5655640h")
+	if err != nil || len(indexes) != 2 || indexes[1] != CoinType+HardenedOffset {
+		t.Errorf("ParsePath() = %v, error = %v", indexes, err)
+	}
+}
@@ -0,0 +1,241 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	owcrypt "github.com/blocktree/go-owcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// Version of key file format
+	Version = 1
+
+	// StandardScryptN and StandardScryptP are the scrypt parameters for key files, use about 256MB memory and 1s CPU time
+	StandardScryptN = 1 << 18
+	StandardScryptP = 1
+
+	// LightScryptN and LightScryptP are the scrypt parameters for testing or devices with limited resources
+	LightScryptN = 1 << 12
+	LightScryptP = 6
+
+	PrivateKeyLen = 32
+
+	cipherName  = "aes-256-gcm"
+	kdfName     = "scrypt"
+	scryptR     = 8
+	scryptDKLen = 32
+)
+
+var (
+	// ErrDecrypt is returned when passphrase is wrong or key file is corrupted
+	ErrDecrypt = errors.New("could not decrypt key with given passphrase")
+)
+
+// Key is a decrypted ed25519 private key and its velas address
+type Key struct {
+	Address    string
+	PublicKey  []byte
+	PrivateKey []byte
+}
+
+// KeyInfo is the public part of a key file
+type KeyInfo struct {
+	Address   string `json:"address"`
+	PublicKey string `json:"publicKey"`
+	Path      string `json:"-"`
+}
+
+type keyJSON struct {
+	Version   int        `json:"version"`
+	Address   string     `json:"address"`
+	PublicKey string     `json:"publicKey"`
+	Crypto    cryptoJSON `json:"crypto"`
+}
+
+type cryptoJSON struct {
+	Cipher     string       `json:"cipher"`
+	CipherText string       `json:"ciphertext"`
+	Nonce      string       `json:"nonce"`
+	KDF        string       `json:"kdf"`
+	KDFParams  scryptParams `json:"kdfparams"`
+}
+
+type scryptParams struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+}
+
+// NewKey generate a random ed25519 private key, the address is encoded on the network of decoder
+func NewKey(decoder *addrdec.AddressDecoderV2) (*Key, error) {
+	priv := make([]byte, PrivateKeyLen)
+	if _, err := io.ReadFull(rand.Reader, priv); err != nil {
+		return nil, err
+	}
+	// owcrypt use the private key as a clamped scalar
+	priv[0] &= 248
+	priv[31] &= 63
+	priv[31] |= 64
+	return NewKeyFromPrivateKey(priv, decoder)
+}
+
+// NewKeyFromPrivateKey create key of ed25519 private key on the network of decoder,
+// the private key must be a clamped scalar as used by owcrypt
+func NewKeyFromPrivateKey(priv []byte, decoder *addrdec.AddressDecoderV2) (*Key, error) {
+	if len(priv) != PrivateKeyLen {
+		return nil, fmt.Errorf("invalid private key length %d", len(priv))
+	}
+	// owcrypt clamp the private key when signing, an unclamped key would sign for another public key
+	if priv[0]&7 != 0 || priv[31]&0xC0 != 0x40 {
+		return nil, fmt.Errorf("private key is not a clamped ed25519 scalar")
+	}
+	pub, ret := owcrypt.GenPubkey(priv, owcrypt.ECC_CURVE_ED25519)
+	if ret != owcrypt.SUCCESS {
+		return nil, fmt.Errorf("generate public key failed")
+	}
+	address, err := decoder.PublicKeyToAddress(pub, false)
+	if err != nil {
+		return nil, err
+	}
+	key := &Key{
+		Address:    address,
+		PublicKey:  pub,
+		PrivateKey: make([]byte, PrivateKeyLen),
+	}
+	copy(key.PrivateKey, priv)
+	return key, nil
+}
+
+// EncryptKey encrypt key with passphrase, the private key is encrypted by AES-256-GCM with key derived by scrypt
+func EncryptKey(key *Key, passphrase string, scryptN, scryptP int) ([]byte, error) {
+
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	derivedKey, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	// the address is authenticated, so that a key file can not be renamed to another address
+	cipherText := gcm.Seal(nil, nonce, key.PrivateKey, []byte(key.Address))
+
+	return json.MarshalIndent(&keyJSON{
+		Version:   Version,
+		Address:   key.Address,
+		PublicKey: hex.EncodeToString(key.PublicKey),
+		Crypto: cryptoJSON{
+			Cipher:     cipherName,
+			CipherText: hex.EncodeToString(cipherText),
+			Nonce:      hex.EncodeToString(nonce),
+			KDF:        kdfName,
+			KDFParams: scryptParams{
+				N:     scryptN,
+				R:     scryptR,
+				P:     scryptP,
+				DKLen: scryptDKLen,
+				Salt:  hex.EncodeToString(salt),
+			},
+		},
+	}, "", "  ")
+}
+
+// DecryptKey decrypt key file with passphrase, the key file must be of an address on the network of decoder
+func DecryptKey(keyfile []byte, passphrase string, decoder *addrdec.AddressDecoderV2) (*Key, error) {
+
+	var k keyJSON
+	if err := json.Unmarshal(keyfile, &k); err != nil {
+		return nil, fmt.Errorf("invalid key file: %v", err)
+	}
+
+	if k.Version != Version {
+		return nil, fmt.Errorf("key file version %d is not supported", k.Version)
+	}
+
+	if k.Crypto.Cipher != cipherName || k.Crypto.KDF != kdfName {
+		return nil, fmt.Errorf("cipher %s with kdf %s is not supported", k.Crypto.Cipher, k.Crypto.KDF)
+	}
+
+	params := k.Crypto.KDFParams
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %v", err)
+	}
+	nonce, err := hex.DecodeString(k.Crypto.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %v", err)
+	}
+	cipherText, err := hex.DecodeString(k.Crypto.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %v", err)
+	}
+
+	derivedKey, err := scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, params.DKLen)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d", len(nonce))
+	}
+
+	priv, err := gcm.Open(nil, nonce, cipherText, []byte(k.Address))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	key, err := NewKeyFromPrivateKey(priv, decoder)
+	if err != nil {
+		return nil, err
+	}
+	if key.Address != k.Address {
+		return nil, fmt.Errorf("key file address %s is not %s on network %s", k.Address, key.Address, decoder.ChainParams().Name)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func readKeyInfo(keyfile []byte) (*KeyInfo, error) {
+	var k keyJSON
+	if err := json.Unmarshal(keyfile, &k); err != nil {
+		return nil, err
+	}
+	if k.Version != Version || len(k.Address) == 0 {
+		return nil, fmt.Errorf("not a key file")
+	}
+	return &KeyInfo{Address: k.Address, PublicKey: k.PublicKey}, nil
+}
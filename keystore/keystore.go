@@ -0,0 +1,188 @@
+package keystore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/assetsadapterstore/velas-adapter/txsigner"
+)
+
+const keyFileExt = ".json"
+
+// KeyStore manage encrypted key files in a directory, one file per key, named by address on the network of decoder
+type KeyStore struct {
+	dir     string
+	decoder *addrdec.AddressDecoderV2
+	scryptN int
+	scryptP int
+}
+
+// NewKeyStore create key store in dir for the network of decoder with scrypt parameters
+func NewKeyStore(dir string, decoder *addrdec.AddressDecoderV2, scryptN, scryptP int) *KeyStore {
+	return &KeyStore{dir: dir, decoder: decoder, scryptN: scryptN, scryptP: scryptP}
+}
+
+// NewAccount generate a new key and store it encrypted with passphrase
+func (ks *KeyStore) NewAccount(passphrase string) (*KeyInfo, error) {
+	key, err := NewKey(ks.decoder)
+	if err != nil {
+		return nil, err
+	}
+	return ks.store(key, passphrase)
+}
+
+// Import store a private key encrypted with passphrase
+func (ks *KeyStore) Import(priv []byte, passphrase string) (*KeyInfo, error) {
+	key, err := NewKeyFromPrivateKey(priv, ks.decoder)
+	if err != nil {
+		return nil, err
+	}
+	return ks.store(key, passphrase)
+}
+
+// ImportWIF store a private key in WIF format encrypted with passphrase
+func (ks *KeyStore) ImportWIF(wif string, passphrase string) (*KeyInfo, error) {
+	priv, err := ks.decoder.WIFToPrivateKey(wif, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WIF: %v", err)
+	}
+	return ks.Import(priv, passphrase)
+}
+
+// ImportKeyFile store a key file exported by Export, the key file is re-encrypted with newPassphrase
+func (ks *KeyStore) ImportKeyFile(keyfile []byte, passphrase, newPassphrase string) (*KeyInfo, error) {
+	key, err := DecryptKey(keyfile, passphrase, ks.decoder)
+	if err != nil {
+		return nil, err
+	}
+	return ks.store(key, newPassphrase)
+}
+
+// Export return the key file of address re-encrypted with newPassphrase
+func (ks *KeyStore) Export(address, passphrase, newPassphrase string) ([]byte, error) {
+	key, err := ks.GetKey(address, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptKey(key, newPassphrase, ks.scryptN, ks.scryptP)
+}
+
+// ExportPrivateKey return the raw private key of address
+func (ks *KeyStore) ExportPrivateKey(address, passphrase string) ([]byte, error) {
+	key, err := ks.GetKey(address, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return key.PrivateKey, nil
+}
+
+// GetKey decrypt key of address
+func (ks *KeyStore) GetKey(address, passphrase string) (*Key, error) {
+	keyfile, err := ioutil.ReadFile(ks.path(address))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("key of %s not found", address)
+		}
+		return nil, err
+	}
+	key, err := DecryptKey(keyfile, passphrase, ks.decoder)
+	if err != nil {
+		return nil, err
+	}
+	if key.Address != address {
+		return nil, fmt.Errorf("key file of %s contains key of %s", address, key.Address)
+	}
+	return key, nil
+}
+
+// Delete remove key of address, the passphrase is required to prevent deleting by mistake
+func (ks *KeyStore) Delete(address, passphrase string) error {
+	if _, err := ks.GetKey(address, passphrase); err != nil {
+		return err
+	}
+	return os.Remove(ks.path(address))
+}
+
+// List return all keys of the network in key store, sorted by address
+func (ks *KeyStore) List() ([]*KeyInfo, error) {
+	files, err := ioutil.ReadDir(ks.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	list := make([]*KeyInfo, 0)
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), keyFileExt) {
+			continue
+		}
+		path := filepath.Join(ks.dir, f.Name())
+		keyfile, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// skip key files of chains with another address prefix, the built-in networks share one prefix
+		info, err := readKeyInfo(keyfile)
+		if err != nil || !ks.decoder.AddressVerify(info.Address) {
+			continue
+		}
+		info.Path = path
+		list = append(list, info)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Address < list[j].Address
+	})
+	return list, nil
+}
+
+// Signer return signer of keys in key store, the key ID of sign request is the address
+func (ks *KeyStore) Signer(passphrase string) txsigner.Signer {
+	return txsigner.NewLocalSigner(func(keyID, hdPath string) ([]byte, error) {
+		return ks.ExportPrivateKey(keyID, passphrase)
+	})
+}
+
+func (ks *KeyStore) store(key *Key, passphrase string) (*KeyInfo, error) {
+	path := ks.path(key.Address)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("key of %s already exists", key.Address)
+	}
+
+	keyfile, err := EncryptKey(key, passphrase, ks.scryptN, ks.scryptP)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(ks.dir, 0700); err != nil {
+		return nil, err
+	}
+
+	// write to a temporary file first so that a crash does not leave a truncated key file
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, keyfile, 0600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+
+	info, err := readKeyInfo(keyfile)
+	if err != nil {
+		return nil, err
+	}
+	info.Path = path
+	return info, nil
+}
+
+func (ks *KeyStore) path(address string) string {
+	return filepath.Join(ks.dir, filepath.Base(address)+keyFileExt)
+}
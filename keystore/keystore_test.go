@@ -0,0 +1,136 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/assetsadapterstore/velas-adapter/txsigner"
+	owcrypt "github.com/blocktree/go-owcrypt"
+)
+
+func TestKeyStore(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := NewKeyStore(dir, addrdec.Default, LightScryptN, LightScryptP)
+
+	info, err := ks.NewAccount("secret")
+	if err != nil {
+		t.Fatalf("NewAccount() unexpected error = %v", err)
+	}
+
+	list, err := ks.List()
+	if err != nil || len(list) != 1 || list[0].Address != info.Address {
+		t.Fatalf("List() = %v, error = %v, want %s", list, err, info.Address)
+	}
+
+	if _, err := ks.GetKey(info.Address, "wrong"); err != ErrDecrypt {
+		t.Errorf("GetKey() with wrong passphrase error = %v, want ErrDecrypt", err)
+	}
+
+	key, err := ks.GetKey(info.Address, "secret")
+	if err != nil {
+		t.Fatalf("GetKey() unexpected error = %v", err)
+	}
+	if hex.EncodeToString(key.PublicKey) != info.PublicKey {
+		t.Errorf("GetKey() public key mismatch")
+	}
+
+	//导出后导入另一个密钥库
+	keyfile, err := ks.Export(info.Address, "secret", "transfer")
+	if err != nil {
+		t.Fatalf("Export() unexpected error = %v", err)
+	}
+	other := NewKeyStore(dir+"-other", addrdec.Default, LightScryptN, LightScryptP)
+	defer os.RemoveAll(dir + "-other")
+	imported, err := other.ImportKeyFile(keyfile, "transfer", "other")
+	if err != nil || imported.Address != info.Address {
+		t.Fatalf("ImportKeyFile() = %v, error = %v", imported, err)
+	}
+
+	if _, err := ks.Import(key.PrivateKey, "secret"); err == nil {
+		t.Errorf("Import() of existing key want error")
+	}
+
+	//签名器按地址查找私钥
+	message := []byte("message")
+	signature, err := ks.Signer("secret").Sign(&txsigner.SignRequest{KeyID: info.Address, EccType: owcrypt.ECC_CURVE_ED25519, Message: message})
+	if err != nil {
+		t.Fatalf("Signer().Sign() unexpected error = %v", err)
+	}
+	if owcrypt.Verify(key.PublicKey, nil, message, signature, owcrypt.ECC_CURVE_ED25519) != owcrypt.SUCCESS {
+		t.Errorf("Signer().Sign() signature verify failed")
+	}
+
+	if err := ks.Delete(info.Address, "secret"); err != nil {
+		t.Fatalf("Delete() unexpected error = %v", err)
+	}
+	if list, _ := ks.List(); len(list) != 0 {
+		t.Errorf("List() after Delete() = %v, want empty", list)
+	}
+}
+
+func TestDecryptKey_Tampered(t *testing.T) {
+	key, _ := NewKey(addrdec.Default)
+	keyfile, err := EncryptKey(key, "secret", LightScryptN, LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, _ := NewKey(addrdec.Default)
+	tampered := []byte(strings.Replace(string(keyfile), key.Address, other.Address, -1))
+	if _, err := DecryptKey(tampered, "secret", addrdec.Default); err == nil {
+		t.Errorf("DecryptKey() of key file with replaced address want error")
+	}
+
+	priv := make([]byte, PrivateKeyLen)
+	priv[0] = 1
+	if _, err := NewKeyFromPrivateKey(priv, addrdec.Default); err == nil {
+		t.Errorf("NewKeyFromPrivateKey() of unclamped key want error")
+	}
+}
+
+func TestKeyStore_Network(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	params, _ := addrdec.DevNetParams.WithPrefix("1446", "f0")
+	devnet := addrdec.NewAddressDecoderV2(params)
+	mainnet := NewKeyStore(dir, addrdec.Default, LightScryptN, LightScryptP)
+	private := NewKeyStore(dir, devnet, LightScryptN, LightScryptP)
+
+	key, _ := NewKey(devnet)
+	wif, _ := devnet.PrivateKeyToWIF(key.PrivateKey, false)
+	info, err := private.ImportWIF(wif, "secret")
+	if err != nil || info.Address != key.Address || !devnet.AddressVerify(info.Address) {
+		t.Fatalf("ImportWIF() = %v, error = %v, want %s", info, err, key.Address)
+	}
+	if _, err := mainnet.ImportWIF(wif, "secret"); err == nil {
+		t.Errorf("ImportWIF() of devnet WIF on mainnet want error")
+	}
+
+	//同一目录下其他网络的密钥不列出，也不能解密
+	mainInfo, _ := mainnet.Import(key.PrivateKey, "secret")
+	if list, _ := private.List(); len(list) != 1 || list[0].Address != info.Address {
+		t.Errorf("List() of devnet = %v, want %s", list, info.Address)
+	}
+	if list, _ := mainnet.List(); len(list) != 1 || list[0].Address != mainInfo.Address {
+		t.Errorf("List() of mainnet = %v, want %s", list, mainInfo.Address)
+	}
+	keyfile, _ := private.Export(info.Address, "secret", "secret")
+	if _, err := DecryptKey(keyfile, "secret", addrdec.Default); err == nil {
+		t.Errorf("DecryptKey() of devnet key file on mainnet want error")
+	}
+}
@@ -1,7 +1,6 @@
 package rpc
 
 import (
-	"encoding/hex"
 	"encoding/json"
 	"strconv"
 
@@ -21,29 +20,27 @@ type TxResponse struct {
 }
 
 func (txr *TxResponse) UnmarshalJSON(data []byte) error {
-	type Alias TxResponse
-	aux := &struct {
-		Hash string `json:"hash"`
-		*Alias
-	}{
-		Alias: (*Alias)(txr),
-	}
-	if aux.Tx == nil {
-		aux.Alias.Tx = &crypto.Tx{}
-	}
+	// crypto.Tx has its own UnmarshalJSON, decode it separately so that it does not shadow the response fields
+	aux := struct {
+		Size               uint32 `json:"size"`
+		Block              string `json:"block"`
+		Confirmed          uint32 `json:"confirmed"`
+		ConfirmedTimestamp uint32 `json:"confirmed_timestamp"`
+		Total              int    `json:"total,omitempty"`
+	}{}
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
-	pHash, err := hex.DecodeString(aux.Hash)
-	if err != nil {
+	tx := &crypto.Tx{}
+	if err := json.Unmarshal(data, tx); err != nil {
 		return err
 	}
-
-	var hash [32]byte
-	if len(pHash) == 32 {
-		copy(hash[:], pHash[:32])
-	}
-	txr.Hash = hash
+	txr.Size = aux.Size
+	txr.Block = aux.Block
+	txr.Confirmed = aux.Confirmed
+	txr.ConfirmedTimestamp = aux.ConfirmedTimestamp
+	txr.Total = aux.Total
+	txr.Tx = tx
 	return nil
 }
 
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 )
@@ -85,3 +86,18 @@ func TestTx_GetHashListByHeight(t *testing.T) {
 		})
 	}
 }
+
+func TestTxResponse_UnmarshalJSON(t *testing.T) {
+	data := `[{"hash":"0101010101010101010101010101010101010101010101010101010101010101","block":"abc","confirmed":3,"size":10,"version":1,"tx_in":[],"tx_out":[]}]`
+	response := make([]TxResponse, 0)
+	if err := json.Unmarshal([]byte(data), &response); err != nil {
+		t.Fatalf("UnmarshalJSON() error = %v", err)
+	}
+	got := response[0]
+	if got.Block != "abc" || got.Confirmed != 3 || got.Size != 10 {
+		t.Errorf("UnmarshalJSON() response fields = %+v", got)
+	}
+	if got.Tx == nil || got.Version != 1 || got.Hash != [32]byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1} {
+		t.Errorf("UnmarshalJSON() tx = %+v", got.Tx)
+	}
+}
@@ -0,0 +1,54 @@
+package txsigner
+
+import (
+	"fmt"
+)
+
+// SignRequest identify the key and message to sign
+type SignRequest struct {
+	KeyID   string `json:"keyID"`   // key identifier, e.g. wallet ID or key label
+	HDPath  string `json:"hdPath"`  // derivation path under the key, empty for non-HD keys
+	EccType uint32 `json:"eccType"` // curve type
+	Message []byte `json:"message"` // message to sign, usually MsgForSign of an input
+}
+
+// Signer sign message with key identified by key ID and HD path, the private key may never leave the signer
+type Signer interface {
+	Sign(req *SignRequest) ([]byte, error)
+}
+
+// KeyFunc return private key bytes of key ID and HD path
+type KeyFunc func(keyID, hdPath string) ([]byte, error)
+
+// LocalSigner sign with private key held in process
+type LocalSigner struct {
+	keyFunc KeyFunc
+}
+
+// NewLocalSigner create local signer which load private key by keyFunc
+func NewLocalSigner(keyFunc KeyFunc) *LocalSigner {
+	return &LocalSigner{keyFunc: keyFunc}
+}
+
+// NewStaticSigner create local signer of fixed private keys, which index by key ID
+func NewStaticSigner(keys map[string][]byte) *LocalSigner {
+	return NewLocalSigner(func(keyID, hdPath string) ([]byte, error) {
+		key, ok := keys[keyID]
+		if !ok {
+			return nil, fmt.Errorf("key %s not found", keyID)
+		}
+		return key, nil
+	})
+}
+
+// Sign implement Signer
+func (signer *LocalSigner) Sign(req *SignRequest) ([]byte, error) {
+	if req == nil {
+		return nil, fmt.Errorf("sign request is empty")
+	}
+	privateKey, err := signer.keyFunc(req.KeyID, req.HDPath)
+	if err != nil {
+		return nil, err
+	}
+	return Default.SignTransactionHash(req.Message, privateKey, req.EccType)
+}
@@ -0,0 +1,77 @@
+package txsigner
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	owcrypt "github.com/blocktree/go-owcrypt"
+)
+
+type testPKCS11Session struct {
+	keys map[string][]byte
+}
+
+func (s *testPKCS11Session) FindPrivateKey(label string) (PKCS11ObjectHandle, error) {
+	if _, ok := s.keys[label]; !ok {
+		return 0, fmt.Errorf("object not found")
+	}
+	return PKCS11ObjectHandle(len(label)), nil
+}
+
+func (s *testPKCS11Session) Sign(mechanism PKCS11Mechanism, key PKCS11ObjectHandle, message []byte) ([]byte, error) {
+	if mechanism != CKM_EDDSA {
+		return nil, fmt.Errorf("mechanism invalid")
+	}
+	for label, priv := range s.keys {
+		if PKCS11ObjectHandle(len(label)) == key {
+			return Default.SignTransactionHash(message, priv, owcrypt.ECC_CURVE_ED25519)
+		}
+	}
+	return nil, fmt.Errorf("object handle invalid")
+}
+
+func TestSigner_Sign(t *testing.T) {
+
+	key := newTestKey(t, 1)
+	message := []byte("message for sign")
+
+	local := NewStaticSigner(map[string][]byte{"wallet": key.priv})
+
+	server := httptest.NewServer(NewRemoteSignerServer(local, "token"))
+	defer server.Close()
+
+	signers := map[string]Signer{
+		"local":  local,
+		"remote": NewRemoteSigner(server.URL, "token", 5*time.Second),
+		"pkcs11": NewPKCS11Signer(&testPKCS11Session{keys: map[string][]byte{"wallet/m/44'/5655640'/0'/0/0": key.priv}}, nil),
+	}
+
+	for name, signer := range signers {
+		signature, err := signer.Sign(&SignRequest{
+			KeyID:   "wallet",
+			HDPath:  "m/44'/5655640'/0'/0/0",
+			EccType: owcrypt.ECC_CURVE_ED25519,
+			Message: message,
+		})
+		if err != nil {
+			t.Errorf("%s: Sign() unexpected error = %v", name, err)
+			continue
+		}
+		if owcrypt.Verify(key.pub, nil, message, signature, owcrypt.ECC_CURVE_ED25519) != owcrypt.SUCCESS {
+			t.Errorf("%s: Sign() signature verify failed", name)
+		}
+
+		_, err = signer.Sign(&SignRequest{KeyID: "unknown", EccType: owcrypt.ECC_CURVE_ED25519, Message: message})
+		if err == nil {
+			t.Errorf("%s: Sign() with unknown key want error", name)
+		}
+	}
+
+	unauthorized := NewRemoteSigner(server.URL, "wrong", 5*time.Second)
+	_, err := unauthorized.Sign(&SignRequest{KeyID: "wallet", EccType: owcrypt.ECC_CURVE_ED25519, Message: message})
+	if err == nil {
+		t.Errorf("Sign() with wrong token want error")
+	}
+}
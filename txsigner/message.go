@@ -0,0 +1,39 @@
+package txsigner
+
+import (
+	"crypto/sha256"
+	"errors"
+	"strconv"
+
+	owcrypt "github.com/blocktree/go-owcrypt"
+)
+
+// MessagePrefix domain separates signed messages from transactions
+const MessagePrefix = "\x19Velas Signed Message:\n"
+
+// MessageHash returns sha256(MessagePrefix | decimal length of message | message), which is the payload signed for a message.
+// The payload is always 32 bytes while Tx.MsgForSign is at least 44 bytes, so a message signature can never be replayed as
+// an input signature.
+func MessageHash(message []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(MessagePrefix))
+	h.Write([]byte(strconv.Itoa(len(message))))
+	h.Write(message)
+	return h.Sum(nil)
+}
+
+// SignMessage signs the envelope of message with ed25519 private key
+func (singer *TransactionSigner) SignMessage(message []byte, privateKey []byte) ([]byte, error) {
+	return singer.SignTransactionHash(MessageHash(message), privateKey, owcrypt.ECC_CURVE_ED25519)
+}
+
+// VerifyMessage verifies the signature of message envelope against the ed25519 public key
+func (singer *TransactionSigner) VerifyMessage(message, signature, pubkey []byte) error {
+	if len(pubkey) != 32 {
+		return errors.New("Invalid public key")
+	}
+	if owcrypt.SUCCESS != owcrypt.Verify(pubkey, nil, MessageHash(message), signature, owcrypt.ECC_CURVE_ED25519) {
+		return errors.New("Message signature verify failed")
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package txsigner
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	owcrypt "github.com/blocktree/go-owcrypt"
+)
+
+func TestTransactionSigner_SignMessage(t *testing.T) {
+	key := newTestKey(t, 1)
+	message := []byte("I own this address")
+
+	signature, err := Default.SignMessage(message, key.priv)
+	if err != nil {
+		t.Fatalf("SignMessage() unexpected error = %v", err)
+	}
+	if err := Default.VerifyMessage(message, signature, key.pub); err != nil {
+		t.Errorf("VerifyMessage() unexpected error = %v", err)
+	}
+	if err := Default.VerifyMessage([]byte("I own this address!"), signature, key.pub); err == nil {
+		t.Errorf("VerifyMessage() of another message want error")
+	}
+	if err := Default.VerifyMessage(message, signature, newTestKey(t, 2).pub); err == nil {
+		t.Errorf("VerifyMessage() with another public key want error")
+	}
+
+	//the raw message signature does not verify, the envelope is always signed
+	raw, _ := Default.SignTransactionHash(message, key.priv, owcrypt.ECC_CURVE_ED25519)
+	if err := Default.VerifyMessage(message, raw, key.pub); err == nil {
+		t.Errorf("VerifyMessage() of raw signature want error")
+	}
+
+	//a message that equals a transaction sign message is still enveloped
+	trx := &crypto.Tx{Version: 1}
+	msg := trx.MsgForSign([32]byte{1}, 0)
+	if bytes.Equal(MessageHash(msg), msg) || len(MessageHash(msg)) >= len(msg) {
+		t.Errorf("MessageHash() collides with MsgForSign")
+	}
+}
@@ -0,0 +1,85 @@
+package txsigner
+
+import (
+	"fmt"
+
+	owcrypt "github.com/blocktree/go-owcrypt"
+)
+
+// PKCS11Mechanism is the CK_MECHANISM_TYPE of PKCS#11
+type PKCS11Mechanism uint
+
+// PKCS11ObjectHandle is the CK_OBJECT_HANDLE of PKCS#11
+type PKCS11ObjectHandle uint
+
+// CKM_EDDSA mechanism of PKCS#11 v3.0
+const CKM_EDDSA PKCS11Mechanism = 0x1057
+
+// PKCS11Session is the subset of a logged in PKCS#11 session used for signing,
+// implemented by the binding of the HSM vendor, e.g. C_FindObjects and C_SignInit/C_Sign
+type PKCS11Session interface {
+	// FindPrivateKey find private key object by CKA_LABEL
+	FindPrivateKey(label string) (PKCS11ObjectHandle, error)
+	// Sign sign message with private key object in one part
+	Sign(mechanism PKCS11Mechanism, key PKCS11ObjectHandle, message []byte) ([]byte, error)
+}
+
+// PKCS11LabelFunc return CKA_LABEL of key ID and HD path
+type PKCS11LabelFunc func(keyID, hdPath string) string
+
+// PKCS11Signer sign by private key objects stored in HSM
+type PKCS11Signer struct {
+	session   PKCS11Session
+	labelFunc PKCS11LabelFunc
+}
+
+// NewPKCS11Signer create PKCS#11 signer, labelFunc is optional, label is {keyID}/{hdPath} by default
+func NewPKCS11Signer(session PKCS11Session, labelFunc PKCS11LabelFunc) *PKCS11Signer {
+	if labelFunc == nil {
+		labelFunc = defaultPKCS11Label
+	}
+	return &PKCS11Signer{session: session, labelFunc: labelFunc}
+}
+
+// Sign implement Signer
+func (signer *PKCS11Signer) Sign(req *SignRequest) ([]byte, error) {
+	if req == nil {
+		return nil, fmt.Errorf("sign request is empty")
+	}
+	if len(req.Message) == 0 {
+		return nil, fmt.Errorf("No message to sign")
+	}
+
+	mechanism, err := pkcs11Mechanism(req.EccType)
+	if err != nil {
+		return nil, err
+	}
+
+	label := signer.labelFunc(req.KeyID, req.HDPath)
+	key, err := signer.session.FindPrivateKey(label)
+	if err != nil {
+		return nil, fmt.Errorf("find private key %s failed: %v", label, err)
+	}
+
+	signature, err := signer.session.Sign(mechanism, key, req.Message)
+	if err != nil {
+		return nil, fmt.Errorf("sign with private key %s failed: %v", label, err)
+	}
+	return signature, nil
+}
+
+func pkcs11Mechanism(eccType uint32) (PKCS11Mechanism, error) {
+	switch eccType {
+	case owcrypt.ECC_CURVE_ED25519:
+		return CKM_EDDSA, nil
+	default:
+		return 0, fmt.Errorf("ecc type %d is not supported by PKCS#11 signer", eccType)
+	}
+}
+
+func defaultPKCS11Label(keyID, hdPath string) string {
+	if len(hdPath) == 0 {
+		return keyID
+	}
+	return keyID + "/" + hdPath
+}
@@ -0,0 +1,152 @@
+package txsigner
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gopkg.in/resty.v1"
+)
+
+const remoteSignPath = "/api/v1/sign"
+
+// remoteSignRequest is the wire format of SignRequest, message is hex encoded
+type remoteSignRequest struct {
+	KeyID   string `json:"keyID"`
+	HDPath  string `json:"hdPath"`
+	EccType uint32 `json:"eccType"`
+	Message string `json:"message"`
+}
+
+// remoteSignResponse is the wire format of sign result, signature is hex encoded
+type remoteSignResponse struct {
+	Signature string `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RemoteSigner sign by a remote HTTP signing service, the private key never enter the wallet process
+type RemoteSigner struct {
+	baseAddress string
+	token       string
+	client      *resty.Client
+}
+
+// NewRemoteSigner create remote signer of signing service at baseAddress, token is sent as bearer token if not empty
+func NewRemoteSigner(baseAddress, token string, timeout time.Duration) *RemoteSigner {
+	client := resty.New()
+	if timeout > 0 {
+		client.SetTimeout(timeout)
+	}
+	return &RemoteSigner{
+		baseAddress: strings.TrimSuffix(baseAddress, "/"),
+		token:       token,
+		client:      client,
+	}
+}
+
+// Sign implement Signer
+func (signer *RemoteSigner) Sign(req *SignRequest) ([]byte, error) {
+	if req == nil {
+		return nil, fmt.Errorf("sign request is empty")
+	}
+
+	r := signer.client.R().SetBody(&remoteSignRequest{
+		KeyID:   req.KeyID,
+		HDPath:  req.HDPath,
+		EccType: req.EccType,
+		Message: hex.EncodeToString(req.Message),
+	})
+	if len(signer.token) > 0 {
+		r.SetAuthToken(signer.token)
+	}
+
+	resp, err := r.Post(signer.baseAddress + remoteSignPath)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer request failed: %v", err)
+	}
+
+	var result remoteSignResponse
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		return nil, fmt.Errorf("remote signer response is invalid, status: %d", resp.StatusCode())
+	}
+	if resp.StatusCode() != http.StatusOK || len(result.Error) > 0 {
+		return nil, fmt.Errorf("remote signer refused, status: %d, error: %s", resp.StatusCode(), result.Error)
+	}
+
+	signature, err := hex.DecodeString(result.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer signature is invalid: %v", err)
+	}
+	return signature, nil
+}
+
+// RemoteSignerServer is a stand-in signing service which serves RemoteSigner by any Signer,
+// used for development and testing in place of the production signing service
+type RemoteSignerServer struct {
+	signer Signer
+	token  string
+}
+
+// NewRemoteSignerServer create stand-in signing service, requests must carry token as bearer token if not empty
+func NewRemoteSignerServer(signer Signer, token string) *RemoteSignerServer {
+	return &RemoteSignerServer{signer: signer, token: token}
+}
+
+// ListenAndServe listen on addr and serve sign requests
+func (server *RemoteSignerServer) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle(remoteSignPath, server)
+	return http.ListenAndServe(addr, mux)
+}
+
+// ServeHTTP implement http.Handler
+func (server *RemoteSignerServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		server.reply(w, http.StatusMethodNotAllowed, nil, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+
+	if len(server.token) > 0 && r.Header.Get("Authorization") != "Bearer "+server.token {
+		server.reply(w, http.StatusUnauthorized, nil, fmt.Errorf("unauthorized"))
+		return
+	}
+
+	var req remoteSignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.reply(w, http.StatusBadRequest, nil, fmt.Errorf("invalid request: %v", err))
+		return
+	}
+
+	message, err := hex.DecodeString(req.Message)
+	if err != nil {
+		server.reply(w, http.StatusBadRequest, nil, fmt.Errorf("invalid message: %v", err))
+		return
+	}
+
+	signature, err := server.signer.Sign(&SignRequest{
+		KeyID:   req.KeyID,
+		HDPath:  req.HDPath,
+		EccType: req.EccType,
+		Message: message,
+	})
+	if err != nil {
+		server.reply(w, http.StatusUnprocessableEntity, nil, err)
+		return
+	}
+
+	server.reply(w, http.StatusOK, signature, nil)
+}
+
+func (server *RemoteSignerServer) reply(w http.ResponseWriter, status int, signature []byte, err error) {
+	result := remoteSignResponse{Signature: hex.EncodeToString(signature)}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&result)
+}
@@ -1,6 +1,7 @@
 package txsigner
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -43,6 +44,8 @@ func (singer *TransactionSigner) SignTransactionHash(message []byte, privateKey
 
 // VerifyAndCombineTransaction verify signature
 // required
+// Signatures are bound to inputs by public key: the public key must hash to the wallet address of the input and
+// the signature must verify against the message of that input. The order of sigPub does not matter.
 func (singer *TransactionSigner) VerifyAndCombineTransaction(emptyTrans string, sigPub []SigPub) (bool, string, error) {
 	trx := crypto.Tx{}
 
@@ -52,23 +55,54 @@ func (singer *TransactionSigner) VerifyAndCombineTransaction(emptyTrans string,
 		return false, "", errors.New("Invalid empty transaction data")
 	}
 
-	if sigPub == nil || len(sigPub) == 0 || len(sigPub) != len(trx.Inputs) {
+	if len(sigPub) == 0 {
 		return false, "", errors.New("Signatures are not enough to unlock transaction")
 	}
 
-	for i, s := range sigPub {
-		utxo := trx.Inputs[i]
+	used := make([]bool, len(sigPub))
+	missing := make([]int, 0)
+
+	for i, utxo := range trx.Inputs {
+		inputHash, err := AddressHash(utxo.WalletAddress)
+		if err != nil {
+			return false, "", fmt.Errorf("Invalid wallet address of input %d: %v", i, err)
+		}
+
 		msg := trx.MsgForSign(utxo.PreviousOutput.Hash, utxo.PreviousOutput.Index)
 
-		fmt.Println("msg:", hex.EncodeToString(msg))
-		fmt.Println("sig:", hex.EncodeToString(s.Signature))
-		fmt.Println("pub:", hex.EncodeToString(s.Pubkey))
-		if owcrypt.SUCCESS != owcrypt.Verify(s.Pubkey, nil, msg, s.Signature, owcrypt.ECC_CURVE_ED25519) {
-			return false, "", errors.New("Signature verify failed")
+		matched := false
+		for j, s := range sigPub {
+			if used[j] || !bytes.Equal(PublicKeyHash(s.Pubkey), inputHash) {
+				continue
+			}
+			if owcrypt.SUCCESS != owcrypt.Verify(s.Pubkey, nil, msg, s.Signature, owcrypt.ECC_CURVE_ED25519) {
+				continue
+			}
+			utxo.Script = s.Signature
+			utxo.PublicKey = s.Pubkey
+			trx.Inputs[i] = utxo
+			used[j] = true
+			matched = true
+			break
 		}
-		utxo.Script = s.Signature
-		utxo.PublicKey = s.Pubkey
-		trx.Inputs[i] = utxo
+		if !matched {
+			missing = append(missing, i)
+		}
+	}
+
+	unmatched := make([]int, 0)
+	for j, u := range used {
+		if !u {
+			unmatched = append(unmatched, j)
+		}
+	}
+
+	if len(missing) > 0 {
+		return false, "", fmt.Errorf("Signature verify failed, inputs %v have no valid signature, signatures %v match no input", missing, unmatched)
+	}
+
+	if len(unmatched) > 0 {
+		return false, "", fmt.Errorf("Signature verify failed, signatures %v match no input", unmatched)
 	}
 
 	txHash := trx.GenerateHash()
@@ -81,3 +115,21 @@ func (singer *TransactionSigner) VerifyAndCombineTransaction(emptyTrans string,
 
 	return true, hex.EncodeToString(txBytes), nil
 }
+
+// PublicKeyHash return ripemd160(sha256(pubkey)), which is the hash part of velas address
+func PublicKeyHash(pubkey []byte) []byte {
+	return owcrypt.Hash(pubkey, 0, owcrypt.HASH_ALG_HASH160)
+}
+
+// AddressHash return the public key hash of decoded velas address, which layout is prefix | hash 20 bytes | checksum 4 bytes
+func AddressHash(address []byte) ([]byte, error) {
+	if len(address) < 24 {
+		return nil, fmt.Errorf("invalid address length %d", len(address))
+	}
+	payload, checksum := address[:len(address)-4], address[len(address)-4:]
+	sum := crypto.DHASH(payload)
+	if !bytes.Equal(sum[:4], checksum) {
+		return nil, errors.New("invalid address checksum")
+	}
+	return payload[len(payload)-20:], nil
+}
@@ -0,0 +1,91 @@
+package txsigner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	owcrypt "github.com/blocktree/go-owcrypt"
+)
+
+type testKey struct {
+	priv    []byte
+	pub     []byte
+	address string
+}
+
+func newTestKey(t *testing.T, seed byte) testKey {
+	priv := make([]byte, 32)
+	for i := range priv {
+		priv[i] = seed
+	}
+	//ed25519私钥为已规整的标量
+	priv[0] &= 248
+	priv[31] &= 63
+	priv[31] |= 64
+	pub, ret := owcrypt.GenPubkey(priv, owcrypt.ECC_CURVE_ED25519)
+	if ret != owcrypt.SUCCESS {
+		t.Fatalf("GenPubkey failed")
+	}
+	address, _ := addrdec.Default.AddressEncode(pub)
+	return testKey{priv: priv, pub: pub, address: address}
+}
+
+func testSignInputs(t *testing.T, trx *crypto.Tx, keys []testKey) []SigPub {
+	sigPub := make([]SigPub, 0)
+	for i, in := range trx.Inputs {
+		msg := trx.MsgForSign(in.PreviousOutput.Hash, in.PreviousOutput.Index)
+		signature, err := Default.SignTransactionHash(msg, keys[i].priv, owcrypt.ECC_CURVE_ED25519)
+		if err != nil {
+			t.Fatalf("SignTransactionHash() error = %v", err)
+		}
+		sigPub = append(sigPub, SigPub{Signature: signature, Pubkey: keys[i].pub})
+	}
+	return sigPub
+}
+
+func TestTransactionSigner_VerifyAndCombineTransaction(t *testing.T) {
+	alice := newTestKey(t, 1)
+	bob := newTestKey(t, 2)
+
+	unspents := []*crypto.TransactionInputOutpoint{
+		{Hash: [32]byte{1}, Index: 0, Value: 1000, Address: alice.address},
+		{Hash: [32]byte{2}, Index: 1, Value: 2000, Address: bob.address},
+		{Hash: [32]byte{3}, Index: 0, Value: 3000, Address: alice.address},
+	}
+	trx, err := crypto.NewTransaction(unspents, map[string]uint64{bob.address: 5000}, alice.address, 100)
+	if err != nil {
+		t.Fatalf("NewTransaction() error = %v", err)
+	}
+	emptyTrans, _ := trx.MarshalJSON()
+
+	sigPub := testSignInputs(t, trx, []testKey{alice, bob, alice})
+
+	//签名顺序与输入顺序无关
+	reordered := []SigPub{sigPub[2], sigPub[1], sigPub[0]}
+	pass, signed, err := Default.VerifyAndCombineTransaction(string(emptyTrans), reordered)
+	if !pass || err != nil || len(signed) == 0 {
+		t.Fatalf("VerifyAndCombineTransaction() reordered = %v, %v", pass, err)
+	}
+
+	//缺少签名的输入需要明确报告
+	pass, _, err = Default.VerifyAndCombineTransaction(string(emptyTrans), sigPub[:2])
+	if pass || err == nil || !strings.Contains(err.Error(), "inputs [2]") {
+		t.Errorf("VerifyAndCombineTransaction() missing = %v, %v", pass, err)
+	}
+
+	//公钥与输入地址不匹配
+	wrong := []SigPub{sigPub[0], {Signature: sigPub[1].Signature, Pubkey: alice.pub}, sigPub[2]}
+	pass, _, err = Default.VerifyAndCombineTransaction(string(emptyTrans), wrong)
+	if pass || err == nil || !strings.Contains(err.Error(), "inputs [1]") || !strings.Contains(err.Error(), "signatures [1]") {
+		t.Errorf("VerifyAndCombineTransaction() wrong pubkey = %v, %v", pass, err)
+	}
+
+	//多余的签名
+	extra := append(append([]SigPub{}, sigPub...), sigPub[0])
+	pass, _, err = Default.VerifyAndCombineTransaction(string(emptyTrans), extra)
+	if pass || err == nil || !strings.Contains(err.Error(), "signatures [3]") {
+		t.Errorf("VerifyAndCombineTransaction() extra = %v, %v", pass, err)
+	}
+}
@@ -16,11 +16,10 @@
 package velas
 
 import (
-	"fmt"
-
 	"github.com/assetsadapterstore/velas-adapter/addrdec"
 )
 
+//AddressDecoder 地址解析器，使用钱包管理者的AddressDecoderV2
 type AddressDecoder struct {
 	wm *WalletManager //钱包管理者
 }
@@ -34,49 +33,41 @@ func NewAddressDecoder(wm *WalletManager) *AddressDecoder {
 
 //PrivateKeyToWIF 私钥转WIF
 func (decoder *AddressDecoder) PrivateKeyToWIF(priv []byte, isTestnet bool) (string, error) {
-
-	cfg := addrdec.VLX_mainnetPrivateWIFCompressed
-	if decoder.wm.Config.IsTestNet {
-		cfg = addrdec.VLX_testnetPrivateWIFCompressed
-	}
-
-	wif, _ := addrdec.Default.AddressEncode(priv, cfg)
-
-	return wif, nil
-
+	return decoder.wm.DecoderV2.PrivateKeyToWIF(priv, isTestnet)
 }
 
 //PublicKeyToAddress 公钥转地址
 func (decoder *AddressDecoder) PublicKeyToAddress(pub []byte, isTestnet bool) (string, error) {
-	addrdec.Default.IsTestNet = decoder.wm.Config.IsTestNet
-	address, err := addrdec.Default.AddressEncode(pub)
-	if err != nil {
-		return "", err
-	}
-
-	return address, nil
+	return decoder.wm.DecoderV2.PublicKeyToAddress(pub, isTestnet)
 }
 
 //RedeemScriptToAddress 多重签名赎回脚本转地址
 func (decoder *AddressDecoder) RedeemScriptToAddress(pubs [][]byte, required uint64, isTestnet bool) (string, error) {
-
-	return "", fmt.Errorf("RedeemScriptToAddress is not supported")
-
+	return decoder.wm.DecoderV2.RedeemScriptToAddress(pubs, required, isTestnet)
 }
 
 //WIFToPrivateKey WIF转私钥
 func (decoder *AddressDecoder) WIFToPrivateKey(wif string, isTestnet bool) ([]byte, error) {
+	return decoder.wm.DecoderV2.WIFToPrivateKey(wif, isTestnet)
+}
 
-	cfg := addrdec.VLX_mainnetPrivateWIFCompressed
-	if decoder.wm.Config.IsTestNet {
-		cfg = addrdec.VLX_testnetPrivateWIFCompressed
-	}
+//ValidateAddress 校验地址，返回地址类型及公钥哈希，无效时返回*addrdec.AddressError
+func (decoder *AddressDecoder) ValidateAddress(address string) (*addrdec.AddressInfo, error) {
+	return decoder.wm.DecoderV2.ValidateAddress(address)
+}
 
-	priv, err := addrdec.Default.AddressDecode(wif, cfg)
-	if err != nil {
-		return nil, err
-	}
+//AddressVerify 地址校验
+func (decoder *AddressDecoder) AddressVerify(address string, opts ...interface{}) bool {
+	return decoder.wm.DecoderV2.AddressVerify(address, opts...)
+}
 
-	return priv, err
+//DetectAddressFormat 判断地址为旧版地址或0x地址，不做校验
+func (decoder *AddressDecoder) DetectAddressFormat(address string) string {
+	return addrdec.DetectAddressFormat(address)
+}
 
+//ValidateHexAddress 校验0x地址，混合大小写时按EIP-55校验
+func (decoder *AddressDecoder) ValidateHexAddress(address string) error {
+	_, err := addrdec.ValidateHexAddress(address)
+	return err
 }
@@ -17,14 +17,20 @@ package velas
 
 import (
 	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/astaxie/beego/config"
+	"github.com/blocktree/openwallet/openwallet"
+	"github.com/shopspring/decimal"
 )
 
 func TestAddressDecoder_AddressEncode(t *testing.T) {
-	addrdec.Default.IsTestNet = false
-
 	p2pk, _ := hex.DecodeString("VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty")
 	p2pkAddr, _ := addrdec.Default.AddressEncode(p2pk)
 	t.Logf("p2pkAddr: %s", p2pkAddr)
@@ -33,10 +39,235 @@ func TestAddressDecoder_AddressEncode(t *testing.T) {
 
 func TestAddressDecoder_AddressDecode(t *testing.T) {
 
-	addrdec.Default.IsTestNet = false
-
 	p2pkAddr := "VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty"
 	p2pkHash, _ := addrdec.Default.AddressDecode(p2pkAddr)
 	t.Logf("p2pkHash: %s", hex.EncodeToString(p2pkHash))
 
 }
+
+func TestAddressDecoder_ValidateAddress(t *testing.T) {
+
+	decoder := NewAddressDecoder(NewWalletManager())
+
+	valid := "VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty"
+	info, err := decoder.ValidateAddress(valid)
+	if err != nil {
+		t.Fatalf("ValidateAddress() unexpected error = %v", err)
+	}
+	if info.PayloadType != addrdec.PayloadTypeP2PKH || len(info.Hash) != 20 {
+		t.Errorf("ValidateAddress() = %+v", info)
+	}
+	if !decoder.AddressVerify(valid) {
+		t.Errorf("AddressVerify() = false, want true")
+	}
+
+	tests := []struct {
+		address string
+		reason  addrdec.AddressErrorReason
+	}{
+		{"", addrdec.ReasonEmpty},
+		{"VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZt0", addrdec.ReasonInvalidCharacter},
+		{"VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZ", addrdec.ReasonInvalidLength},
+		{"VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZtz", addrdec.ReasonInvalidChecksum},
+		{"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", addrdec.ReasonInvalidLength},
+		{"VKuzpTPbxHw2sUhLFzeghXMyZtKgyfUhfbT", addrdec.ReasonInvalidPrefix},
+	}
+
+	for _, test := range tests {
+		_, err := decoder.ValidateAddress(test.address)
+		reason, ok := addrdec.IsAddressError(err)
+		if !ok || reason != test.reason {
+			t.Errorf("ValidateAddress(%q) error = %v, want reason %s", test.address, err, test.reason)
+		}
+		if decoder.AddressVerify(test.address) {
+			t.Errorf("AddressVerify(%q) = true, want false", test.address)
+		}
+	}
+}
+
+func TestAddressDecoder_Network(t *testing.T) {
+
+	dataDir, err := ioutil.TempDir("", "velas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+
+	pub := make([]byte, 32)
+	pub[0] = 1
+
+	addresses := make(map[string]string)
+	for _, network := range []string{addrdec.NetworkMainnet, addrdec.NetworkTestnet, addrdec.NetworkDevnet} {
+		wm := NewWalletManager()
+		c, _ := config.NewConfigData("ini", []byte("network = "+network+"\ndataDir = "+dataDir))
+		if err := wm.LoadAssetsConfig(c); err != nil {
+			t.Fatalf("LoadAssetsConfig(%s) unexpected error = %v", network, err)
+		}
+		if wm.Config.ServerAPI != wm.Config.ChainParams.NodeURL || wm.Config.FixFees != wm.Config.ChainParams.DefaultFee {
+			t.Errorf("LoadAssetsConfig(%s) does not use network defaults", network)
+		}
+
+		address, _ := wm.Decoder.PublicKeyToAddress(pub, wm.Config.IsTestNet)
+		addresses[network] = address
+
+		info, err := wm.Decoder.ValidateAddress(address)
+		if err != nil || info.Address != address {
+			t.Errorf("ValidateAddress(%s) = %+v, error = %v", address, info, err)
+		}
+
+		priv := make([]byte, 32)
+		wif, _ := wm.Decoder.PrivateKeyToWIF(priv, wm.Config.IsTestNet)
+		if decoded, err := wm.Decoder.WIFToPrivateKey(wif, wm.Config.IsTestNet); err != nil || len(decoded) != 32 {
+			t.Errorf("WIFToPrivateKey(%s) error = %v", wif, err)
+		}
+	}
+
+	//测试网、开发网与主网编码相同，已有地址在各网络均有效
+	legacy := "VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty"
+	if addresses[addrdec.NetworkTestnet] != addresses[addrdec.NetworkMainnet] || addresses[addrdec.NetworkDevnet] != addresses[addrdec.NetworkMainnet] {
+		t.Errorf("addresses of networks = %v, want the same encoding", addresses)
+	}
+	testnet := NewWalletManager()
+	testnet.DecoderV2 = addrdec.NewAddressDecoderV2(&addrdec.TestNetParams)
+	if info, err := testnet.Decoder.ValidateAddress(legacy); err != nil || info.Address != legacy {
+		t.Errorf("ValidateAddress(%s) on testnet = %+v, error = %v", legacy, info, err)
+	}
+
+	//私有链配置前缀后，前缀不同的地址无效
+	private := NewWalletManager()
+	c, _ := config.NewConfigData("ini", []byte("network = devnet\naddressPrefix = 1446\nwifPrefix = f0\ndataDir = "+dataDir))
+	if err := private.LoadAssetsConfig(c); err != nil {
+		t.Fatalf("LoadAssetsConfig() with prefix unexpected error = %v", err)
+	}
+	privateAddress, _ := private.Decoder.PublicKeyToAddress(pub, true)
+	if !strings.HasPrefix(privateAddress, "d") || !private.Decoder.AddressVerify(privateAddress) {
+		t.Errorf("address with prefix 1446 = %s", privateAddress)
+	}
+	_, err = private.Decoder.ValidateAddress(legacy)
+	if reason, _ := addrdec.IsAddressError(err); reason != addrdec.ReasonInvalidPrefix {
+		t.Errorf("ValidateAddress() of mainnet address on private chain error = %v, want reason %s", err, addrdec.ReasonInvalidPrefix)
+	}
+	if addrdec.DevNetParams.AddressPrefix[0] != 15 {
+		t.Errorf("LoadAssetsConfig() modified DevNetParams")
+	}
+
+	//主网不识别私有链的地址
+	mainnet := NewWalletManager()
+	if mainnet.Decoder.AddressVerify(privateAddress) {
+		t.Errorf("AddressVerify() of private chain address on mainnet = true")
+	}
+
+	decoder := NewTransactionDecoder(private)
+	rawTx := &openwallet.RawTransaction{Account: &openwallet.AssetsAccount{AccountID: "account"}}
+	utxo := []*crypto.TransactionInputOutpoint{{Hash: [32]byte{1}, Value: 100000000, Address: privateAddress}}
+	to := map[string]decimal.Decimal{legacy: decimal.New(1, -1)}
+	err = decoder.createVLXRawTransaction(&testWalletDAI{}, rawTx, utxo, to, privateAddress, decimal.New(1, -3))
+	if err == nil {
+		t.Errorf("createVLXRawTransaction() to mainnet address on private chain want error")
+	}
+
+	for _, ini := range []string{"network = regtest", "addressPrefix = xyz", "wifPrefix = 0"} {
+		c, _ := config.NewConfigData("ini", []byte(ini))
+		if err := NewWalletManager().LoadAssetsConfig(c); err == nil {
+			t.Errorf("LoadAssetsConfig(%s) want error", ini)
+		}
+	}
+}
+
+func TestAddressDecoder_Concurrent(t *testing.T) {
+
+	private, _ := addrdec.DevNetParams.WithPrefix("1446", "")
+	mainnet := NewWalletManager()
+	devnet := NewWalletManager()
+	devnet.DecoderV2 = addrdec.NewAddressDecoderV2(private)
+
+	pub := make([]byte, 32)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if address, _ := mainnet.Decoder.PublicKeyToAddress(pub, false); !strings.HasPrefix(address, "V") {
+				t.Errorf("mainnet address = %s", address)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if address, _ := devnet.Decoder.PublicKeyToAddress(pub, true); !strings.HasPrefix(address, "d") {
+				t.Errorf("devnet address = %s", address)
+			}
+		}()
+	}
+	wg.Wait()
+
+	//修改网络参数不影响已创建的解析器
+	params := mainnet.DecoderV2.ChainParams()
+	params.AddressPrefix[0] = 0
+	if address, _ := mainnet.Decoder.PublicKeyToAddress(pub, false); !strings.HasPrefix(address, "V") {
+		t.Errorf("address after modifying params copy = %s", address)
+	}
+}
+
+func TestAddressDecoder_HexFormat(t *testing.T) {
+
+	wm := NewWalletManager()
+	decoder := wm.Decoder
+
+	legacy := "VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty"
+	hexAddress := "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	if decoder.DetectAddressFormat(legacy) != addrdec.FormatBase58 || decoder.DetectAddressFormat(hexAddress) != addrdec.FormatHex {
+		t.Errorf("DetectAddressFormat() did not tell legacy and hex addresses apart")
+	}
+
+	//EIP-55测试向量
+	hash, _ := hex.DecodeString(strings.ToLower(hexAddress[2:]))
+	if address := addrdec.ChecksumHexAddress(hash); address != hexAddress {
+		t.Errorf("ChecksumHexAddress() = %s, want %s", address, hexAddress)
+	}
+	if err := decoder.ValidateHexAddress(hexAddress); err != nil {
+		t.Errorf("ValidateHexAddress(%s) unexpected error = %v", hexAddress, err)
+	}
+	if err := decoder.ValidateHexAddress(strings.ToLower(hexAddress)); err != nil {
+		t.Errorf("ValidateHexAddress(lower) unexpected error = %v", err)
+	}
+
+	tests := []struct {
+		address string
+		reason  addrdec.AddressErrorReason
+	}{
+		{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", addrdec.ReasonInvalidChecksum},
+		{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeA", addrdec.ReasonInvalidLength},
+		{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeg", addrdec.ReasonInvalidCharacter},
+		{legacy, addrdec.ReasonInvalidPrefix},
+	}
+	for _, test := range tests {
+		err := decoder.ValidateHexAddress(test.address)
+		if reason, ok := addrdec.IsAddressError(err); !ok || reason != test.reason {
+			t.Errorf("ValidateHexAddress(%q) error = %v, want reason %s", test.address, err, test.reason)
+		}
+	}
+
+	//0x地址与旧版地址的密钥不同，不能互相转换
+	if _, err := decoder.ValidateAddress(hexAddress); err == nil {
+		t.Errorf("ValidateAddress(%s) want error", hexAddress)
+	} else if reason, _ := addrdec.IsAddressError(err); reason != addrdec.ReasonWrongFormat {
+		t.Errorf("ValidateAddress(%s) reason = %s, want %s", hexAddress, reason, addrdec.ReasonWrongFormat)
+	}
+
+	//UTXO交易单不接受0x接收地址
+	txDecoder := NewTransactionDecoder(wm)
+	if err := txDecoder.validateReceivers(&openwallet.RawTransaction{To: map[string]string{legacy: "1"}}); err != nil {
+		t.Errorf("validateReceivers() unexpected error = %v", err)
+	}
+	if err := txDecoder.validateReceivers(&openwallet.RawTransaction{To: map[string]string{legacy: "1", hexAddress: "0.5"}}); err == nil {
+		t.Errorf("validateReceivers() with 0x address want error")
+	}
+
+	//EVM交易单不接受旧版接收地址
+	if _, err := wm.evmParseReceiver(legacy); err == nil {
+		t.Errorf("evmParseReceiver(%s) want error", legacy)
+	}
+	if receiver, err := wm.evmParseReceiver(hexAddress); err != nil || receiver.Hex() != hexAddress {
+		t.Errorf("evmParseReceiver() = %s, error = %v, want %s", receiver.Hex(), err, hexAddress)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/assetsadapterstore/velas-adapter/rpc"
+)
+
+//BlockVerifyError 节点返回的区块内容与区块头不一致
+type BlockVerifyError struct {
+	Height uint64
+	Hash   string
+	Reason string
+}
+
+func (e *BlockVerifyError) Error() string {
+	return fmt.Sprintf("block %d %s is invalid: %s", e.Height, e.Hash, e.Reason)
+}
+
+//IsBlockVerifyError 判断错误是否因区块校验失败
+func IsBlockVerifyError(err error) bool {
+	_, ok := err.(*BlockVerifyError)
+	return ok
+}
+
+//VerifyBlock 校验区块高度、与上一区块的链接、交易数量、每笔交易的哈希及默克尔根
+//prevHash为上一区块的哈希，为空时不校验链接；区块头哈希及出块节点签名无法由交易内容得出，不在校验范围内
+//交易哈希及默克尔根规则需TestVerifyBlock_Mainnet用主网区块验证，规则不符时每个区块都校验失败
+func VerifyBlock(block *rpc.BlockResponse, height uint64, prevHash string) error {
+
+	if block == nil || block.Header == nil {
+		return &BlockVerifyError{Height: height, Reason: "block header is empty"}
+	}
+
+	header := block.Header
+	invalid := func(format string, args ...interface{}) error {
+		return &BlockVerifyError{Height: height, Hash: header.Hash, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	if uint64(header.Height) != height {
+		return invalid("header height is %d", header.Height)
+	}
+
+	if len(prevHash) > 0 && header.PrevBlock != prevHash {
+		return invalid("previous block is %s, want %s", header.PrevBlock, prevHash)
+	}
+
+	if int(header.TxnCount) != len(block.Transactions) {
+		return invalid("header has %d transactions, block has %d", header.TxnCount, len(block.Transactions))
+	}
+
+	hashes := make([][32]byte, 0, len(block.Transactions))
+	for i, trx := range block.Transactions {
+		if trx == nil {
+			return invalid("transaction %d is empty", i)
+		}
+		hash := trx.GenerateHash()
+		if hash != trx.Hash {
+			return invalid("transaction %d hash is %x, content hashes to %x", i, trx.Hash, hash)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	root := crypto.MerkleRoot(hashes)
+	if header.MerkleRoot != hex.EncodeToString(root[:]) {
+		return invalid("merkle root is %s, transactions hash to %x", header.MerkleRoot, root)
+	}
+
+	return nil
+}
+
+//VerifyBlockByHeight 查询并校验高度height的区块，以及与上一区块的链接
+func (wm *WalletManager) VerifyBlockByHeight(height uint64) (*rpc.BlockResponse, error) {
+
+	block, err := wm.GetBlock(height)
+	if err != nil {
+		return nil, err
+	}
+
+	prevHash := ""
+	if height > 0 {
+		prev, err := wm.GetBlock(height - 1)
+		if err != nil {
+			return nil, err
+		}
+		if prev.Header == nil {
+			return nil, fmt.Errorf("block %d not found", height-1)
+		}
+		prevHash = prev.Header.Hash
+	}
+
+	if err := VerifyBlock(block, height, prevHash); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/assetsadapterstore/velas-adapter/rpc"
+)
+
+//testVerifiedBlock 生成交易哈希及默克尔根一致的区块
+func testVerifiedBlock(height uint32, prevHash string, txCount int) *rpc.BlockResponse {
+	txs := make([]*crypto.Tx, 0, txCount)
+	for i := 0; i < txCount; i++ {
+		trx := &crypto.Tx{Version: 1, LockTime: uint32(i)}
+		trx.Outputs = []crypto.TransactionOutput{{Value: uint64(i + 1)}}
+		trx.Hash = trx.GenerateHash()
+		txs = append(txs, trx)
+	}
+	root := crypto.TxMerkleRoot(txs)
+	return &rpc.BlockResponse{
+		Header: &rpc.Header{
+			Hash:       "hash",
+			Height:     height,
+			PrevBlock:  prevHash,
+			MerkleRoot: hex.EncodeToString(root[:]),
+			TxnCount:   uint32(len(txs)),
+		},
+		Transactions: txs,
+	}
+}
+
+func TestVerifyBlock(t *testing.T) {
+
+	if err := VerifyBlock(testVerifiedBlock(10, "prev", 3), 10, "prev"); err != nil {
+		t.Errorf("VerifyBlock() unexpected error = %v", err)
+	}
+	if err := VerifyBlock(testVerifiedBlock(10, "prev", 0), 10, ""); err != nil {
+		t.Errorf("VerifyBlock() of empty block unexpected error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(block *rpc.BlockResponse)
+	}{
+		{"tampered transaction", func(block *rpc.BlockResponse) { block.Transactions[1].Outputs[0].Value = 100 }},
+		{"tampered transaction and hash", func(block *rpc.BlockResponse) {
+			block.Transactions[1].Outputs[0].Value = 100
+			block.Transactions[1].Hash = block.Transactions[1].GenerateHash()
+		}},
+		{"dropped transaction", func(block *rpc.BlockResponse) {
+			block.Transactions = block.Transactions[:2]
+			block.Header.TxnCount = 2
+		}},
+		{"reordered transactions", func(block *rpc.BlockResponse) {
+			block.Transactions[0], block.Transactions[1] = block.Transactions[1], block.Transactions[0]
+		}},
+		{"transaction count", func(block *rpc.BlockResponse) { block.Header.TxnCount = 4 }},
+		{"height", func(block *rpc.BlockResponse) { block.Header.Height = 11 }},
+		{"previous block", func(block *rpc.BlockResponse) { block.Header.PrevBlock = "fork" }},
+		{"empty header", func(block *rpc.BlockResponse) { block.Header = nil }},
+	}
+
+	for _, tt := range tests {
+		block := testVerifiedBlock(10, "prev", 3)
+		tt.modify(block)
+		if err := VerifyBlock(block, 10, "prev"); !IsBlockVerifyError(err) {
+			t.Errorf("VerifyBlock() of %s error = %v, want BlockVerifyError", tt.name, err)
+		}
+	}
+}
+
+//testMainnetBlocks 读取testdata中记录的主网区块，文件由以下命令生成：
+//vlxctl -server https://mainnet.velas.website -output json block <height> > velas/testdata/mainnet_block_<height>.json
+//testMainnetBlocks 读取velas/testdata记录的主网区块，没有记录时从主网节点记录一个已确认的区块，记录后提交到仓库
+func testMainnetBlocks(t *testing.T) map[string]*rpc.BlockResponse {
+	files, err := filepath.Glob(filepath.Join("testdata", "mainnet_block_*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		files = append(files, testRecordMainnetBlock(t))
+	}
+	blocks := make(map[string]*rpc.BlockResponse)
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var block rpc.BlockResponse
+		if err := json.Unmarshal(data, &block); err != nil || block.Header == nil {
+			t.Fatalf("%s is not a block: %v", file, err)
+		}
+		blocks[file] = &block
+	}
+	return blocks
+}
+
+//testRecordMainnetBlock 从主网节点记录最新高度之前第10个区块的区块头及全部交易
+func testRecordMainnetBlock(t *testing.T) string {
+	client := rpc.NewClient(addrdec.MainNetParams.NodeURL)
+	info, err := client.NodeInfo()
+	if err != nil || info == nil || info.Blockchain == nil {
+		t.Fatalf("no mainnet block recorded in velas/testdata and get node info from %s failed: %v", addrdec.MainNetParams.NodeURL, err)
+	}
+	height := uint32(info.Blockchain.Height - 10)
+	block, err := client.Block.GetByHeight(height)
+	if err != nil || block == nil || block.Header == nil {
+		t.Fatalf("get mainnet block %d failed: %v", height, err)
+	}
+	data, err := json.MarshalIndent(block, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join("testdata", fmt.Sprintf("mainnet_block_%d.json", height))
+	if err := os.MkdirAll("testdata", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("recorded mainnet block %d to %s", height, file)
+	return file
+}
+
+func TestVerifyBlock_Mainnet(t *testing.T) {
+
+	for file, block := range testMainnetBlocks(t) {
+		header := block.Header
+		if int(header.TxnCount) != len(block.Transactions) {
+			t.Errorf("%s: header txn count %d, block has %d transactions", file, header.TxnCount, len(block.Transactions))
+		}
+		//交易哈希规则
+		for i, trx := range block.Transactions {
+			if got := trx.GenerateHash(); got != trx.Hash {
+				t.Errorf("%s: transaction %d GenerateHash() = %x, node hash %x", file, i, got, trx.Hash)
+			}
+		}
+		//默克尔根的拼接规则、哈希函数及字节序
+		if root := crypto.TxMerkleRoot(block.Transactions); hex.EncodeToString(root[:]) != header.MerkleRoot {
+			t.Errorf("%s: TxMerkleRoot() = %x, header merkle root %s", file, root, header.MerkleRoot)
+		}
+		if err := VerifyBlock(block, uint64(header.Height), ""); err != nil {
+			t.Errorf("%s: VerifyBlock() unexpected error = %v", file, err)
+		}
+	}
+}
@@ -145,6 +145,14 @@ func (bs *VLXBlockScanner) ScanBlockTask() {
 			continue
 		}
 
+		//节点返回的区块不一致时不提取，下次任务重新获取
+		if bs.wm.Config.VerifyBlock {
+			if err := VerifyBlock(block, currentHeight, ""); err != nil {
+				bs.wm.Log.Std.Error("block scanner rejected block; unexpected error: %v", err)
+				break
+			}
+		}
+
 		isFork := false
 
 		//判断hash是否上一区块的hash
@@ -265,6 +273,17 @@ func (bs *VLXBlockScanner) scanBlock(height uint64) (*Block, error) {
 		return nil, err
 	}
 
+	if bs.wm.Config.VerifyBlock {
+		if err := VerifyBlock(block, height, ""); err != nil {
+			bs.wm.Log.Std.Error("block scanner rejected block; unexpected error: %v", err)
+
+			//记录未扫区块
+			unscanRecord := NewUnscanRecord(height, "", err.Error())
+			bs.SaveUnscanRecord(unscanRecord)
+			return nil, err
+		}
+	}
+
 	bs.wm.Log.Std.Info("block scanner scanning height: %d ...", block.Header.Height)
 
 	err = bs.BatchExtractTransaction(block.Header.Height, block.Header.Hash, block.Header.Timestamp, block.Transactions)
@@ -372,6 +391,11 @@ func (bs *VLXBlockScanner) BatchExtractTransaction(blockHeight uint32, blockHash
 		return fmt.Errorf("Block[%+v] have no transaction(BatchExtractTransaction)", blockHeight)
 	}
 
+	//确认已锁定的utxo被花费
+	if err := bs.wm.ConfirmSpentUTXO(txs); err != nil {
+		bs.wm.Log.Std.Error("block height: %d, confirm spent utxo failed. unexpected error: %v", blockHeight, err)
+	}
+
 	//生产通道
 	producer := make(chan ExtractResult)
 	defer close(producer)
@@ -783,8 +807,16 @@ func (bs *VLXBlockScanner) ExtractTransactionData(txid string, scanTargetFunc op
 	if !result.Success {
 		return nil, fmt.Errorf("extract transaction failed")
 	}
+	//附加交易的默克尔证明，交易对手可不依赖本节点验证交易已上链
+	proof, err := NewTxInclusionProof(block, txid)
+	if err != nil {
+		bs.wm.Log.Std.Error("transaction %s inclusion proof failed, ext param %s is omitted; unexpected error: %v", txid, InclusionProofExtParam, err)
+	}
 	extData := make(map[string][]*openwallet.TxExtractData)
 	for key, data := range result.extractData {
+		if proof != nil && data.Transaction != nil {
+			data.Transaction.SetExtParam(InclusionProofExtParam, proof)
+		}
 		txs := extData[key]
 		if txs == nil {
 			txs = make([]*openwallet.TxExtractData, 0)
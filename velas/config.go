@@ -18,7 +18,9 @@ package velas
 import (
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
 	"github.com/blocktree/go-owcrypt"
 	"github.com/blocktree/openwallet/common/file"
 )
@@ -31,9 +33,59 @@ const (
 	//默认配置内容
 	defaultConfig = `
 
-# RPC api url
+# network: mainnet, testnet or devnet, decides address prefix, WIF prefix and defaults of node url and fees
+Network = "mainnet"
+# RPC api url, default is the node url of network
 ServerAPI = ""
+# backend: utxo uses the legacy UTXO REST api, evm uses the Ethereum-compatible JSON-RPC
+Backend = "utxo"
+# JSON-RPC url of evm node, used by evm backend
+EVMServerAPI = ""
+# chain id of evm network, used to sign transactions
+EVMChainID = 0
+# gas limit of transfer transaction
+EVMGasLimit = 21000
+# fixed gas price in VLX, empty uses the price suggested by node
+EVMGasPrice = ""
+# confirmations of receipt to mark a transaction as confirmed
+EVMConfirmations = 1
+# fee mode: flat, size or feedback
+FeeMode = "flat"
+# fixed fees of one transaction, used by flat fee mode, default is the fees of network
 FixFees=0.001
+# fee rate per byte of signed transaction, used by size and feedback fee mode
+FeeRate=0.00000010
+# minimum fees of one transaction, used by size and feedback fee mode
+MinFees=0
+# maximum fee rate per byte raised by feedback fee mode, empty is 10 times of the larger of FeeRate and MinFees per byte of the smallest transaction
+MaxFeeRate=""
+# required by feedback fee mode, the validation error text returned by node for a transaction with insufficient fees, copy it from a rejected transaction
+FeeRejectionMessage = ""
+# verify transaction hashes and merkle root of every block before extraction, reject inconsistent blocks from node
+# the rules are not verified against mainnet blocks yet, keep false until TestVerifyBlock_Mainnet passes, otherwise scanning stops at every block
+VerifyBlock = false
+# other node api urls separated by comma, compared with ServerAPI by node monitor to detect forked or stuck nodes
+MonitorNodes = ""
+# seconds without height growth to mark a node as stuck
+NodeStuckTimeout=600
+# blocks behind the highest node to mark a node as lagging
+NodeMaxLag=10
+# seconds to reserve the utxo or evm nonce used by a built transaction
+UTXOReserveTimeout=600
+# seconds after submission to mark a transaction which is still in the pool of node as dropped
+TxDropTimeout=3600
+# seconds a transaction is missing from node before it is marked as dropped, or failed if its inputs are spent, longer than TxDropTimeout
+TxMissingTimeout=7200
+# minimum seconds between rebroadcasts of a transaction which disappeared from node
+TxRebroadcastInterval=120
+# signer mode: local signs with wallet HD key in process, remote signs by signing service
+SignerMode = "local"
+# signing service url, used by remote signer mode
+RemoteSignerURL = ""
+# bearer token of signing service
+RemoteSignerToken = ""
+# seconds to wait for signing service
+RemoteSignerTimeout=30
 `
 )
 
@@ -57,12 +109,62 @@ type WalletConfig struct {
 	CurveType uint32
 	//是否测试网
 	IsTestNet bool
+	//网络名称
+	Network string
+	//网络参数
+	ChainParams *addrdec.ChainParams
 	//最大的输入数量
 	MaxTxInputs int
 	//数据目录
 	DataDir string
 	//固定手续费
 	FixFees string
+	//手续费模型
+	FeeMode string
+	//每字节费率
+	FeeRate string
+	//最低手续费
+	MinFees string
+	//反馈模式的最高每字节费率
+	MaxFeeRate string
+	//节点因手续费不足拒绝交易的错误信息
+	FeeRejectionMessage string
+	//交易单构建后锁定utxo或EVM nonce的时长
+	UTXOReserveTimeout time.Duration
+	//交易单广播后仍在交易池中未上链的超时时长
+	TxDropTimeout time.Duration
+	//交易单从节点消失超时的时长
+	TxMissingTimeout time.Duration
+	//重新广播交易单的最小间隔
+	TxRebroadcastInterval time.Duration
+	//签名模式
+	SignerMode string
+	//远程签名服务地址
+	RemoteSignerURL string
+	//远程签名服务令牌
+	RemoteSignerToken string
+	//远程签名服务超时
+	RemoteSignerTimeout time.Duration
+	//节点后端类型
+	Backend string
+	//EVM节点JSON-RPC地址
+	EVMServerAPI string
+	//EVM链ID
+	EVMChainID int64
+	//转账交易的gas上限
+	EVMGasLimit uint64
+	//固定gas价格，为空时使用节点建议价格
+	EVMGasPrice string
+	//交易回执的确认数
+	EVMConfirmations uint64
+	//扫描前校验区块的交易哈希及默克尔根
+	VerifyBlock bool
+	//与ServerAPI比对一致性的其他节点地址
+	MonitorNodes []string
+	//节点高度未增长的时长，超过后标记为停滞
+	NodeStuckTimeout time.Duration
+	//节点高度落后的区块数，超过后标记为落后
+	NodeMaxLag uint64
 }
 
 func NewConfig(symbol string) *WalletConfig {
@@ -82,9 +184,27 @@ func NewConfig(symbol string) *WalletConfig {
 	c.dbPath = filepath.Join("data", strings.ToLower(c.Symbol), "db")
 	//钱包服务API
 	c.ServerAPI = ""
+	c.Network = addrdec.NetworkMainnet
+	c.ChainParams = &addrdec.MainNetParams
 	//最大的输入数量
 	c.MaxTxInputs = 50
 	c.FixFees = "0"
+	c.FeeMode = FeeModeFlat
+	c.FeeRate = "0"
+	c.MinFees = "0"
+	c.MaxFeeRate = ""
+	c.FeeRejectionMessage = ""
+	c.UTXOReserveTimeout = 600 * time.Second
+	c.TxDropTimeout = 3600 * time.Second
+	c.TxMissingTimeout = 7200 * time.Second
+	c.TxRebroadcastInterval = 120 * time.Second
+	c.NodeStuckTimeout = 600 * time.Second
+	c.NodeMaxLag = 10
+	c.SignerMode = SignerModeLocal
+	c.RemoteSignerTimeout = 30 * time.Second
+	c.Backend = BackendUTXO
+	c.EVMGasLimit = 21000
+	c.EVMConfirmations = 1
 
 	//创建目录
 	//file.MkdirAll(c.dbPath)
@@ -0,0 +1,344 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/blocktree/go-owcrypt"
+	"github.com/blocktree/openwallet/openwallet"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	BackendUTXO = "utxo" //旧版UTXO REST接口
+	BackendEVM  = "evm"  //以太坊兼容JSON-RPC接口
+
+	//EVM账户模型的精度及曲线
+	EVMDecimals  = int32(18)
+	EVMCurveType = owcrypt.ECC_CURVE_SECP256K1
+
+	evmRequestTimeout = 30 * time.Second
+)
+
+//EVMBackend EVM节点接口，ethclient.Client实现该接口，测试时可包装backends.SimulatedBackend
+type EVMBackend interface {
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error)
+	FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+}
+
+//NewEVMClient 根据配置连接EVM节点
+func NewEVMClient(c *WalletConfig) (EVMBackend, error) {
+	if len(c.EVMServerAPI) == 0 {
+		return nil, fmt.Errorf("evm server api is empty")
+	}
+	return ethclient.Dial(c.EVMServerAPI)
+}
+
+//SetEVMBackend 切换到EVM后端，地址解析、交易单、代币合约及区块扫描均通过EVM节点完成
+func (wm *WalletManager) SetEVMBackend(client EVMBackend) {
+	wm.Config.Backend = BackendEVM
+	wm.Config.CurveType = EVMCurveType
+	wm.EVMClient = client
+	wm.EVMDecoder = NewEVMAddressDecoder(wm)
+	wm.EVMBlockscanner = NewEVMBlockScanner(wm)
+	wm.EVMContractDecoder = NewEVMContractDecoder(wm)
+	wm.TxDecoder = NewEVMTransactionDecoder(wm)
+}
+
+//IsEVM 是否使用EVM后端
+func (wm *WalletManager) IsEVM() bool {
+	return wm.Config.Backend == BackendEVM
+}
+
+//evmSigner 交易单签名算法
+func (wm *WalletManager) evmSigner() types.Signer {
+	return types.NewEIP155Signer(big.NewInt(wm.Config.EVMChainID))
+}
+
+//evmSender 交易单发送者，兼容未使用EIP155的交易单
+func (wm *WalletManager) evmSender(tx *types.Transaction) (common.Address, error) {
+	if tx.Protected() {
+		return types.Sender(wm.evmSigner(), tx)
+	}
+	return types.Sender(types.HomesteadSigner{}, tx)
+}
+
+//EVMGasPrice 获取gas价格，优先使用配置的固定价格
+func (wm *WalletManager) EVMGasPrice() (*big.Int, error) {
+	if len(wm.Config.EVMGasPrice) > 0 {
+		price, err := decimal.NewFromString(wm.Config.EVMGasPrice)
+		if err != nil {
+			return nil, fmt.Errorf("invalid evm gas price: %v", err)
+		}
+		return evmToWei(price), nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), evmRequestTimeout)
+	defer cancel()
+	return wm.EVMClient.SuggestGasPrice(ctx)
+}
+
+//EVMTxStatus 根据交易回执确认的交易单状态
+type EVMTxStatus struct {
+	TxID          string
+	Status        string //openwallet.TxStatusSuccess或TxStatusFail，未上链为空
+	BlockHeight   uint64
+	BlockHash     string
+	Confirmations uint64
+	GasUsed       uint64
+	Confirmed     bool //已上链且确认数达到配置的EVMConfirmations
+}
+
+//GetEVMTransactionStatus 通过交易回执查询交易单状态及确认数
+func (wm *WalletManager) GetEVMTransactionStatus(txid string) (*EVMTxStatus, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), evmRequestTimeout)
+	defer cancel()
+
+	status := &EVMTxStatus{TxID: txid}
+
+	receipt, err := wm.EVMClient.TransactionReceipt(ctx, common.HexToHash(txid))
+	if err == ethereum.NotFound || (err == nil && receipt == nil) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := wm.EVMClient.BlockByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	status.Status = evmReceiptStatus(receipt)
+	status.BlockHeight = receipt.BlockNumber.Uint64()
+	status.BlockHash = receipt.BlockHash.Hex()
+	status.GasUsed = receipt.GasUsed
+	if latest.NumberU64() >= status.BlockHeight {
+		status.Confirmations = latest.NumberU64() - status.BlockHeight + 1
+	}
+	status.Confirmed = status.Confirmations >= wm.Config.EVMConfirmations
+
+	return status, nil
+}
+
+func evmReceiptStatus(receipt *types.Receipt) string {
+	if receipt.Status == types.ReceiptStatusSuccessful {
+		return openwallet.TxStatusSuccess
+	}
+	return openwallet.TxStatusFail
+}
+
+//EVMNonceManager 管理地址的nonce，构建交易单时预留nonce，已构建未广播及已广播但未打包的交易单都会占用nonce
+type EVMNonceManager struct {
+	mu      sync.Mutex
+	Timeout time.Duration //预留的nonce超过该时长未广播，可重新使用，不大于0时不过期
+	nonces  map[common.Address]*evmNonceState
+	now     func() time.Time
+}
+
+//evmNonceState 地址在本地记录的nonce
+type evmNonceState struct {
+	next     uint64               //本地记录的下一个nonce
+	reserved map[uint64]time.Time //已构建未广播的nonce及过期时间
+	released map[uint64]bool      //广播失败或过期，可重新使用的nonce
+}
+
+//NewEVMNonceManager 创建nonce管理器
+func NewEVMNonceManager(timeout time.Duration) *EVMNonceManager {
+	return &EVMNonceManager{Timeout: timeout, nonces: make(map[common.Address]*evmNonceState), now: time.Now}
+}
+
+//Nonce 地址的下一个可用nonce，不预留
+func (m *EVMNonceManager) Nonce(client EVMBackend, address common.Address) (uint64, error) {
+	pending, err := m.pendingNonce(client, address)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	nonce, _ := m.available(address, pending)
+	return nonce, nil
+}
+
+//Reserve 构建交易单时预留nonce，优先使用广播失败释放的nonce，否则取节点pending nonce与本地记录的较大值
+//同时构建的交易单不会得到相同的nonce，广播失败时需调用Release
+func (m *EVMNonceManager) Reserve(client EVMBackend, address common.Address) (uint64, error) {
+	pending, err := m.pendingNonce(client, address)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	nonce, state := m.available(address, pending)
+	delete(state.released, nonce)
+	state.reserved[nonce] = m.now().Add(m.Timeout)
+	if nonce+1 > state.next {
+		state.next = nonce + 1
+	}
+	return nonce, nil
+}
+
+//Release 交易单广播失败后释放预留的nonce，之后构建的交易单可重新使用
+func (m *EVMNonceManager) Release(address common.Address, nonce uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state := m.state(address)
+	if _, ok := state.reserved[nonce]; !ok {
+		return
+	}
+	delete(state.reserved, nonce)
+	state.released[nonce] = true
+	//释放的是最后的nonce时回退本地记录
+	for state.next > 0 && state.released[state.next-1] {
+		delete(state.released, state.next-1)
+		state.next--
+	}
+}
+
+//Commit 交易单广播成功后记录已使用的nonce
+func (m *EVMNonceManager) Commit(address common.Address, nonce uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state := m.state(address)
+	delete(state.reserved, nonce)
+	delete(state.released, nonce)
+	if nonce+1 > state.next {
+		state.next = nonce + 1
+	}
+}
+
+//pendingNonce 查询节点的pending nonce
+func (m *EVMNonceManager) pendingNonce(client EVMBackend, address common.Address) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), evmRequestTimeout)
+	defer cancel()
+	return client.PendingNonceAt(ctx, address)
+}
+
+//state 地址的本地记录，调用方需持有锁
+func (m *EVMNonceManager) state(address common.Address) *evmNonceState {
+	state, ok := m.nonces[address]
+	if !ok {
+		state = &evmNonceState{reserved: make(map[uint64]time.Time), released: make(map[uint64]bool)}
+		m.nonces[address] = state
+	}
+	return state
+}
+
+//available 清理过期及节点已使用的记录，返回下一个可用nonce，调用方需持有锁
+func (m *EVMNonceManager) available(address common.Address, pending uint64) (uint64, *evmNonceState) {
+	state := m.state(address)
+	now := m.now()
+	for nonce, expire := range state.reserved {
+		if m.Timeout > 0 && now.After(expire) {
+			delete(state.reserved, nonce)
+			state.released[nonce] = true
+		}
+	}
+
+	//低于节点pending nonce的记录已被使用
+	found := false
+	lowest := uint64(0)
+	for nonce := range state.released {
+		if nonce < pending {
+			delete(state.released, nonce)
+			continue
+		}
+		if !found || nonce < lowest {
+			found, lowest = true, nonce
+		}
+	}
+	for nonce := range state.reserved {
+		if nonce < pending {
+			delete(state.reserved, nonce)
+		}
+	}
+	if found {
+		return lowest, state
+	}
+	if state.next > pending {
+		return state.next, state
+	}
+	return pending, state
+}
+
+//evmToWei 金额转为最小单位
+func evmToWei(amount decimal.Decimal) *big.Int {
+	return evmToUnit(amount, EVMDecimals)
+}
+
+//evmFromWei 最小单位转为金额
+func evmFromWei(value *big.Int) decimal.Decimal {
+	return evmFromUnit(value, EVMDecimals)
+}
+
+//evmToUnit 按精度将金额转为最小单位
+func evmToUnit(amount decimal.Decimal, decimals int32) *big.Int {
+	//Coefficient不包含正指数，转为整数字符串后解析
+	value, _ := new(big.Int).SetString(amount.Shift(decimals).Truncate(0).String(), 10)
+	if value == nil {
+		return big.NewInt(0)
+	}
+	return value
+}
+
+//evmFromUnit 按精度将最小单位转为金额
+func evmFromUnit(value *big.Int, decimals int32) decimal.Decimal {
+	return decimal.NewFromBigInt(value, -decimals)
+}
+
+//evmCoinDecimals 币种精度，代币使用合约精度
+func evmCoinDecimals(coin openwallet.Coin) int32 {
+	if coin.IsContract {
+		return int32(coin.Contract.Decimals)
+	}
+	return EVMDecimals
+}
+
+//evmParseAddress 解析并校验EVM地址，混合大小写时校验EIP-55
+func evmParseAddress(address string) (common.Address, error) {
+	hash, err := addrdec.ValidateHexAddress(address)
+	if err != nil {
+		return common.Address{}, openwallet.Errorf(openwallet.ErrAdressDecodeFailed, "invalid evm address: %v", err)
+	}
+	return common.BytesToAddress(hash), nil
+}
+
+//evmParseReceiver 解析接收地址，旧版地址为ed25519公钥的哈希，没有对应的secp256k1密钥，转入后无法花费，不能作为接收地址
+func (wm *WalletManager) evmParseReceiver(address string) (common.Address, error) {
+	if addrdec.DetectAddressFormat(address) == addrdec.FormatBase58 {
+		return common.Address{}, openwallet.Errorf(openwallet.ErrAdressDecodeFailed, "receiver %s is a legacy address, use a 0x address of the evm chain", address)
+	}
+	return evmParseAddress(address)
+}
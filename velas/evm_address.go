@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"fmt"
+
+	"github.com/blocktree/openwallet/openwallet"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+//EVMAddressDecoder EVM地址解析器，地址为secp256k1公钥keccak256哈希的后20字节
+type EVMAddressDecoder struct {
+	openwallet.AddressDecoderV2Base
+	wm *WalletManager //钱包管理者
+}
+
+//NewEVMAddressDecoder EVM地址解析器
+func NewEVMAddressDecoder(wm *WalletManager) *EVMAddressDecoder {
+	decoder := EVMAddressDecoder{}
+	decoder.wm = wm
+	return &decoder
+}
+
+//AddressEncode 公钥转地址，支持压缩及非压缩公钥
+func (decoder *EVMAddressDecoder) AddressEncode(pub []byte, opts ...interface{}) (string, error) {
+	var (
+		address string
+	)
+	switch len(pub) {
+	case 33:
+		key, err := crypto.DecompressPubkey(pub)
+		if err != nil {
+			return "", err
+		}
+		address = crypto.PubkeyToAddress(*key).Hex()
+	case 65:
+		key, err := crypto.UnmarshalPubkey(pub)
+		if err != nil {
+			return "", err
+		}
+		address = crypto.PubkeyToAddress(*key).Hex()
+	default:
+		return "", fmt.Errorf("invalid secp256k1 public key length %d", len(pub))
+	}
+	return address, nil
+}
+
+//AddressDecode 地址转20字节哈希
+func (decoder *EVMAddressDecoder) AddressDecode(addr string, opts ...interface{}) ([]byte, error) {
+	address, err := evmParseAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	return address.Bytes(), nil
+}
+
+//AddressVerify 地址校验
+func (decoder *EVMAddressDecoder) AddressVerify(address string, opts ...interface{}) bool {
+	_, err := evmParseAddress(address)
+	return err == nil
+}
+
+//PublicKeyToAddress 公钥转地址
+func (decoder *EVMAddressDecoder) PublicKeyToAddress(pub []byte, isTestnet bool) (string, error) {
+	return decoder.AddressEncode(pub)
+}
+
+//PrivateKeyToWIF 私钥转WIF
+func (decoder *EVMAddressDecoder) PrivateKeyToWIF(priv []byte, isTestnet bool) (string, error) {
+	return "", fmt.Errorf("PrivateKeyToWIF is not supported by evm backend")
+}
+
+//WIFToPrivateKey WIF转私钥
+func (decoder *EVMAddressDecoder) WIFToPrivateKey(wif string, isTestnet bool) ([]byte, error) {
+	return nil, fmt.Errorf("WIFToPrivateKey is not supported by evm backend")
+}
+
+//RedeemScriptToAddress 多重签名赎回脚本转地址
+func (decoder *EVMAddressDecoder) RedeemScriptToAddress(pubs [][]byte, required uint64, isTestnet bool) (string, error) {
+	return "", fmt.Errorf("RedeemScriptToAddress is not supported")
+}
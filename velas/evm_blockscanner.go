@@ -0,0 +1,774 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/blocktree/openwallet/openwallet"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+//EVMBlockScanner EVM区块链扫描器，交易单状态及手续费以交易回执为准
+type EVMBlockScanner struct {
+	*openwallet.BlockScannerBase
+
+	wm                   *WalletManager //钱包管理者
+	RescanLastBlockCount uint64         //重扫上N个区块数量
+}
+
+//NewEVMBlockScanner 创建EVM区块链扫描器
+func NewEVMBlockScanner(wm *WalletManager) *EVMBlockScanner {
+	bs := EVMBlockScanner{
+		BlockScannerBase: openwallet.NewBlockScannerBase(),
+	}
+
+	bs.wm = wm
+	bs.RescanLastBlockCount = 1
+
+	//设置扫描任务
+	bs.SetTask(bs.ScanBlockTask)
+
+	return &bs
+}
+
+//SetRescanBlockHeight 重置区块链扫描高度
+func (bs *EVMBlockScanner) SetRescanBlockHeight(height uint64) error {
+	if height == 0 {
+		return fmt.Errorf("block height to rescan must greater than 0")
+	}
+	height = height - 1
+
+	block, err := bs.getBlock(height)
+	if err != nil {
+		return err
+	}
+
+	bs.wm.SaveLocalNewBlock(height, block.Hash().Hex())
+
+	return nil
+}
+
+//ScanBlockTask 扫描任务
+func (bs *EVMBlockScanner) ScanBlockTask() {
+
+	//获取本地区块高度
+	blockHeader, err := bs.GetScannedBlockHeader()
+	if err != nil {
+		bs.wm.Log.Std.Info("block scanner can not get new block height; unexpected error: %v", err)
+		return
+	}
+
+	currentHeight := blockHeader.Height
+	currentHash := blockHeader.Hash
+
+	for {
+
+		if !bs.Scanning {
+			//区块扫描器已暂停，马上结束本次任务
+			return
+		}
+
+		//获取最大高度
+		maxHeight, err := bs.getBlockHeight()
+		if err != nil {
+			bs.wm.Log.Std.Info("block scanner can not get rpc-server block height; unexpected error: %v", err)
+			break
+		}
+
+		//是否已到最新高度
+		if currentHeight >= maxHeight {
+			bs.wm.Log.Std.Info("block scanner has scanned full chain data. Current height: %d", maxHeight)
+			break
+		}
+
+		//继续扫描下一个区块
+		currentHeight = currentHeight + 1
+
+		bs.wm.Log.Std.Info("block scanner scanning height: %d ...", currentHeight)
+
+		block, err := bs.getBlock(currentHeight)
+		if err != nil {
+			bs.wm.Log.Std.Info("block scanner can not get new block data; unexpected error: %v", err)
+
+			//记录未扫区块
+			unscanRecord := NewUnscanRecord(currentHeight, "", err.Error())
+			bs.SaveUnscanRecord(unscanRecord)
+			bs.wm.Log.Std.Info("block height: %d extract failed.", currentHeight)
+			continue
+		}
+
+		//判断hash是否上一区块的hash
+		if currentHash != block.ParentHash().Hex() {
+
+			bs.wm.Log.Std.Info("block has been fork on height: %d.", currentHeight)
+			bs.wm.Log.Std.Info("block height: %d local hash = %s ", currentHeight-1, currentHash)
+			bs.wm.Log.Std.Info("block height: %d mainnet hash = %s ", currentHeight-1, block.ParentHash().Hex())
+
+			//查询本地分叉的区块
+			forkBlock, _ := bs.wm.GetLocalBlock(currentHeight - 1)
+
+			//删除上一区块链的未扫记录
+			bs.wm.DeleteUnscanRecord(currentHeight - 1)
+			if currentHeight > 2 {
+				currentHeight = currentHeight - 2 //倒退2个区块重新扫描
+			} else {
+				currentHeight = 1
+			}
+
+			localBlock, err := bs.wm.GetLocalBlock(currentHeight)
+			if err != nil {
+				b, err := bs.getBlock(currentHeight)
+				if err != nil {
+					bs.wm.Log.Std.Error("block scanner can not get prev block; unexpected error: %v", err)
+					break
+				}
+				currentHash = b.Hash().Hex()
+			} else {
+				currentHash = localBlock.Hash //重置当前区块的hash
+			}
+
+			bs.wm.Log.Std.Info("rescan block on height: %d, hash: %s .", currentHeight, currentHash)
+
+			//重新记录一个新扫描起点
+			bs.wm.SaveLocalNewBlock(currentHeight, currentHash)
+
+			if forkBlock != nil {
+				//通知分叉区块给观测者，异步处理
+				bs.newBlockNotify(forkBlock, true)
+			}
+
+		} else {
+
+			err = bs.BatchExtractTransaction(block)
+			if err != nil {
+				bs.wm.Log.Std.Info("block scanner can not extractRechargeRecords; unexpected error: %v", err)
+			}
+
+			//重置当前区块的hash
+			currentHash = block.Hash().Hex()
+
+			//保存本地新高度
+			bs.wm.SaveLocalNewBlock(currentHeight, currentHash)
+
+			b := evmLocalBlock(block)
+			bs.wm.SaveLocalBlock(b)
+
+			//通知新区块给观测者，异步处理
+			bs.newBlockNotify(b, false)
+		}
+	}
+
+	//重扫前N个块，为保证记录找到
+	for i := currentHeight - bs.RescanLastBlockCount; i < currentHeight; i++ {
+		bs.scanBlock(i)
+	}
+
+	//重扫失败区块
+	bs.RescanFailedRecord()
+}
+
+//ScanBlock 扫描指定高度区块
+func (bs *EVMBlockScanner) ScanBlock(height uint64) error {
+
+	block, err := bs.scanBlock(height)
+	if err != nil {
+		return err
+	}
+
+	//通知新区块给观测者，异步处理
+	bs.newBlockNotify(block, false)
+
+	return nil
+}
+
+func (bs *EVMBlockScanner) scanBlock(height uint64) (*Block, error) {
+
+	block, err := bs.getBlock(height)
+	if err != nil {
+		bs.wm.Log.Std.Info("block scanner can not get new block data; unexpected error: %v", err)
+
+		//记录未扫区块
+		unscanRecord := NewUnscanRecord(height, "", err.Error())
+		bs.SaveUnscanRecord(unscanRecord)
+		bs.wm.Log.Std.Info("block height: %d extract failed.", height)
+		return nil, err
+	}
+
+	bs.wm.Log.Std.Info("block scanner scanning height: %d ...", height)
+
+	err = bs.BatchExtractTransaction(block)
+	if err != nil {
+		bs.wm.Log.Std.Info("block scanner can not extractRechargeRecords; unexpected error: %v", err)
+	}
+
+	return evmLocalBlock(block), nil
+}
+
+//RescanFailedRecord 重扫失败记录
+func (bs *EVMBlockScanner) RescanFailedRecord() {
+
+	list, err := bs.wm.GetUnscanRecords()
+	if err != nil {
+		bs.wm.Log.Std.Info("block scanner can not get rescan data; unexpected error: %v", err)
+	}
+
+	heights := make(map[uint64]bool)
+	for _, r := range list {
+		heights[r.BlockHeight] = true
+	}
+
+	for height := range heights {
+
+		if height == 0 {
+			continue
+		}
+
+		bs.wm.Log.Std.Info("block scanner rescanning height: %d ...", height)
+
+		block, err := bs.getBlock(height)
+		if err != nil {
+			bs.wm.Log.Std.Info("block scanner can not get new block hash; unexpected error: %v", err)
+			continue
+		}
+
+		err = bs.BatchExtractTransaction(block)
+		if err != nil {
+			bs.wm.Log.Std.Info("block scanner can not extractRechargeRecords; unexpected error: %v", err)
+			continue
+		}
+
+		//删除未扫记录
+		bs.wm.DeleteUnscanRecord(height)
+	}
+}
+
+//newBlockNotify 获得新区块后，通知给观测者
+func (bs *EVMBlockScanner) newBlockNotify(block *Block, isFork bool) {
+	header := block.BlockHeader(bs.wm.Symbol())
+	header.Fork = isFork
+	bs.NewBlockNotify(header)
+}
+
+//BatchExtractTransaction 提取区块中与钱包地址相关的交易单及代币转账
+func (bs *EVMBlockScanner) BatchExtractTransaction(block *types.Block) error {
+
+	failed := 0
+	for _, tx := range block.Transactions() {
+		result := bs.ExtractTransaction(block, tx, bs.ScanAddressFunc)
+		if !result.Success {
+			unscanRecord := NewUnscanRecord(block.NumberU64(), "", "")
+			bs.SaveUnscanRecord(unscanRecord)
+			failed++
+			continue
+		}
+		bs.newExtractDataNotify(block.NumberU64(), result.extractData)
+	}
+
+	//代币转账通过区块内的Transfer日志提取
+	logs, err := bs.getTransferLogs(block)
+	if err != nil {
+		unscanRecord := NewUnscanRecord(block.NumberU64(), "", err.Error())
+		bs.SaveUnscanRecord(unscanRecord)
+		return fmt.Errorf("block scanner get transfer logs failed, %v", err)
+	}
+
+	for _, tx := range block.Transactions() {
+		txLogs := logs[tx.Hash()]
+		if len(txLogs) == 0 {
+			continue
+		}
+		for _, result := range bs.ExtractTokenTransaction(block, tx, txLogs, bs.ScanAddressFunc) {
+			if !result.Success {
+				unscanRecord := NewUnscanRecord(block.NumberU64(), "", "")
+				bs.SaveUnscanRecord(unscanRecord)
+				failed++
+				continue
+			}
+			bs.newExtractDataNotify(block.NumberU64(), result.extractData)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("block scanner extract %d transactions failed", failed)
+	}
+	return nil
+}
+
+//ExtractTransaction 提取交易单，发送地址记为输入，接收地址记为输出，失败的交易单只扣除手续费
+func (bs *EVMBlockScanner) ExtractTransaction(block *types.Block, tx *types.Transaction, scanAddressFunc openwallet.BlockScanAddressFunc) ExtractResult {
+
+	var (
+		txid   = tx.Hash().Hex()
+		result = ExtractResult{
+			BlockHeight: block.NumberU64(),
+			TxID:        txid,
+			extractData: make(map[string]*openwallet.TxExtractData),
+		}
+		to string
+	)
+
+	sender, err := bs.wm.evmSender(tx)
+	if err != nil {
+		bs.wm.Log.Std.Error("transaction %s sender recover failed. unexpected error: %v", txid, err)
+		return result
+	}
+	from := sender.Hex()
+	if tx.To() != nil {
+		to = tx.To().Hex()
+	}
+
+	fromKey, fromOK := scanAddressFunc(from)
+	toKey, toOK := "", false
+	if len(to) > 0 {
+		toKey, toOK = scanAddressFunc(to)
+	}
+
+	if !fromOK && !toOK {
+		result.Success = true
+		return result
+	}
+
+	//交易回执确认交易单状态及实际手续费
+	ctx, cancel := context.WithTimeout(context.Background(), evmRequestTimeout)
+	defer cancel()
+	receipt, err := bs.wm.EVMClient.TransactionReceipt(ctx, tx.Hash())
+	if err != nil || receipt == nil {
+		bs.wm.Log.Std.Error("transaction %s receipt not found. unexpected error: %v", txid, err)
+		return result
+	}
+
+	var (
+		blockHash = block.Hash().Hex()
+		height    = block.NumberU64()
+		status    = evmReceiptStatus(receipt)
+		amount    = evmFromWei(tx.Value()).String()
+		fees      = evmFromWei(new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(receipt.GasUsed))).String()
+		coin      = openwallet.Coin{Symbol: bs.wm.Symbol(), IsContract: false}
+		createAt  = time.Now().Unix()
+	)
+
+	if status != openwallet.TxStatusSuccess {
+		amount = "0"
+	}
+
+	if fromOK {
+		input := openwallet.TxInput{}
+		input.TxID = txid
+		input.Address = from
+		input.Amount = amount
+		input.Coin = coin
+		input.Index = 0
+		input.Sid = openwallet.GenTxInputSID(txid, bs.wm.Symbol(), "", 0)
+		input.CreateAt = createAt
+		input.BlockHeight = height
+		input.BlockHash = blockHash
+
+		ed := evmExtractData(result.extractData, fromKey)
+		ed.TxInputs = append(ed.TxInputs, &input)
+	}
+
+	if toOK && status == openwallet.TxStatusSuccess {
+		output := openwallet.TxOutPut{}
+		output.TxID = txid
+		output.Address = to
+		output.Amount = amount
+		output.Coin = coin
+		output.Index = 0
+		output.Sid = openwallet.GenTxOutPutSID(txid, bs.wm.Symbol(), "", 0)
+		output.CreateAt = createAt
+		output.BlockHeight = height
+		output.BlockHash = blockHash
+
+		ed := evmExtractData(result.extractData, toKey)
+		ed.TxOutputs = append(ed.TxOutputs, &output)
+	}
+
+	for _, extractData := range result.extractData {
+		trx := &openwallet.Transaction{
+			From:        []string{from + ":" + amount},
+			To:          []string{to + ":" + amount},
+			Fees:        fees,
+			Coin:        coin,
+			BlockHash:   blockHash,
+			BlockHeight: height,
+			TxID:        txid,
+			Decimal:     EVMDecimals,
+			ConfirmTime: int64(block.Time()),
+			Status:      status,
+		}
+		trx.WxID = openwallet.GenTransactionWxID(trx)
+		extractData.Transaction = trx
+	}
+
+	result.Success = true
+	return result
+}
+
+func evmExtractData(extractData map[string]*openwallet.TxExtractData, sourceKey string) *openwallet.TxExtractData {
+	ed := extractData[sourceKey]
+	if ed == nil {
+		ed = openwallet.NewBlockExtractData()
+		extractData[sourceKey] = ed
+	}
+	return ed
+}
+
+//ExtractTokenTransaction 提取交易单的代币转账，每个合约生成一条记录，Transfer日志的发送地址记为输入，接收地址记为输出
+func (bs *EVMBlockScanner) ExtractTokenTransaction(block *types.Block, tx *types.Transaction, logs []*types.Log, scanAddressFunc openwallet.BlockScanAddressFunc) []ExtractResult {
+
+	var (
+		txid      = tx.Hash().Hex()
+		blockHash = block.Hash().Hex()
+		height    = block.NumberU64()
+		createAt  = time.Now().Unix()
+		contracts = make([]common.Address, 0)
+		transfers = make(map[common.Address][]*EVMTokenTransfer)
+		results   = make([]ExtractResult, 0)
+	)
+
+	for _, txLog := range logs {
+		transfer, ok := evmParseTransferLog(txLog)
+		if !ok {
+			continue
+		}
+		if _, exist := transfers[transfer.Contract]; !exist {
+			contracts = append(contracts, transfer.Contract)
+		}
+		transfers[transfer.Contract] = append(transfers[transfer.Contract], transfer)
+	}
+
+	for _, contractAddr := range contracts {
+
+		result := ExtractResult{
+			BlockHeight: height,
+			TxID:        txid,
+			extractData: make(map[string]*openwallet.TxExtractData),
+		}
+
+		var (
+			from     = make([]string, 0)
+			to       = make([]string, 0)
+			contract *openwallet.SmartContract
+			failed   bool
+		)
+
+		for _, transfer := range transfers[contractAddr] {
+
+			fromKey, fromOK := scanAddressFunc(transfer.From.Hex())
+			toKey, toOK := scanAddressFunc(transfer.To.Hex())
+			if !fromOK && !toOK {
+				continue
+			}
+
+			//只查询与钱包地址相关的合约信息
+			if contract == nil {
+				token, err := bs.wm.EVMContractDecoder.GetTokenContract(contractAddr.Hex())
+				if err != nil {
+					bs.wm.Log.Std.Error("transaction %s get token contract %s failed. unexpected error: %v", txid, contractAddr.Hex(), err)
+					failed = true
+					break
+				}
+				contract = token
+			}
+
+			amount := evmFromUnit(transfer.Value, int32(contract.Decimals)).String()
+			coin := openwallet.Coin{
+				Symbol:     bs.wm.Symbol(),
+				IsContract: true,
+				ContractID: contract.ContractID,
+				Contract:   *contract,
+			}
+			from = append(from, transfer.From.Hex()+":"+amount)
+			to = append(to, transfer.To.Hex()+":"+amount)
+
+			if fromOK {
+				input := openwallet.TxInput{}
+				input.TxID = txid
+				input.Address = transfer.From.Hex()
+				input.Amount = amount
+				input.Coin = coin
+				input.Index = uint64(transfer.LogIndex)
+				input.Sid = openwallet.GenTxInputSID(txid, bs.wm.Symbol(), contract.ContractID, uint64(transfer.LogIndex))
+				input.CreateAt = createAt
+				input.BlockHeight = height
+				input.BlockHash = blockHash
+
+				ed := evmExtractData(result.extractData, fromKey)
+				ed.TxInputs = append(ed.TxInputs, &input)
+			}
+
+			if toOK {
+				output := openwallet.TxOutPut{}
+				output.TxID = txid
+				output.Address = transfer.To.Hex()
+				output.Amount = amount
+				output.Coin = coin
+				output.Index = uint64(transfer.LogIndex)
+				output.Sid = openwallet.GenTxOutPutSID(txid, bs.wm.Symbol(), contract.ContractID, uint64(transfer.LogIndex))
+				output.CreateAt = createAt
+				output.BlockHeight = height
+				output.BlockHash = blockHash
+
+				ed := evmExtractData(result.extractData, toKey)
+				ed.TxOutputs = append(ed.TxOutputs, &output)
+			}
+		}
+
+		//合约信息查询失败时需重扫
+		if failed {
+			results = append(results, result)
+			continue
+		}
+
+		if len(result.extractData) == 0 {
+			continue
+		}
+
+		for _, extractData := range result.extractData {
+			//手续费以主币支付，记录在主币交易单中
+			trx := &openwallet.Transaction{
+				From:        from,
+				To:          to,
+				Fees:        "0",
+				Coin:        openwallet.Coin{Symbol: bs.wm.Symbol(), IsContract: true, ContractID: contract.ContractID, Contract: *contract},
+				BlockHash:   blockHash,
+				BlockHeight: height,
+				TxID:        txid,
+				Decimal:     int32(contract.Decimals),
+				ConfirmTime: int64(block.Time()),
+				Status:      openwallet.TxStatusSuccess,
+			}
+			trx.WxID = openwallet.GenTransactionWxID(trx)
+			extractData.Transaction = trx
+		}
+
+		result.Success = true
+		results = append(results, result)
+	}
+
+	return results
+}
+
+//getTransferLogs 查询区块内的Transfer日志，按交易单分组
+func (bs *EVMBlockScanner) getTransferLogs(block *types.Block) (map[common.Hash][]*types.Log, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), evmRequestTimeout)
+	defer cancel()
+
+	blockHash := block.Hash()
+	logs, err := bs.wm.EVMClient.FilterLogs(ctx, ethereum.FilterQuery{
+		BlockHash: &blockHash,
+		Topics:    [][]common.Hash{{EVMTransferEventTopic}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	txLogs := make(map[common.Hash][]*types.Log)
+	for i := range logs {
+		txLogs[logs[i].TxHash] = append(txLogs[logs[i].TxHash], &logs[i])
+	}
+	return txLogs, nil
+}
+
+//newExtractDataNotify 发送通知
+func (bs *EVMBlockScanner) newExtractDataNotify(height uint64, extractData map[string]*openwallet.TxExtractData) {
+	for o := range bs.Observers {
+		for key, data := range extractData {
+			err := o.BlockExtractDataNotify(key, data)
+			if err != nil {
+				bs.wm.Log.Error("BlockExtractDataNotify unexpected error:", err)
+				//记录未扫区块
+				unscanRecord := NewUnscanRecord(height, "", "ExtractData Notify failed.")
+				bs.SaveUnscanRecord(unscanRecord)
+			}
+		}
+	}
+}
+
+//ExtractTransactionData 提取指定交易单
+func (bs *EVMBlockScanner) ExtractTransactionData(txid string, scanTargetFunc openwallet.BlockScanTargetFunc) (map[string][]*openwallet.TxExtractData, error) {
+
+	scanAddressFunc := func(address string) (string, bool) {
+		target := openwallet.ScanTarget{
+			Address:          address,
+			BalanceModelType: openwallet.BalanceModelTypeAddress,
+		}
+		return scanTargetFunc(target)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), evmRequestTimeout)
+	defer cancel()
+
+	receipt, err := bs.wm.EVMClient.TransactionReceipt(ctx, common.HexToHash(txid))
+	if err != nil || receipt == nil {
+		return nil, fmt.Errorf("fetch transaction receipt failed, %v", err)
+	}
+
+	block, err := bs.getBlock(receipt.BlockNumber.Uint64())
+	if err != nil {
+		return nil, fmt.Errorf("fetch block failed, %v", err)
+	}
+
+	tx := block.Transaction(common.HexToHash(txid))
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %s not found in block %d", txid, block.NumberU64())
+	}
+
+	result := bs.ExtractTransaction(block, tx, scanAddressFunc)
+	if !result.Success {
+		return nil, fmt.Errorf("extract transaction failed")
+	}
+
+	extData := make(map[string][]*openwallet.TxExtractData)
+	for key, data := range result.extractData {
+		extData[key] = append(extData[key], data)
+	}
+
+	//代币转账从交易回执的日志中提取
+	for _, tokenResult := range bs.ExtractTokenTransaction(block, tx, receipt.Logs, scanAddressFunc) {
+		if !tokenResult.Success {
+			return nil, fmt.Errorf("extract token transaction failed")
+		}
+		for key, data := range tokenResult.extractData {
+			extData[key] = append(extData[key], data)
+		}
+	}
+	return extData, nil
+}
+
+//GetScannedBlockHeader 获取当前扫描的区块头
+func (bs *EVMBlockScanner) GetScannedBlockHeader() (*openwallet.BlockHeader, error) {
+
+	blockHeight, hash := bs.wm.GetLocalNewBlock()
+
+	//如果本地没有记录，以上一个区块为当前区块
+	if blockHeight == 0 {
+		maxHeight, err := bs.getBlockHeight()
+		if err != nil {
+			return nil, err
+		}
+		if maxHeight > 0 {
+			blockHeight = maxHeight - 1
+		}
+
+		block, err := bs.getBlock(blockHeight)
+		if err != nil {
+			return nil, err
+		}
+		hash = block.Hash().Hex()
+	}
+
+	return &openwallet.BlockHeader{Height: blockHeight, Hash: hash}, nil
+}
+
+//GetCurrentBlockHeader 获取当前区块高度
+func (bs *EVMBlockScanner) GetCurrentBlockHeader() (*openwallet.BlockHeader, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), evmRequestTimeout)
+	defer cancel()
+
+	block, err := bs.wm.EVMClient.BlockByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &openwallet.BlockHeader{Height: block.NumberU64(), Hash: block.Hash().Hex()}, nil
+}
+
+//GetGlobalMaxBlockHeight 获取节点最大高度
+func (bs *EVMBlockScanner) GetGlobalMaxBlockHeight() uint64 {
+	maxHeight, err := bs.getBlockHeight()
+	if err != nil {
+		bs.wm.Log.Std.Info("get global max block height error;unexpected error:%v", err)
+		return 0
+	}
+	return maxHeight
+}
+
+//GetScannedBlockHeight 获取已扫区块高度
+func (bs *EVMBlockScanner) GetScannedBlockHeight() uint64 {
+	localHeight, _ := bs.wm.GetLocalNewBlock()
+	return localHeight
+}
+
+//SaveUnscanRecord 保存未扫记录
+func (bs *EVMBlockScanner) SaveUnscanRecord(record *UnscanRecord) error {
+	return bs.wm.Blockscanner.SaveUnscanRecord(record)
+}
+
+//GetBalanceByAddress 查询地址余额
+func (bs *EVMBlockScanner) GetBalanceByAddress(address ...string) ([]*openwallet.Balance, error) {
+
+	addrsBalance := make([]*openwallet.Balance, 0)
+
+	for _, a := range address {
+		addr, err := evmParseAddress(a)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), evmRequestTimeout)
+		confirmed, err := bs.wm.EVMClient.BalanceAt(ctx, addr, nil)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		balance := evmFromWei(confirmed).String()
+		addrsBalance = append(addrsBalance, &openwallet.Balance{
+			Symbol:           bs.wm.Symbol(),
+			Address:          a,
+			Balance:          balance,
+			ConfirmBalance:   balance,
+			UnconfirmBalance: "0",
+		})
+	}
+
+	return addrsBalance, nil
+}
+
+func (bs *EVMBlockScanner) getBlockHeight() (uint64, error) {
+	block, err := bs.GetCurrentBlockHeader()
+	if err != nil {
+		return 0, err
+	}
+	return block.Height, nil
+}
+
+func (bs *EVMBlockScanner) getBlock(height uint64) (*types.Block, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), evmRequestTimeout)
+	defer cancel()
+	return bs.wm.EVMClient.BlockByNumber(ctx, new(big.Int).SetUint64(height))
+}
+
+//evmLocalBlock 本地记录的区块
+func evmLocalBlock(block *types.Block) *Block {
+	return &Block{
+		Hash:              block.Hash().Hex(),
+		Merkleroot:        block.TxHash().Hex(),
+		Previousblockhash: block.ParentHash().Hex(),
+		Height:            block.NumberU64(),
+		Time:              block.Time(),
+		Fork:              false,
+	}
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/blocktree/openwallet/openwallet"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	//EVMTokenProtocol 代币合约协议，兼容ERC20
+	EVMTokenProtocol = "VRC20"
+
+	//evmTokenABI 代币合约用到的最小ABI
+	evmTokenABI = `[
+{"constant":true,"inputs":[{"name":"owner","type":"address"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"type":"function"},
+{"constant":false,"inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"name":"transfer","outputs":[],"type":"function"},
+{"constant":true,"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"type":"function"},
+{"constant":true,"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"type":"function"},
+{"constant":true,"inputs":[],"name":"name","outputs":[{"name":"","type":"string"}],"type":"function"},
+{"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"value","type":"uint256"}],"name":"Transfer","type":"event"}
+]`
+)
+
+var (
+	evmTokenContractABI, _ = abi.JSON(strings.NewReader(evmTokenABI))
+
+	//EVMTransferEventTopic Transfer(address,address,uint256)事件签名
+	EVMTransferEventTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+)
+
+//EVMContractDecoder 代币合约解析器
+type EVMContractDecoder struct {
+	openwallet.SmartContractDecoderBase
+	wm *WalletManager //钱包管理者
+
+	mu     sync.RWMutex
+	tokens map[common.Address]*openwallet.SmartContract //已查询的合约信息
+}
+
+//NewEVMContractDecoder 代币合约解析器
+func NewEVMContractDecoder(wm *WalletManager) *EVMContractDecoder {
+	decoder := EVMContractDecoder{}
+	decoder.wm = wm
+	decoder.tokens = make(map[common.Address]*openwallet.SmartContract)
+	return &decoder
+}
+
+//GetTokenBalanceByAddress 查询地址的代币余额
+func (decoder *EVMContractDecoder) GetTokenBalanceByAddress(contract openwallet.SmartContract, address ...string) ([]*openwallet.TokenBalance, error) {
+
+	contractAddr, err := evmParseAddress(contract.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenBalanceList := make([]*openwallet.TokenBalance, 0)
+
+	for _, a := range address {
+		value, err := decoder.balanceOf(contractAddr, a)
+		if err != nil {
+			return nil, err
+		}
+
+		balance := decimal.NewFromBigInt(value, -int32(contract.Decimals)).String()
+		tokenBalanceList = append(tokenBalanceList, &openwallet.TokenBalance{
+			Contract: &contract,
+			Balance: &openwallet.Balance{
+				Symbol:           contract.Symbol,
+				Address:          a,
+				Balance:          balance,
+				ConfirmBalance:   balance,
+				UnconfirmBalance: "0",
+			},
+		})
+	}
+
+	return tokenBalanceList, nil
+}
+
+//GetTokenContract 查询合约的代币符号、名称及精度，结果会被缓存
+func (decoder *EVMContractDecoder) GetTokenContract(address string) (*openwallet.SmartContract, error) {
+
+	contractAddr, err := evmParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder.mu.RLock()
+	token, ok := decoder.tokens[contractAddr]
+	decoder.mu.RUnlock()
+	if ok {
+		copied := *token
+		return &copied, nil
+	}
+
+	var (
+		decimals uint8
+		symbol   string
+		name     string
+	)
+	if err := decoder.call(contractAddr, &decimals, "decimals"); err != nil {
+		return nil, fmt.Errorf("get decimals of contract %s failed, %v", contractAddr.Hex(), err)
+	}
+	//部分合约未实现symbol及name，不影响金额计算
+	decoder.call(contractAddr, &symbol, "symbol")
+	decoder.call(contractAddr, &name, "name")
+
+	token = &openwallet.SmartContract{
+		ContractID: openwallet.GenContractID(decoder.wm.Symbol(), contractAddr.Hex()),
+		Symbol:     decoder.wm.Symbol(),
+		Address:    contractAddr.Hex(),
+		Token:      symbol,
+		Protocol:   EVMTokenProtocol,
+		Name:       name,
+		Decimals:   uint64(decimals),
+	}
+
+	decoder.mu.Lock()
+	decoder.tokens[contractAddr] = token
+	decoder.mu.Unlock()
+
+	copied := *token
+	return &copied, nil
+}
+
+//balanceOf 查询地址在合约中的余额，最小单位
+func (decoder *EVMContractDecoder) balanceOf(contract common.Address, address string) (*big.Int, error) {
+	owner, err := evmParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	value := new(big.Int)
+	if err := decoder.call(contract, &value, "balanceOf", owner); err != nil {
+		return nil, fmt.Errorf("get token balance of %s failed, %v", address, err)
+	}
+	return value, nil
+}
+
+//call 调用合约只读方法
+func (decoder *EVMContractDecoder) call(contract common.Address, result interface{}, method string, args ...interface{}) error {
+	data, err := evmTokenContractABI.Pack(method, args...)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), evmRequestTimeout)
+	defer cancel()
+
+	output, err := decoder.wm.EVMClient.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if err != nil {
+		return err
+	}
+	if len(output) == 0 {
+		return fmt.Errorf("contract %s returns empty result of %s", contract.Hex(), method)
+	}
+	return evmTokenContractABI.Unpack(result, method, output)
+}
+
+//evmTokenTransferData 代币transfer调用数据
+func evmTokenTransferData(to common.Address, value *big.Int) ([]byte, error) {
+	return evmTokenContractABI.Pack("transfer", to, value)
+}
+
+//EVMTokenTransfer 从日志解析的代币转账
+type EVMTokenTransfer struct {
+	Contract common.Address
+	From     common.Address
+	To       common.Address
+	Value    *big.Int
+	LogIndex uint
+}
+
+//evmParseTransferLog 解析Transfer事件日志，非Transfer事件返回false
+func evmParseTransferLog(log *types.Log) (*EVMTokenTransfer, bool) {
+	//ERC721的Transfer事件第3个参数也被索引，不属于代币转账
+	if len(log.Topics) != 3 || log.Topics[0] != EVMTransferEventTopic || len(log.Data) != 32 {
+		return nil, false
+	}
+	return &EVMTokenTransfer{
+		Contract: log.Address,
+		From:     common.BytesToAddress(log.Topics[1].Bytes()),
+		To:       common.BytesToAddress(log.Topics[2].Bytes()),
+		Value:    new(big.Int).SetBytes(log.Data),
+		LogIndex: log.Index,
+	}, true
+}
@@ -0,0 +1,209 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/txsigner"
+	"github.com/blocktree/openwallet/openwallet"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+//testTokenBin https://ethereum.org/token 示例合约，构造参数为发行量、名称、精度及符号
+const testTokenBin = `60606040526040516107fd3803806107fd83398101604052805160805160a05160c051929391820192909101600160a060020a0333166000908152600360209081526040822086905581548551838052601f6002600019610100600186161502019093169290920482018390047f290decd9548b62a8d60345a988386fc84ba6bc95484008f6362f93160ef3e56390810193919290918801908390106100e857805160ff19168380011785555b506101189291505b8082111561017157600081556001016100b4565b50506002805460ff19168317905550505050610658806101a56000396000f35b828001600101855582156100ac579182015b828111156100ac5782518260005055916020019190600101906100fa565b50508060016000509080519060200190828054600181600116156101000203166002900490600052602060002090601f016020900481019282601f1061017557805160ff19168380011785555b506100c89291506100b4565b5090565b82800160010185558215610165579182015b8281111561016557825182600050559160200191906001019061018756606060405236156100775760e060020a600035046306fdde03811461007f57806323b872dd146100dc578063313ce5671461010e57806370a082311461011a57806395d89b4114610132578063a9059cbb1461018e578063cae9ca51146101bd578063dc3080f21461031c578063dd62ed3e14610341575b610365610002565b61036760008054602060026001831615610100026000190190921691909104601f810182900490910260809081016040526060828152929190828280156104eb5780601f106104c0576101008083540402835291602001916104eb565b6103d5600435602435604435600160a060020a038316600090815260036020526040812054829010156104f357610002565b6103e760025460ff1681565b6103d560043560036020526000908152604090205481565b610367600180546020600282841615610100026000190190921691909104601f810182900490910260809081016040526060828152929190828280156104eb5780601f106104c0576101008083540402835291602001916104eb565b610365600435602435600160a060020a033316600090815260036020526040902054819010156103f157610002565b60806020604435600481810135601f8101849004909302840160405260608381526103d5948235946024803595606494939101919081908382808284375094965050505050505060006000836004600050600033600160a060020a03168152602001908152602001600020600050600087600160a060020a031681526020019081526020016000206000508190555084905080600160a060020a0316638f4ffcb1338630876040518560e060020a0281526004018085600160a060020a0316815260200184815260200183600160a060020a03168152602001806020018281038252838181518152602001915080519060200190808383829060006004602084601f0104600f02600301f150905090810190601f1680156102f25780820380516001836020036101000a031916815260200191505b50955050505050506000604051808303816000876161da5a03f11561000257505050509392505050565b6005602090815260043560009081526040808220909252602435815220546103d59081565b60046020818152903560009081526040808220909252602435815220546103d59081565b005b60405180806020018281038252838181518152602001915080519060200190808383829060006004602084601f0104600f02600301f150905090810190601f1680156103c75780820380516001836020036101000a031916815260200191505b509250505060405180910390f35b60408051918252519081900360200190f35b6060908152602090f35b600160a060020a03821660009081526040902054808201101561041357610002565b806003600050600033600160a060020a03168152602001908152602001600020600082828250540392505081905550806003600050600084600160a060020a0316815260200190815260200160002060008282825054019250508190555081600160a060020a031633600160a060020a03167fddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef836040518082815260200191505060405180910390a35050565b820191906000526020600020905b8154815290600101906020018083116104ce57829003601f168201915b505050505081565b600160a060020a03831681526040812054808301101561051257610002565b600160a060020a0380851680835260046020908152604080852033949094168086529382528085205492855260058252808520938552929052908220548301111561055c57610002565b816003600050600086600160a060020a03168152602001908152602001600020600082828250540392505081905550816003600050600085600160a060020a03168152602001908152602001600020600082828250540192505081905550816005600050600086600160a060020a03168152602001908152602001600020600050600033600160a060020a0316815260200190815260200160002060008282825054019250508190555082600160a060020a031633600160a060020a03167fddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef846040518082815260200191505060405180910390a3939250505056`
+
+const testTokenConstructorABI = `[{"inputs":[{"name":"initialSupply","type":"uint256"},{"name":"tokenName","type":"string"},{"name":"decimalUnits","type":"uint8"},{"name":"tokenSymbol","type":"string"}],"type":"constructor"}]`
+
+type testExtractObserver struct {
+	mu   sync.Mutex
+	data map[string][]*openwallet.TxExtractData
+}
+
+func (o *testExtractObserver) BlockScanNotify(header *openwallet.BlockHeader) error {
+	return nil
+}
+
+func (o *testExtractObserver) BlockExtractDataNotify(sourceKey string, data *openwallet.TxExtractData) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.data[sourceKey] = append(o.data[sourceKey], data)
+	return nil
+}
+
+func TestEVMContractDecoder_TokenTransfer(t *testing.T) {
+
+	dataDir, err := ioutil.TempDir("", "velas-evm-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	receiver := "0x3535353535353535353535353535353535353535"
+
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{sender: {Balance: new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(10))}}, 8000000)
+	defer sim.Close()
+
+	//发行10000.00个精度为2的代币
+	constructorABI, _ := abi.JSON(strings.NewReader(testTokenConstructorABI))
+	contractAddr, _, _, err := bind.DeployContract(bind.NewKeyedTransactor(key), constructorABI, common.FromHex(testTokenBin), sim, big.NewInt(1000000), "Velas Token", uint8(2), "VT")
+	if err != nil {
+		t.Fatalf("DeployContract() unexpected error = %v", err)
+	}
+	sim.Commit()
+
+	wm := NewWalletManager()
+	wm.Config.DataDir = dataDir
+	wm.Config.makeDataDir()
+	wm.Config.EVMChainID = params.AllEthashProtocolChanges.ChainID.Int64()
+	wm.SetEVMBackend(&simulatedEVMBackend{sim})
+	wm.Signer = txsigner.NewStaticSigner(map[string][]byte{"": crypto.FromECDSA(key)})
+
+	wrapper := &testEVMWalletDAI{testWalletDAI{addresses: map[string]*openwallet.Address{
+		sender.Hex(): {AccountID: "account", Address: sender.Hex()},
+	}}}
+
+	contract, err := wm.EVMContractDecoder.GetTokenContract(contractAddr.Hex())
+	if err != nil {
+		t.Fatalf("GetTokenContract() unexpected error = %v", err)
+	}
+	if contract.Token != "VT" || contract.Name != "Velas Token" || contract.Decimals != 2 || contract.Protocol != EVMTokenProtocol {
+		t.Errorf("GetTokenContract() = %+v", contract)
+	}
+
+	tokenBalance := func(address string) string {
+		balances, err := wm.GetSmartContractDecoder().GetTokenBalanceByAddress(*contract, address)
+		if err != nil || len(balances) != 1 {
+			t.Fatalf("GetTokenBalanceByAddress() = %+v, error = %v", balances, err)
+		}
+		return balances[0].Balance.Balance
+	}
+	if balance := tokenBalance(sender.Hex()); balance != "10000" {
+		t.Errorf("GetTokenBalanceByAddress() = %s, want 10000", balance)
+	}
+
+	coin := openwallet.Coin{Symbol: Symbol, IsContract: true, ContractID: contract.ContractID, Contract: *contract}
+	rawTx := &openwallet.RawTransaction{
+		Coin:    coin,
+		Account: &openwallet.AssetsAccount{AccountID: "account"},
+		To:      map[string]string{receiver: "12.5"},
+	}
+	decoder := wm.GetTransactionDecoder()
+	if err := decoder.CreateRawTransaction(wrapper, rawTx); err != nil {
+		t.Fatalf("CreateRawTransaction() unexpected error = %v", err)
+	}
+	if rawTx.TxAmount != "-12.5" || rawTx.TxTo[0] != receiver+":12.5" {
+		t.Errorf("CreateRawTransaction() TxAmount = %s, TxTo = %v", rawTx.TxAmount, rawTx.TxTo)
+	}
+	if err := decoder.SignRawTransaction(wrapper, rawTx); err != nil {
+		t.Fatalf("SignRawTransaction() unexpected error = %v", err)
+	}
+	if err := decoder.VerifyRawTransaction(wrapper, rawTx); err != nil {
+		t.Fatalf("VerifyRawTransaction() unexpected error = %v", err)
+	}
+	tx, err := decoder.SubmitRawTransaction(wrapper, rawTx)
+	if err != nil {
+		t.Fatalf("SubmitRawTransaction() unexpected error = %v", err)
+	}
+	if tx.Decimal != 2 || tx.Coin.ContractID != contract.ContractID {
+		t.Errorf("SubmitRawTransaction() = %+v", tx)
+	}
+	sim.Commit()
+
+	if balance := tokenBalance(receiver); balance != "12.5" {
+		t.Errorf("GetTokenBalanceByAddress(receiver) = %s, want 12.5", balance)
+	}
+	if balance := tokenBalance(sender.Hex()); balance != "9987.5" {
+		t.Errorf("GetTokenBalanceByAddress(sender) = %s, want 9987.5", balance)
+	}
+
+	//发送地址记录主币手续费及代币输入，接收地址记录代币输出
+	extracted, err := wm.GetBlockScanner().ExtractTransactionData(tx.TxID, func(target openwallet.ScanTarget) (string, bool) {
+		switch target.Address {
+		case receiver:
+			return "receiver", true
+		case sender.Hex():
+			return "sender", true
+		}
+		return "", false
+	})
+	if err != nil {
+		t.Fatalf("ExtractTransactionData() unexpected error = %v", err)
+	}
+	if data := extracted["sender"]; len(data) != 2 || data[0].Transaction.Coin.IsContract || data[1].TxInputs[0].Amount != "12.5" {
+		t.Errorf("ExtractTransactionData() sender = %+v", data)
+	}
+	data := extracted["receiver"]
+	if len(data) != 1 || len(data[0].TxOutputs) != 1 {
+		t.Fatalf("ExtractTransactionData() receiver = %+v", data)
+	}
+	output := data[0].TxOutputs[0]
+	if output.Amount != "12.5" || !output.Coin.IsContract || output.Coin.Contract.Address != contractAddr.Hex() || output.Coin.ContractID != contract.ContractID {
+		t.Errorf("ExtractTransactionData() output = %+v", output)
+	}
+
+	//区块扫描通过Transfer日志提取代币转账
+	observer := &testExtractObserver{data: make(map[string][]*openwallet.TxExtractData)}
+	wm.GetBlockScanner().AddObserver(observer)
+	wm.GetBlockScanner().SetBlockScanAddressFunc(func(address string) (string, bool) {
+		return "receiver", address == receiver
+	})
+	if err := wm.GetBlockScanner().ScanBlock(2); err != nil {
+		t.Errorf("ScanBlock() unexpected error = %v", err)
+	}
+	observer.mu.Lock()
+	scanned := observer.data["receiver"]
+	observer.mu.Unlock()
+	if len(scanned) != 1 || scanned[0].TxOutputs[0].Amount != "12.5" || scanned[0].Transaction.Coin.Contract.Token != "VT" {
+		t.Errorf("ScanBlock() extract data = %+v", scanned)
+	}
+
+	//代币余额不足
+	rawTx = &openwallet.RawTransaction{
+		Coin:    coin,
+		Account: &openwallet.AssetsAccount{AccountID: "account"},
+		To:      map[string]string{receiver: "100000"},
+	}
+	err = decoder.CreateRawTransaction(wrapper, rawTx)
+	if owErr, ok := err.(*openwallet.Error); !ok || owErr.Code() != openwallet.ErrInsufficientTokenBalanceOfAddress {
+		t.Errorf("CreateRawTransaction() with insufficient token balance error = %v", err)
+	}
+
+	//汇总代币，手续费不从代币中扣除
+	summary, err := decoder.CreateSummaryRawTransactionWithError(wrapper, &openwallet.SummaryRawTransaction{
+		Coin:           coin,
+		Account:        &openwallet.AssetsAccount{AccountID: "account"},
+		SummaryAddress: receiver,
+		MinTransfer:    "1",
+		AddressLimit:   -1,
+	})
+	if err != nil || len(summary) != 1 || summary[0].Error != nil || summary[0].RawTx.To[receiver] != "9987.5" {
+		t.Errorf("CreateSummaryRawTransactionWithError() = %+v, error = %v", summary, err)
+	}
+}
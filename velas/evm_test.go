@@ -0,0 +1,237 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/assetsadapterstore/velas-adapter/txsigner"
+	"github.com/blocktree/openwallet/openwallet"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/shopspring/decimal"
+)
+
+//simulatedEVMBackend 补充模拟链缺少的BlockByNumber
+type simulatedEVMBackend struct {
+	*backends.SimulatedBackend
+}
+
+func (b *simulatedEVMBackend) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	if number == nil {
+		return b.Blockchain().CurrentBlock(), nil
+	}
+	block := b.Blockchain().GetBlockByNumber(number.Uint64())
+	if block == nil {
+		return nil, ethereum.NotFound
+	}
+	return block, nil
+}
+
+type testEVMWalletDAI struct {
+	testWalletDAI
+}
+
+func (w *testEVMWalletDAI) GetAddressList(offset, limit int, cols ...interface{}) ([]*openwallet.Address, error) {
+	list := make([]*openwallet.Address, 0)
+	for _, a := range w.addresses {
+		list = append(list, a)
+	}
+	return list, nil
+}
+
+func TestEVMBackend_Transfer(t *testing.T) {
+
+	dataDir, err := ioutil.TempDir("", "velas-evm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	receiver := "0x3535353535353535353535353535353535353535"
+
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{sender: {Balance: new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(10))}}, 8000000)
+	defer sim.Close()
+
+	wm := NewWalletManager()
+	wm.Config.DataDir = dataDir
+	wm.Config.makeDataDir()
+	wm.Config.EVMChainID = params.AllEthashProtocolChanges.ChainID.Int64()
+	wm.SetEVMBackend(&simulatedEVMBackend{sim})
+	wm.Signer = txsigner.NewStaticSigner(map[string][]byte{"": crypto.FromECDSA(key)})
+
+	address, err := wm.GetAddressDecode().PublicKeyToAddress(crypto.CompressPubkey(&key.PublicKey), false)
+	if err != nil || address != sender.Hex() {
+		t.Fatalf("PublicKeyToAddress() = %s, error = %v, want %s", address, err, sender.Hex())
+	}
+
+	wrapper := &testEVMWalletDAI{testWalletDAI{addresses: map[string]*openwallet.Address{
+		address: {AccountID: "account", Address: address},
+	}}}
+
+	transfer := func(amount string) *openwallet.Transaction {
+		rawTx := &openwallet.RawTransaction{
+			Coin:    openwallet.Coin{Symbol: Symbol},
+			Account: &openwallet.AssetsAccount{AccountID: "account"},
+			To:      map[string]string{receiver: amount},
+		}
+		decoder := wm.GetTransactionDecoder()
+		if err := decoder.CreateRawTransaction(wrapper, rawTx); err != nil {
+			t.Fatalf("CreateRawTransaction() unexpected error = %v", err)
+		}
+		if err := decoder.SignRawTransaction(wrapper, rawTx); err != nil {
+			t.Fatalf("SignRawTransaction() unexpected error = %v", err)
+		}
+		if err := decoder.VerifyRawTransaction(wrapper, rawTx); err != nil {
+			t.Fatalf("VerifyRawTransaction() unexpected error = %v", err)
+		}
+		tx, err := decoder.SubmitRawTransaction(wrapper, rawTx)
+		if err != nil {
+			t.Fatalf("SubmitRawTransaction() unexpected error = %v", err)
+		}
+		return tx
+	}
+
+	//未打包前连续转账，nonce由本地记录递增
+	first := transfer("1.5")
+	second := transfer("0.5")
+	sim.Commit()
+
+	balances, err := wm.GetBlockScanner().GetBalanceByAddress(receiver)
+	if err != nil || balances[0].Balance != "2" {
+		t.Fatalf("GetBalanceByAddress() = %+v, error = %v, want 2", balances, err)
+	}
+
+	for _, tx := range []*openwallet.Transaction{first, second} {
+		status, err := wm.GetEVMTransactionStatus(tx.TxID)
+		if err != nil || status.Status != openwallet.TxStatusSuccess || !status.Confirmed {
+			t.Errorf("GetEVMTransactionStatus(%s) = %+v, error = %v", tx.TxID, status, err)
+		}
+	}
+
+	//区块扫描提取接收地址的入账记录
+	extracted, err := wm.GetBlockScanner().ExtractTransactionData(first.TxID, func(target openwallet.ScanTarget) (string, bool) {
+		return "receiver", target.Address == receiver
+	})
+	if err != nil {
+		t.Fatalf("ExtractTransactionData() unexpected error = %v", err)
+	}
+	data := extracted["receiver"]
+	if len(data) != 1 || len(data[0].TxOutputs) != 1 || data[0].TxOutputs[0].Amount != "1.5" || data[0].Transaction.Status != openwallet.TxStatusSuccess {
+		t.Errorf("ExtractTransactionData() = %+v", data)
+	}
+
+	wm.GetBlockScanner().SetBlockScanAddressFunc(func(address string) (string, bool) {
+		return "receiver", address == receiver
+	})
+	if err := wm.GetBlockScanner().ScanBlock(1); err != nil {
+		t.Errorf("ScanBlock() unexpected error = %v", err)
+	}
+
+	//余额不足
+	rawTx := &openwallet.RawTransaction{
+		Coin:    openwallet.Coin{Symbol: Symbol},
+		Account: &openwallet.AssetsAccount{AccountID: "account"},
+		To:      map[string]string{receiver: "100"},
+	}
+	if err := wm.GetTransactionDecoder().CreateRawTransaction(wrapper, rawTx); err == nil {
+		t.Errorf("CreateRawTransaction() with insufficient balance want error")
+	}
+
+	if nonce, _ := wm.EVMNonce.Nonce(wm.EVMClient, sender); nonce != 2 {
+		t.Errorf("Nonce() = %d, want 2", nonce)
+	}
+}
+
+func TestEVMNonceManager_Reserve(t *testing.T) {
+
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}}, 8000000)
+	defer sim.Close()
+	client := &simulatedEVMBackend{sim}
+
+	now := time.Unix(1700000000, 0)
+	m := NewEVMNonceManager(time.Minute)
+	m.now = func() time.Time { return now }
+
+	//同时构建的交易单得到不同的nonce
+	var wg sync.WaitGroup
+	nonces := make(chan uint64, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := m.Reserve(client, sender)
+			if err != nil {
+				t.Errorf("Reserve() unexpected error = %v", err)
+			}
+			nonces <- nonce
+		}()
+	}
+	wg.Wait()
+	close(nonces)
+	seen := make(map[uint64]bool)
+	for nonce := range nonces {
+		if seen[nonce] || nonce >= 10 {
+			t.Errorf("Reserve() = %d, duplicated or out of range", nonce)
+		}
+		seen[nonce] = true
+	}
+
+	//广播失败释放的nonce优先使用，释放最后的nonce时回退
+	m.Release(sender, 5)
+	m.Release(sender, 9)
+	if nonce, _ := m.Reserve(client, sender); nonce != 5 {
+		t.Errorf("Reserve() after release = %d, want 5", nonce)
+	}
+	if nonce, _ := m.Nonce(client, sender); nonce != 9 {
+		t.Errorf("Nonce() after releasing last nonce = %d, want 9", nonce)
+	}
+	m.Commit(sender, 0)
+	m.Release(sender, 0)
+	if nonce, _ := m.Nonce(client, sender); nonce != 9 {
+		t.Errorf("Nonce() after releasing submitted nonce = %d, want 9", nonce)
+	}
+
+	//超时未广播的nonce可重新使用
+	now = now.Add(2 * time.Minute)
+	if nonce, _ := m.Reserve(client, sender); nonce != 1 {
+		t.Errorf("Reserve() after timeout = %d, want 1", nonce)
+	}
+}
+
+func TestEVMToWei(t *testing.T) {
+	amount, _ := decimal.NewFromString("1.5")
+	if wei := evmToWei(amount); wei.String() != "1500000000000000000" {
+		t.Errorf("evmToWei(1.5) = %s", wei.String())
+	}
+	if v := evmFromWei(big.NewInt(params.Ether)); v.String() != "1" {
+		t.Errorf("evmFromWei(1 ether) = %s", v.String())
+	}
+}
@@ -0,0 +1,609 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/assetsadapterstore/velas-adapter/txsigner"
+	"github.com/blocktree/openwallet/openwallet"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/shopspring/decimal"
+)
+
+//EVMTransactionDecoder EVM账户模型的交易单解析器
+type EVMTransactionDecoder struct {
+	openwallet.TransactionDecoderBase
+	wm *WalletManager //钱包管理者
+}
+
+//NewEVMTransactionDecoder EVM交易单解析器
+func NewEVMTransactionDecoder(wm *WalletManager) *EVMTransactionDecoder {
+	decoder := EVMTransactionDecoder{}
+	decoder.wm = wm
+	return &decoder
+}
+
+//CreateRawTransaction 创建交易单，从账户中余额足够的地址转账，只支持一个接收地址
+func (decoder *EVMTransactionDecoder) CreateRawTransaction(wrapper openwallet.WalletDAI, rawTx *openwallet.RawTransaction) error {
+
+	if len(rawTx.To) != 1 {
+		return fmt.Errorf("evm transaction only support one receiver")
+	}
+
+	var (
+		to     string
+		amount decimal.Decimal
+	)
+	for addr, a := range rawTx.To {
+		to = addr
+		amount, _ = decimal.NewFromString(a)
+	}
+
+	if !amount.IsPositive() {
+		return fmt.Errorf("amount to send must be positive")
+	}
+
+	receiver, err := decoder.wm.evmParseReceiver(to)
+	if err != nil {
+		return err
+	}
+
+	gasPrice, err := decoder.gasPrice(rawTx.FeeRate)
+	if err != nil {
+		return err
+	}
+
+	value := evmToUnit(amount, evmCoinDecimals(rawTx.Coin))
+
+	addresses, err := wrapper.GetAddressList(0, -1, "AccountID", rawTx.Account.AccountID)
+	if err != nil {
+		return err
+	}
+	if len(addresses) == 0 {
+		return openwallet.Errorf(openwallet.ErrAccountNotAddress, "[%s] have not address", rawTx.Account.AccountID)
+	}
+
+	if rawTx.Coin.IsContract {
+		return decoder.createTokenRawTransaction(rawTx, addresses, receiver, value, gasPrice)
+	}
+
+	fees := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(decoder.wm.Config.EVMGasLimit))
+	need := new(big.Int).Add(value, fees)
+
+	//选择第一个余额足够支付金额及手续费的地址
+	for _, addr := range addresses {
+		balance, err := decoder.balance(addr.Address)
+		if err != nil {
+			return err
+		}
+		if balance.Cmp(need) >= 0 {
+			call, err := decoder.newEVMCall(rawTx.Coin, addr, receiver, value)
+			if err != nil {
+				return err
+			}
+			return decoder.createEVMRawTransaction(rawTx, addr, call, gasPrice)
+		}
+	}
+
+	return openwallet.Errorf(openwallet.ErrInsufficientBalanceOfAccount, "[%s] balance is not enough to send %s and fees %s", rawTx.Account.AccountID, amount.String(), evmFromWei(fees).String())
+}
+
+//createTokenRawTransaction 创建代币交易单，选择第一个代币余额足够且主币足够支付手续费的地址
+func (decoder *EVMTransactionDecoder) createTokenRawTransaction(rawTx *openwallet.RawTransaction, addresses []*openwallet.Address, receiver common.Address, value, gasPrice *big.Int) error {
+
+	contract, err := evmParseAddress(rawTx.Coin.Contract.Address)
+	if err != nil {
+		return err
+	}
+
+	tokenEnough := false
+	for _, addr := range addresses {
+		tokenBalance, err := decoder.wm.EVMContractDecoder.balanceOf(contract, addr.Address)
+		if err != nil {
+			return err
+		}
+		if tokenBalance.Cmp(value) < 0 {
+			continue
+		}
+		tokenEnough = true
+
+		call, err := decoder.newEVMCall(rawTx.Coin, addr, receiver, value)
+		if err != nil {
+			return err
+		}
+		fees := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(call.GasLimit))
+
+		balance, err := decoder.balance(addr.Address)
+		if err != nil {
+			return err
+		}
+		if balance.Cmp(fees) >= 0 {
+			return decoder.createEVMRawTransaction(rawTx, addr, call, gasPrice)
+		}
+	}
+
+	amount := evmFromUnit(value, evmCoinDecimals(rawTx.Coin))
+	if tokenEnough {
+		return openwallet.Errorf(openwallet.ErrInsufficientFees, "[%s] the address which has enough %s token does not have enough fees", rawTx.Account.AccountID, amount.String())
+	}
+	return openwallet.Errorf(openwallet.ErrInsufficientTokenBalanceOfAddress, "[%s] token balance is not enough to send %s", rawTx.Account.AccountID, amount.String())
+}
+
+//SignRawTransaction 签名交易单
+func (decoder *EVMTransactionDecoder) SignRawTransaction(wrapper openwallet.WalletDAI, rawTx *openwallet.RawTransaction) error {
+
+	if rawTx.Signatures == nil || len(rawTx.Signatures) == 0 {
+		return fmt.Errorf("transaction signature is empty")
+	}
+
+	//签名前核对待签名哈希与交易单一致
+	tx, err := decodeEVMRawHex(rawTx.RawHex)
+	if err != nil {
+		return err
+	}
+	msg := decoder.wm.evmSigner().Hash(tx)
+
+	signer, err := decoder.wm.walletSigner(wrapper)
+	if err != nil {
+		return err
+	}
+
+	keySignatures := rawTx.Signatures[rawTx.Account.AccountID]
+	for _, keySignature := range keySignatures {
+
+		if keySignature.Message != hex.EncodeToString(msg.Bytes()) {
+			return openwallet.Errorf(openwallet.ErrSignRawTransactionFailed, "message to sign does not match transaction")
+		}
+
+		signature, err := signer.Sign(&txsigner.SignRequest{
+			KeyID:   signerKeyID(wrapper),
+			HDPath:  keySignature.Address.HDPath,
+			EccType: keySignature.EccType,
+			Message: msg.Bytes(),
+		})
+		if err != nil {
+			return fmt.Errorf("transaction hash sign failed, unexpected error: %v", err)
+		}
+
+		keySignature.Signature = hex.EncodeToString(signature)
+	}
+
+	decoder.wm.Log.Info("transaction hash sign success")
+
+	rawTx.Signatures[rawTx.Account.AccountID] = keySignatures
+
+	return nil
+}
+
+//VerifyRawTransaction 验证交易单，验证签名属于发送地址并返回加入签名后的交易单
+func (decoder *EVMTransactionDecoder) VerifyRawTransaction(wrapper openwallet.WalletDAI, rawTx *openwallet.RawTransaction) error {
+
+	tx, err := decodeEVMRawHex(rawTx.RawHex)
+	if err != nil {
+		return err
+	}
+
+	keySignatures := rawTx.Signatures[rawTx.Account.AccountID]
+	if len(keySignatures) != 1 {
+		return openwallet.Errorf(openwallet.ErrVerifyRawTransactionFailed, "transaction need exactly one signature")
+	}
+	keySignature := keySignatures[0]
+
+	from, err := evmParseAddress(keySignature.Address.Address)
+	if err != nil {
+		return err
+	}
+
+	signature, err := hex.DecodeString(keySignature.Signature)
+	if err != nil {
+		return openwallet.Errorf(openwallet.ErrVerifyRawTransactionFailed, "invalid signature: %v", err)
+	}
+
+	signedTx, err := decoder.combineSignature(tx, signature, from)
+	if err != nil {
+		rawTx.IsCompleted = false
+		return openwallet.Errorf(openwallet.ErrVerifyRawTransactionFailed, "transaction verify failed, unexpected error: %v", err)
+	}
+
+	signedRaw, err := rlp.EncodeToBytes(signedTx)
+	if err != nil {
+		return err
+	}
+
+	decoder.wm.Log.Debug("transaction verify passed")
+	rawTx.IsCompleted = true
+	rawTx.RawHex = hex.EncodeToString(signedRaw)
+
+	return nil
+}
+
+//SubmitRawTransaction 广播交易单
+func (decoder *EVMTransactionDecoder) SubmitRawTransaction(wrapper openwallet.WalletDAI, rawTx *openwallet.RawTransaction) (*openwallet.Transaction, error) {
+
+	if len(rawTx.RawHex) == 0 {
+		return nil, fmt.Errorf("transaction hex is empty")
+	}
+
+	if !rawTx.IsCompleted {
+		return nil, fmt.Errorf("transaction is not completed validation")
+	}
+
+	tx, err := decodeEVMRawHex(rawTx.RawHex)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := decoder.wm.evmSender(tx)
+	if err != nil {
+		return nil, openwallet.Errorf(openwallet.ErrSubmitRawTransactionFailed, "transaction is not signed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), evmRequestTimeout)
+	defer cancel()
+
+	err = decoder.wm.EVMClient.SendTransaction(ctx, tx)
+	if err != nil {
+		//释放构建时预留的nonce，之后的交易单可重新使用
+		decoder.wm.EVMNonce.Release(from, tx.Nonce())
+		return nil, openwallet.Errorf(openwallet.ErrSubmitRawTransactionFailed, "send transaction failed, unexpected error: %v", err)
+	}
+
+	decoder.wm.EVMNonce.Commit(from, tx.Nonce())
+
+	rawTx.TxID = tx.Hash().Hex()
+	rawTx.IsSubmit = true
+
+	//记录一个交易单
+	trx := &openwallet.Transaction{
+		From:       rawTx.TxFrom,
+		To:         rawTx.TxTo,
+		Amount:     rawTx.TxAmount,
+		Coin:       rawTx.Coin,
+		TxID:       rawTx.TxID,
+		Decimal:    evmCoinDecimals(rawTx.Coin),
+		AccountID:  rawTx.Account.AccountID,
+		Fees:       rawTx.Fees,
+		SubmitTime: time.Now().Unix(),
+	}
+
+	trx.WxID = openwallet.GenTransactionWxID(trx)
+
+	return trx, nil
+}
+
+//GetRawTransactionFeeRate 获取gas价格
+func (decoder *EVMTransactionDecoder) GetRawTransactionFeeRate() (feeRate string, unit string, err error) {
+	gasPrice, err := decoder.wm.EVMGasPrice()
+	if err != nil {
+		return "", "", err
+	}
+	return evmFromWei(gasPrice).String(), "Gas", nil
+}
+
+//CreateSummaryRawTransaction 创建汇总交易，返回原始交易单数组
+func (decoder *EVMTransactionDecoder) CreateSummaryRawTransaction(wrapper openwallet.WalletDAI, sumRawTx *openwallet.SummaryRawTransaction) ([]*openwallet.RawTransaction, error) {
+	rawTxWithErrArray, err := decoder.CreateSummaryRawTransactionWithError(wrapper, sumRawTx)
+	if err != nil {
+		return nil, err
+	}
+	rawTxArray := make([]*openwallet.RawTransaction, 0)
+	for _, rawTxWithErr := range rawTxWithErrArray {
+		if rawTxWithErr.Error != nil {
+			continue
+		}
+		rawTxArray = append(rawTxArray, rawTxWithErr.RawTx)
+	}
+	return rawTxArray, nil
+}
+
+//CreateSummaryRawTransactionWithError 创建汇总交易，每个地址的余额扣除保留余额及手续费后转到汇总地址
+func (decoder *EVMTransactionDecoder) CreateSummaryRawTransactionWithError(wrapper openwallet.WalletDAI, sumRawTx *openwallet.SummaryRawTransaction) ([]*openwallet.RawTransactionWithError, error) {
+
+	var (
+		decimals           = evmCoinDecimals(sumRawTx.Coin)
+		minTransfer, _     = decimal.NewFromString(sumRawTx.MinTransfer)
+		retainedBalance, _ = decimal.NewFromString(sumRawTx.RetainedBalance)
+		rawTxArray         = make([]*openwallet.RawTransactionWithError, 0)
+		contract           common.Address
+	)
+
+	if minTransfer.LessThan(retainedBalance) {
+		return nil, fmt.Errorf("mini transfer amount must be greater than address retained balance")
+	}
+
+	summaryAddress, err := decoder.wm.evmParseReceiver(sumRawTx.SummaryAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	if sumRawTx.Coin.IsContract {
+		contract, err = evmParseAddress(sumRawTx.Coin.Contract.Address)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	gasPrice, err := decoder.gasPrice(sumRawTx.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+	fees := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(decoder.wm.Config.EVMGasLimit))
+
+	addresses, err := wrapper.GetAddressList(sumRawTx.AddressStartIndex, sumRawTx.AddressLimit, "AccountID", sumRawTx.Account.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, addr := range addresses {
+
+		var balance *big.Int
+		if sumRawTx.Coin.IsContract {
+			balance, err = decoder.wm.EVMContractDecoder.balanceOf(contract, addr.Address)
+		} else {
+			balance, err = decoder.balance(addr.Address)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if evmFromUnit(balance, decimals).LessThan(minTransfer) || balance.Sign() == 0 {
+			continue
+		}
+
+		//代币的手续费由地址的主币支付，不从汇总金额中扣除
+		value := new(big.Int).Sub(balance, evmToUnit(retainedBalance, decimals))
+		if !sumRawTx.Coin.IsContract {
+			value.Sub(value, fees)
+		}
+		if value.Sign() <= 0 {
+			continue
+		}
+
+		decoder.wm.Log.Debugf("summary address: %s, balance: %s, send: %s", addr.Address, evmFromUnit(balance, decimals).String(), evmFromUnit(value, decimals).String())
+
+		rawTx := &openwallet.RawTransaction{
+			Coin:    sumRawTx.Coin,
+			Account: sumRawTx.Account,
+			To: map[string]string{
+				sumRawTx.SummaryAddress: evmFromUnit(value, decimals).String(),
+			},
+			Required: 1,
+		}
+
+		createErr := decoder.createSummaryRawTransaction(rawTx, addr, summaryAddress, value, gasPrice)
+		rawTxWithErr := &openwallet.RawTransactionWithError{
+			RawTx: rawTx,
+			Error: openwallet.ConvertError(createErr),
+		}
+
+		rawTxArray = append(rawTxArray, rawTxWithErr)
+	}
+
+	return rawTxArray, nil
+}
+
+//createSummaryRawTransaction 创建单个地址的汇总交易单，代币汇总需地址主币足够支付手续费
+func (decoder *EVMTransactionDecoder) createSummaryRawTransaction(rawTx *openwallet.RawTransaction, from *openwallet.Address, to common.Address, value, gasPrice *big.Int) error {
+
+	call, err := decoder.newEVMCall(rawTx.Coin, from, to, value)
+	if err != nil {
+		return err
+	}
+
+	if rawTx.Coin.IsContract {
+		fees := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(call.GasLimit))
+		balance, err := decoder.balance(from.Address)
+		if err != nil {
+			return err
+		}
+		if balance.Cmp(fees) < 0 {
+			return openwallet.Errorf(openwallet.ErrInsufficientFees, "address %s balance %s is not enough to pay fees %s", from.Address, evmFromWei(balance).String(), evmFromWei(fees).String())
+		}
+	}
+
+	return decoder.createEVMRawTransaction(rawTx, from, call, gasPrice)
+}
+
+//evmCall 交易单调用参数，代币转账时交易单发往合约地址并携带transfer调用数据
+type evmCall struct {
+	Receiver common.Address //实际接收地址
+	Value    *big.Int       //实际转账金额，最小单位
+	TxTo     common.Address //交易单接收地址
+	TxValue  *big.Int       //交易单转账的主币金额
+	Data     []byte         //合约调用数据
+	GasLimit uint64
+}
+
+//newEVMCall 创建转账调用，代币转账的gas上限通过节点估算
+func (decoder *EVMTransactionDecoder) newEVMCall(coin openwallet.Coin, from *openwallet.Address, receiver common.Address, value *big.Int) (*evmCall, error) {
+
+	if !coin.IsContract {
+		return &evmCall{
+			Receiver: receiver,
+			Value:    value,
+			TxTo:     receiver,
+			TxValue:  value,
+			GasLimit: decoder.wm.Config.EVMGasLimit,
+		}, nil
+	}
+
+	fromAddr, err := evmParseAddress(from.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	contract, err := evmParseAddress(coin.Contract.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := evmTokenTransferData(receiver, value)
+	if err != nil {
+		return nil, openwallet.Errorf(openwallet.ErrCreateRawTransactionFailed, "pack token transfer failed, unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), evmRequestTimeout)
+	defer cancel()
+
+	gasLimit, err := decoder.wm.EVMClient.EstimateGas(ctx, ethereum.CallMsg{From: fromAddr, To: &contract, Data: data})
+	if err != nil {
+		return nil, openwallet.Errorf(openwallet.ErrCreateRawTransactionFailed, "estimate gas of token transfer failed, unexpected error: %v", err)
+	}
+
+	return &evmCall{
+		Receiver: receiver,
+		Value:    value,
+		TxTo:     contract,
+		TxValue:  big.NewInt(0),
+		Data:     data,
+		GasLimit: gasLimit,
+	}, nil
+}
+
+//createEVMRawTransaction 创建EVM原始交易单，RawHex为未签名交易单的RLP编码
+func (decoder *EVMTransactionDecoder) createEVMRawTransaction(rawTx *openwallet.RawTransaction, from *openwallet.Address, call *evmCall, gasPrice *big.Int) error {
+
+	fromAddr, err := evmParseAddress(from.Address)
+	if err != nil {
+		return err
+	}
+
+	//构建时预留nonce，同时构建的交易单不会使用相同的nonce
+	nonce, err := decoder.wm.EVMNonce.Reserve(decoder.wm.EVMClient, fromAddr)
+	if err != nil {
+		return openwallet.Errorf(openwallet.ErrNonceInvaild, "get nonce of %s failed, unexpected error: %v", from.Address, err)
+	}
+
+	tx := types.NewTransaction(nonce, call.TxTo, call.TxValue, call.GasLimit, gasPrice, call.Data)
+
+	raw, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		decoder.wm.EVMNonce.Release(fromAddr, nonce)
+		return openwallet.Errorf(openwallet.ErrCreateRawTransactionFailed, "encode transaction failed, unexpected error: %v", err)
+	}
+
+	msg := decoder.wm.evmSigner().Hash(tx)
+	fees := evmFromWei(new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(call.GasLimit)))
+	amount := evmFromUnit(call.Value, evmCoinDecimals(rawTx.Coin))
+
+	//转给本账户地址时，账户实际支出只有手续费；代币的手续费以主币支付，不计入代币支出
+	accountTotalSent := decimal.Zero
+	if !rawTx.Coin.IsContract {
+		accountTotalSent = fees
+	}
+	if call.Receiver != fromAddr {
+		accountTotalSent = accountTotalSent.Add(amount)
+	}
+
+	rawTx.RawHex = hex.EncodeToString(raw)
+	rawTx.Fees = fees.String()
+	rawTx.FeeRate = evmFromWei(gasPrice).String()
+	rawTx.Signatures = map[string][]*openwallet.KeySignature{
+		rawTx.Account.AccountID: {
+			{
+				EccType: decoder.wm.Config.CurveType,
+				Nonce:   fmt.Sprintf("%d", nonce),
+				Address: from,
+				Message: hex.EncodeToString(msg.Bytes()),
+			},
+		},
+	}
+	rawTx.IsBuilt = true
+	rawTx.TxAmount = decimal.Zero.Sub(accountTotalSent).String()
+	rawTx.TxFrom = []string{fmt.Sprintf("%s:%s", from.Address, amount.String())}
+	rawTx.TxTo = []string{fmt.Sprintf("%s:%s", call.Receiver.Hex(), amount.String())}
+
+	return nil
+}
+
+//combineSignature 合并签名，签名器只返回64字节签名，通过恢复发送地址确定recovery id
+func (decoder *EVMTransactionDecoder) combineSignature(tx *types.Transaction, signature []byte, from common.Address) (*types.Transaction, error) {
+
+	if len(signature) != 64 && len(signature) != 65 {
+		return nil, fmt.Errorf("invalid signature length %d", len(signature))
+	}
+
+	//以太坊要求s为低值
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:64])
+	curveN := crypto.S256().Params().N
+	if s.Cmp(new(big.Int).Rsh(curveN, 1)) > 0 {
+		s.Sub(curveN, s)
+	}
+
+	sig := make([]byte, 65)
+	copy(sig[32-len(r.Bytes()):32], r.Bytes())
+	copy(sig[64-len(s.Bytes()):64], s.Bytes())
+
+	signer := decoder.wm.evmSigner()
+	for v := byte(0); v < 2; v++ {
+		sig[64] = v
+		signedTx, err := tx.WithSignature(signer, sig)
+		if err != nil {
+			return nil, err
+		}
+		sender, err := types.Sender(signer, signedTx)
+		if err == nil && sender == from {
+			return signedTx, nil
+		}
+	}
+
+	return nil, fmt.Errorf("signature does not belong to %s", from.Hex())
+}
+
+//gasPrice 优先使用交易单指定的费率
+func (decoder *EVMTransactionDecoder) gasPrice(feeRate string) (*big.Int, error) {
+	if len(feeRate) > 0 {
+		price, err := decimal.NewFromString(feeRate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fee rate: %v", err)
+		}
+		return evmToWei(price), nil
+	}
+	return decoder.wm.EVMGasPrice()
+}
+
+func (decoder *EVMTransactionDecoder) balance(address string) (*big.Int, error) {
+	addr, err := evmParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), evmRequestTimeout)
+	defer cancel()
+	return decoder.wm.EVMClient.BalanceAt(ctx, addr, nil)
+}
+
+//decodeEVMRawHex 解析RLP编码的交易单
+func decodeEVMRawHex(rawHex string) (*types.Transaction, error) {
+	raw, err := hex.DecodeString(rawHex)
+	if err != nil {
+		return nil, openwallet.ConvertError(err)
+	}
+	var tx types.Transaction
+	if err := rlp.DecodeBytes(raw, &tx); err != nil {
+		return nil, fmt.Errorf("invalid evm transaction: %v", err)
+	}
+	return &tx, nil
+}
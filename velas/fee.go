@@ -0,0 +1,173 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	FeeModeFlat     = "flat"     //固定手续费
+	FeeModeSize     = "size"     //按交易大小计算手续费
+	FeeModeFeedback = "feedback" //按交易大小计算，费率根据节点验证结果调整
+
+	feeRateUnitTx   = "TX"
+	feeRateUnitByte = "B"
+)
+
+var (
+	feedbackRaiseScale   = decimal.NewFromFloat(1.25)
+	feedbackDecayScale   = decimal.NewFromFloat(0.99)
+	feedbackMaxRateScale = decimal.New(10, 0) //未配置最高费率时，最高为有效费率的倍数
+)
+
+//FeeModel 手续费模型
+type FeeModel interface {
+	//EstimateFees 估算输入数量为inputs，地址输出数量为outputs的交易手续费，feeRate为空时使用默认费率
+	EstimateFees(inputs, outputs int, feeRate string) (decimal.Decimal, error)
+	//FeeRate 当前默认费率及单位
+	FeeRate() (string, string)
+}
+
+//FeeFeedback 可根据节点验证结果调整费率的手续费模型
+type FeeFeedback interface {
+	//ValidateFeedback 节点验证交易后回调，err为节点返回的验证错误
+	ValidateFeedback(err error)
+}
+
+//NewFeeModel 根据配置创建手续费模型
+func NewFeeModel(c *WalletConfig) (FeeModel, error) {
+	switch strings.ToLower(c.FeeMode) {
+	case "", FeeModeFlat:
+		return &flatFeeModel{fees: c.FixFees}, nil
+	case FeeModeSize, FeeModeFeedback:
+		rate, err := decimal.NewFromString(c.FeeRate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fee rate: %s", c.FeeRate)
+		}
+		minFees := decimal.Zero
+		if len(c.MinFees) > 0 {
+			minFees, err = decimal.NewFromString(c.MinFees)
+			if err != nil {
+				return nil, fmt.Errorf("invalid min fees: %s", c.MinFees)
+			}
+		}
+		sizeModel := &sizeFeeModel{rate: rate, minFees: minFees}
+		if strings.ToLower(c.FeeMode) == FeeModeSize {
+			return sizeModel, nil
+		}
+		//有效费率：配置费率与最低手续费折算的每字节费率的较大值，配置费率为0时也可提高
+		effectiveRate := decimal.Max(rate, minFees.Div(decimal.New(int64(crypto.EstimateSize(1, 1)), 0)))
+		maxRate := effectiveRate.Mul(feedbackMaxRateScale)
+		if len(c.MaxFeeRate) > 0 {
+			maxRate, err = decimal.NewFromString(c.MaxFeeRate)
+			if err != nil || maxRate.LessThan(effectiveRate) {
+				return nil, fmt.Errorf("invalid max fee rate: %s", c.MaxFeeRate)
+			}
+		}
+		if !maxRate.IsPositive() {
+			return nil, fmt.Errorf("feedback fee mode needs a positive fee rate, min fees or max fee rate")
+		}
+		//节点的错误信息须从被拒绝的交易中获取并配置
+		rejection := strings.ToLower(strings.TrimSpace(c.FeeRejectionMessage))
+		if len(rejection) == 0 {
+			return nil, fmt.Errorf("feedback fee mode needs the fee rejection message of node")
+		}
+		return &feedbackFeeModel{sizeFeeModel: sizeModel, baseRate: rate, effectiveRate: effectiveRate, maxRate: maxRate, rejection: rejection}, nil
+	default:
+		return nil, fmt.Errorf("unknown fee mode: %s", c.FeeMode)
+	}
+}
+
+//flatFeeModel 固定手续费，与交易大小无关
+type flatFeeModel struct {
+	fees string
+}
+
+func (m *flatFeeModel) EstimateFees(inputs, outputs int, feeRate string) (decimal.Decimal, error) {
+	if len(feeRate) > 0 {
+		return decimal.NewFromString(feeRate)
+	}
+	return decimal.NewFromString(m.fees)
+}
+
+func (m *flatFeeModel) FeeRate() (string, string) {
+	return m.fees, feeRateUnitTx
+}
+
+//sizeFeeModel 手续费 = 签名后交易大小 * 每字节费率，不低于最低手续费
+type sizeFeeModel struct {
+	mu      sync.RWMutex
+	rate    decimal.Decimal
+	minFees decimal.Decimal
+}
+
+func (m *sizeFeeModel) EstimateFees(inputs, outputs int, feeRate string) (decimal.Decimal, error) {
+	rate := m.currentRate()
+	if len(feeRate) > 0 {
+		r, err := decimal.NewFromString(feeRate)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		rate = r
+	}
+	size := decimal.New(int64(crypto.EstimateSize(inputs, outputs)), 0)
+	fees := rate.Mul(size).Shift(Decimals).Ceil().Shift(-Decimals)
+	return decimal.Max(fees, m.minFees), nil
+}
+
+func (m *sizeFeeModel) FeeRate() (string, string) {
+	return m.currentRate().StringFixed(Decimals), feeRateUnitByte
+}
+
+func (m *sizeFeeModel) currentRate() decimal.Decimal {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.rate
+}
+
+//feedbackFeeModel 节点因手续费不足拒绝交易时提高费率，不超过最高费率，验证通过时逐步回落到配置费率
+type feedbackFeeModel struct {
+	*sizeFeeModel
+	baseRate      decimal.Decimal
+	effectiveRate decimal.Decimal //提高费率的起点
+	maxRate       decimal.Decimal
+	rejection     string //节点因手续费不足拒绝交易的错误信息，小写
+}
+
+func (m *feedbackFeeModel) ValidateFeedback(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err == nil {
+		m.rate = decimal.Max(m.rate.Mul(feedbackDecayScale), m.baseRate)
+		return
+	}
+
+	if m.isFeeRejection(err) {
+		m.rate = decimal.Min(decimal.Max(m.rate, m.effectiveRate).Mul(feedbackRaiseScale), m.maxRate)
+	}
+}
+
+//isFeeRejection 判断节点的验证错误是否为手续费不足的错误信息，其他包含fee字样的错误不提高费率
+func (m *feedbackFeeModel) isFeeRejection(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), m.rejection)
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestFeeModel_EstimateFees(t *testing.T) {
+	c := NewConfig(Symbol)
+	c.FixFees = "0.001"
+	c.FeeRate = "0.0000001"
+	c.MinFees = "0.00001"
+
+	tests := []struct {
+		mode    string
+		inputs  int
+		outputs int
+		feeRate string
+		want    string
+	}{
+		{mode: FeeModeFlat, inputs: 1, outputs: 2, want: "0.001"},
+		{mode: FeeModeFlat, inputs: 50, outputs: 2, want: "0.001"},
+		{mode: FeeModeFlat, inputs: 1, outputs: 2, feeRate: "0.002", want: "0.002"},
+		{mode: FeeModeSize, inputs: 1, outputs: 2, want: "0.000024"},
+		{mode: FeeModeSize, inputs: 50, outputs: 2, want: "0.0007296"},
+		{mode: FeeModeSize, inputs: 1, outputs: 2, feeRate: "0", want: "0.00001"},
+	}
+
+	for _, tt := range tests {
+		c.FeeMode = tt.mode
+		m, err := NewFeeModel(c)
+		if err != nil {
+			t.Fatalf("NewFeeModel(%s) error = %v", tt.mode, err)
+		}
+		got, err := m.EstimateFees(tt.inputs, tt.outputs, tt.feeRate)
+		if err != nil {
+			t.Errorf("EstimateFees() error = %v", err)
+			continue
+		}
+		want, _ := decimal.NewFromString(tt.want)
+		if !got.Equal(want) {
+			t.Errorf("[%s] EstimateFees(%d, %d) = %s, want %s", tt.mode, tt.inputs, tt.outputs, got, want)
+		}
+	}
+}
+
+func TestFeeModel_ValidateFeedback(t *testing.T) {
+	c := NewConfig(Symbol)
+	c.FeeMode = FeeModeFeedback
+	c.FeeRate = "0.0000001"
+
+	//节点的错误信息须配置
+	if _, err := NewFeeModel(c); err == nil {
+		t.Errorf("NewFeeModel() without fee rejection message want error")
+	}
+	c.FeeRejectionMessage = "insufficient commission"
+
+	m, err := NewFeeModel(c)
+	if err != nil {
+		t.Fatalf("NewFeeModel() error = %v", err)
+	}
+	feedback, ok := m.(FeeFeedback)
+	if !ok {
+		t.Fatalf("feedback fee model does not implement FeeFeedback")
+	}
+
+	before, _ := m.EstimateFees(1, 1, "")
+	feedback.ValidateFeedback(errors.New("insufficient commission"))
+	raised, _ := m.EstimateFees(1, 1, "")
+	if !raised.GreaterThan(before) {
+		t.Errorf("fees not raised after rejection: %s -> %s", before, raised)
+	}
+
+	for _, msg := range []string{"invalid signature", "fee output index is invalid", "commission output is missing"} {
+		feedback.ValidateFeedback(errors.New(msg))
+		if got, _ := m.EstimateFees(1, 1, ""); !got.Equal(raised) {
+			t.Errorf("fees changed after unrelated rejection %q: %s -> %s", msg, raised, got)
+		}
+	}
+
+	//费率不超过最高费率
+	for i := 0; i < 100; i++ {
+		feedback.ValidateFeedback(errors.New("Insufficient Commission: 100 < 200"))
+	}
+	if rate, _ := m.FeeRate(); rate != "0.00000100" {
+		t.Errorf("FeeRate() after repeated rejections = %s, want max rate 0.00000100", rate)
+	}
+
+	for i := 0; i < 1000; i++ {
+		feedback.ValidateFeedback(nil)
+	}
+	if got, _ := m.EstimateFees(1, 1, ""); !got.Equal(before) {
+		t.Errorf("fees not restored to base rate: %s, want %s", got, before)
+	}
+
+	//配置的最高费率低于配置费率
+	c.MaxFeeRate = "0.00000001"
+	if _, err := NewFeeModel(c); err == nil {
+		t.Errorf("NewFeeModel() with max fee rate below fee rate want error")
+	}
+	c.MaxFeeRate = "0.0000002"
+	c.FeeRejectionMessage = "commission too low"
+	m, _ = NewFeeModel(c)
+	m.(FeeFeedback).ValidateFeedback(errors.New("insufficient commission"))
+	m.(FeeFeedback).ValidateFeedback(errors.New("tx commission too low"))
+	m.(FeeFeedback).ValidateFeedback(errors.New("tx commission too low"))
+	m.(FeeFeedback).ValidateFeedback(errors.New("tx commission too low"))
+	if rate, _ := m.FeeRate(); rate != "0.00000020" {
+		t.Errorf("FeeRate() with configured rejection message = %s, want 0.00000020", rate)
+	}
+
+	//配置费率为0时，从最低手续费折算的费率提高，最高为其10倍
+	c.FeeRate = "0"
+	c.MaxFeeRate = ""
+	c.MinFees = "0.0001"
+	m, err = NewFeeModel(c)
+	if err != nil {
+		t.Fatalf("NewFeeModel() with zero fee rate error = %v", err)
+	}
+	feedback = m.(FeeFeedback)
+	before, _ = m.EstimateFees(1, 1, "")
+	feedback.ValidateFeedback(errors.New("commission too low"))
+	if raised, _ := m.EstimateFees(1, 1, ""); !raised.GreaterThan(before) {
+		t.Errorf("fees not raised from min fees: %s -> %s", before, raised)
+	}
+	for i := 0; i < 100; i++ {
+		feedback.ValidateFeedback(errors.New("commission too low"))
+	}
+	//费率折算有进位误差
+	if got, _ := m.EstimateFees(1, 1, ""); !got.Round(6).Equal(before.Mul(decimal.New(10, 0))) {
+		t.Errorf("fees after repeated rejections = %s, want 10 times of %s", got, before)
+	}
+
+	c.MinFees = "0"
+	if _, err := NewFeeModel(c); err == nil {
+		t.Errorf("NewFeeModel() without any fee rate want error")
+	}
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/assetsadapterstore/velas-adapter/rpc"
+)
+
+const (
+	//InclusionProofExtParam ExtractTransactionData返回的交易记录中，包含证明的扩展参数
+	InclusionProofExtParam = "inclusionProof"
+)
+
+//TxInclusionProof 交易在区块中的默克尔证明，哈希均为hex编码
+//验证方需从自己信任的节点或区块浏览器核对区块哈希对应的默克尔根及交易数量
+//默克尔规则与区块校验相同，需TestTxInclusionProof_Mainnet用主网区块验证
+type TxInclusionProof struct {
+	TxID        string   `json:"txid"`
+	BlockHash   string   `json:"blockHash"`
+	BlockHeight uint64   `json:"blockHeight"`
+	MerkleRoot  string   `json:"merkleRoot"`
+	TxCount     uint32   `json:"txCount"`
+	Index       uint32   `json:"index"`
+	Branch      []string `json:"branch"` //从交易到默克尔根路径上的兄弟节点
+}
+
+//NewTxInclusionProof 根据区块生成交易的默克尔证明，区块须通过VerifyBlock的交易哈希及默克尔根校验
+func NewTxInclusionProof(block *rpc.BlockResponse, txid string) (*TxInclusionProof, error) {
+
+	if block == nil || block.Header == nil {
+		return nil, fmt.Errorf("block header is empty")
+	}
+
+	header := block.Header
+	if err := VerifyBlock(block, uint64(header.Height), ""); err != nil {
+		return nil, err
+	}
+
+	index := -1
+	hashes := make([][32]byte, 0, len(block.Transactions))
+	for i, trx := range block.Transactions {
+		if hex.EncodeToString(trx.Hash[:]) == txid {
+			index = i
+		}
+		hashes = append(hashes, trx.Hash)
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("transaction %s is not in block %s", txid, header.Hash)
+	}
+
+	branch, err := crypto.MerkleBranch(hashes, index)
+	if err != nil {
+		return nil, err
+	}
+
+	proof := &TxInclusionProof{
+		TxID:        txid,
+		BlockHash:   header.Hash,
+		BlockHeight: uint64(header.Height),
+		MerkleRoot:  header.MerkleRoot,
+		TxCount:     header.TxnCount,
+		Index:       uint32(index),
+		Branch:      make([]string, 0, len(branch)),
+	}
+	for _, h := range branch {
+		proof.Branch = append(proof.Branch, hex.EncodeToString(h[:]))
+	}
+	return proof, nil
+}
+
+//VerifyTxInclusionProof 不访问节点，验证证明的默克尔路径能得到MerkleRoot；trx不为空时，同时验证交易内容的哈希为TxID
+func VerifyTxInclusionProof(proof *TxInclusionProof, trx *crypto.Tx) error {
+
+	if proof == nil {
+		return fmt.Errorf("proof is empty")
+	}
+
+	leaf, err := decodeHash(proof.TxID)
+	if err != nil {
+		return fmt.Errorf("invalid txid: %v", err)
+	}
+
+	if trx != nil && trx.GenerateHash() != leaf {
+		return fmt.Errorf("transaction content does not hash to %s", proof.TxID)
+	}
+
+	branch := make([][32]byte, 0, len(proof.Branch))
+	for _, h := range proof.Branch {
+		hash, err := decodeHash(h)
+		if err != nil {
+			return fmt.Errorf("invalid branch hash: %v", err)
+		}
+		branch = append(branch, hash)
+	}
+
+	root, err := crypto.MerkleRootFromBranch(leaf, int(proof.Index), int(proof.TxCount), branch)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(root[:]) != proof.MerkleRoot {
+		return fmt.Errorf("branch hashes to merkle root %x, proof has %s", root, proof.MerkleRoot)
+	}
+	return nil
+}
+
+//GetTxInclusionProof 查询交易所在区块并生成默克尔证明
+func (wm *WalletManager) GetTxInclusionProof(txid string) (*TxInclusionProof, error) {
+
+	tx, err := wm.GetTransaction(txid)
+	if err != nil {
+		return nil, err
+	}
+	if len(tx.Block) == 0 {
+		return nil, fmt.Errorf("transaction %s is not confirmed", txid)
+	}
+
+	block, err := wm.GetBlockByHash(tx.Block)
+	if err != nil {
+		return nil, err
+	}
+	return NewTxInclusionProof(block, txid)
+}
+
+//decodeHash 解析hex编码的32字节哈希
+func decodeHash(s string) ([32]byte, error) {
+	var hash [32]byte
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return hash, err
+	}
+	if len(b) != len(hash) {
+		return hash, fmt.Errorf("hash length is %d", len(b))
+	}
+	copy(hash[:], b)
+	return hash, nil
+}
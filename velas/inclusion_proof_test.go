@@ -0,0 +1,158 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/rpc"
+	"github.com/blocktree/openwallet/openwallet"
+)
+
+func TestTxInclusionProof(t *testing.T) {
+
+	block := testVerifiedBlock(10, "prev", 5)
+
+	for i, trx := range block.Transactions {
+		txid := hex.EncodeToString(trx.Hash[:])
+		proof, err := NewTxInclusionProof(block, txid)
+		if err != nil {
+			t.Fatalf("NewTxInclusionProof(%d) unexpected error = %v", i, err)
+		}
+		if proof.Index != uint32(i) || proof.MerkleRoot != block.Header.MerkleRoot || proof.TxCount != 5 {
+			t.Errorf("NewTxInclusionProof(%d) = %+v", i, proof)
+		}
+
+		//证明经JSON传递给交易对手后验证
+		data, _ := json.Marshal(proof)
+		var received TxInclusionProof
+		json.Unmarshal(data, &received)
+		if err := VerifyTxInclusionProof(&received, trx); err != nil {
+			t.Errorf("VerifyTxInclusionProof(%d) unexpected error = %v", i, err)
+		}
+	}
+
+	proof, _ := NewTxInclusionProof(block, hex.EncodeToString(block.Transactions[1].Hash[:]))
+
+	//交易内容与证明不符
+	if err := VerifyTxInclusionProof(proof, block.Transactions[2]); err == nil {
+		t.Errorf("VerifyTxInclusionProof() with another transaction want error")
+	}
+
+	tampered := *proof
+	tampered.Branch = append([]string{}, proof.Branch...)
+	tampered.Branch[0] = hex.EncodeToString(block.Transactions[3].Hash[:])
+	if err := VerifyTxInclusionProof(&tampered, nil); err == nil {
+		t.Errorf("VerifyTxInclusionProof() with tampered branch want error")
+	}
+
+	tampered = *proof
+	tampered.Index = 2
+	if err := VerifyTxInclusionProof(&tampered, nil); err == nil {
+		t.Errorf("VerifyTxInclusionProof() with another index want error")
+	}
+
+	if _, err := NewTxInclusionProof(block, "00"); err == nil {
+		t.Errorf("NewTxInclusionProof() of transaction not in block want error")
+	}
+
+	//区块内容与默克尔根不一致时不生成证明
+	block.Transactions[0].Outputs[0].Value = 100
+	block.Transactions[0].Hash = block.Transactions[0].GenerateHash()
+	if _, err := NewTxInclusionProof(block, hex.EncodeToString(block.Transactions[0].Hash[:])); !IsBlockVerifyError(err) {
+		t.Errorf("NewTxInclusionProof() of inconsistent block error = %v, want BlockVerifyError", err)
+	}
+}
+
+func TestTxInclusionProof_Mainnet(t *testing.T) {
+
+	for file, block := range testMainnetBlocks(t) {
+		for i, trx := range block.Transactions {
+			proof, err := NewTxInclusionProof(block, hex.EncodeToString(trx.Hash[:]))
+			if err != nil {
+				t.Fatalf("%s: NewTxInclusionProof(%d) unexpected error = %v", file, i, err)
+			}
+			if proof.MerkleRoot != block.Header.MerkleRoot || proof.TxCount != block.Header.TxnCount {
+				t.Errorf("%s: NewTxInclusionProof(%d) = %+v", file, i, proof)
+			}
+			//路径须得到节点区块头中的默克尔根
+			proof.MerkleRoot = block.Header.MerkleRoot
+			if err := VerifyTxInclusionProof(proof, trx); err != nil {
+				t.Errorf("%s: VerifyTxInclusionProof(%d) against header merkle root unexpected error = %v", file, i, err)
+			}
+			if len(proof.Branch) > 0 {
+				proof.Index ^= 1
+				if proof.Index < proof.TxCount {
+					if err := VerifyTxInclusionProof(proof, trx); err == nil {
+						t.Errorf("%s: VerifyTxInclusionProof(%d) at sibling position want error", file, i)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestVLXBlockScanner_ExtractTransactionDataProof(t *testing.T) {
+
+	block := testVerifiedBlock(10, "prev", 3)
+	trx := block.Transactions[1]
+	txid := hex.EncodeToString(trx.Hash[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/txs":
+			data, _ := trx.MarshalJSON()
+			tx := make(map[string]interface{})
+			json.Unmarshal(data, &tx)
+			tx["block"] = block.Header.Hash
+			json.NewEncoder(w).Encode([]interface{}{tx})
+		case "/api/v1/blocks/" + block.Header.Hash:
+			json.NewEncoder(w).Encode(block)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"not found"}`)
+		}
+	}))
+	defer server.Close()
+
+	wm := NewWalletManager()
+	wm.WalletClient = rpc.NewClient(server.URL)
+	scanner := NewVLXBlockScanner(wm)
+
+	extData, err := scanner.ExtractTransactionData(txid, func(target openwallet.ScanTarget) (string, bool) {
+		return "account", true
+	})
+	if err != nil {
+		t.Fatalf("ExtractTransactionData() unexpected error = %v", err)
+	}
+	if len(extData["account"]) == 0 || extData["account"][0].Transaction == nil {
+		t.Fatalf("ExtractTransactionData() = %v, want transaction of account", extData)
+	}
+
+	var proof TxInclusionProof
+	raw := extData["account"][0].Transaction.GetExtParam().Get(InclusionProofExtParam).Raw
+	if err := json.Unmarshal([]byte(raw), &proof); err != nil {
+		t.Fatalf("ext param %s = %s, unexpected error = %v", InclusionProofExtParam, raw, err)
+	}
+	if err := VerifyTxInclusionProof(&proof, trx); err != nil || proof.BlockHeight != 10 {
+		t.Errorf("VerifyTxInclusionProof() of ext param %+v, error = %v", proof, err)
+	}
+}
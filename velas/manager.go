@@ -16,7 +16,11 @@
 package velas
 
 import (
+	"sync"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
 	"github.com/assetsadapterstore/velas-adapter/rpc"
+	"github.com/assetsadapterstore/velas-adapter/txsigner"
 	"github.com/blocktree/openwallet/log"
 	"github.com/blocktree/openwallet/openwallet"
 )
@@ -32,8 +36,21 @@ type WalletManager struct {
 	Config       *WalletConfig                 //钱包管理配置
 	Blockscanner *VLXBlockScanner              //区块扫描器
 	Decoder      *AddressDecoder               //地址编码器
+	DecoderV2    *addrdec.AddressDecoderV2     //地址编码器，网络参数不可变
 	TxDecoder    openwallet.TransactionDecoder //交易单编码器
+	FeeModel     FeeModel                      //手续费模型
+	TxTracker    *TxTracker                    //交易单跟踪器
+	NodeMonitor  *NodeMonitor                  //多节点一致性检查器
+	Signer       txsigner.Signer               //签名器，为空时使用钱包HD密钥在进程内签名
 	Log          *log.OWLogger                 //日志工具
+
+	EVMClient          EVMBackend          //EVM节点客户端，使用EVM后端时有效
+	EVMDecoder         *EVMAddressDecoder  //EVM地址编码器
+	EVMBlockscanner    *EVMBlockScanner    //EVM区块扫描器
+	EVMContractDecoder *EVMContractDecoder //EVM代币合约解析器
+	EVMNonce           *EVMNonceManager    //EVM地址nonce管理
+
+	reserveMu sync.Mutex //utxo锁定记录的读写锁
 }
 
 func NewWalletManager() *WalletManager {
@@ -42,8 +59,13 @@ func NewWalletManager() *WalletManager {
 	wm.WalletClient = rpc.NewClient(wm.Config.ServerAPI)
 	//区块扫描器
 	wm.Blockscanner = NewVLXBlockScanner(&wm)
+	wm.DecoderV2 = addrdec.NewAddressDecoderV2(wm.Config.ChainParams)
 	wm.Decoder = NewAddressDecoder(&wm)
 	wm.TxDecoder = NewTransactionDecoder(&wm)
+	wm.FeeModel, _ = NewFeeModel(wm.Config)
+	wm.TxTracker = NewTxTracker(&wm)
+	wm.NodeMonitor = NewNodeMonitor(&wm)
+	wm.EVMNonce = NewEVMNonceManager(wm.Config.UTXOReserveTimeout)
 	wm.Log = log.NewOWLogger(wm.Symbol())
 	return &wm
 }
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/assetsadapterstore/velas-adapter/txsigner"
+	"github.com/blocktree/go-owcrypt"
+	"github.com/blocktree/openwallet/openwallet"
+)
+
+//SignedMessage 地址所有权证明，签名及公钥为hex编码
+type SignedMessage struct {
+	Address   string `json:"address"`
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+	PublicKey string `json:"publicKey"`
+}
+
+//SignMessage 使用钱包中地址的密钥签名消息，签名内容为txsigner.MessageHash，不会与交易签名混淆
+func (wm *WalletManager) SignMessage(wrapper openwallet.WalletDAI, address, message string) (*SignedMessage, error) {
+
+	if wm.IsEVM() {
+		return nil, fmt.Errorf("message signing is not supported by evm backend")
+	}
+
+	addr, err := wrapper.GetAddress(address)
+	if err != nil {
+		return nil, openwallet.Errorf(openwallet.ErrAddressNotFound, "address %s not found in wallet: %v", address, err)
+	}
+
+	signer, err := wm.walletSigner(wrapper)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signer.Sign(&txsigner.SignRequest{
+		KeyID:   signerKeyID(wrapper),
+		HDPath:  addr.HDPath,
+		EccType: owcrypt.ECC_CURVE_ED25519,
+		Message: txsigner.MessageHash([]byte(message)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("message sign failed, unexpected error: %v", err)
+	}
+
+	signed := &SignedMessage{
+		Address:   address,
+		Message:   message,
+		Signature: hex.EncodeToString(signature),
+		PublicKey: addr.PublicKey,
+	}
+
+	//签名器的密钥须与地址一致，否则证明无效
+	if err := wm.VerifyMessage(address, message, signed.Signature, signed.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return signed, nil
+}
+
+//VerifyMessage 验证消息签名，并验证公钥哈希与地址一致
+func (wm *WalletManager) VerifyMessage(address, message, signature, pubkey string) error {
+	return verifyMessage(wm.DecoderV2, address, message, signature, pubkey)
+}
+
+//verifyMessage 使用指定网络的地址解析器验证消息签名
+func verifyMessage(dec *addrdec.AddressDecoderV2, address, message, signature, pubkey string) error {
+
+	info, err := dec.ValidateAddress(address)
+	if err != nil {
+		return openwallet.Errorf(openwallet.ErrAdressDecodeFailed, "address %v", err)
+	}
+
+	pub, err := hex.DecodeString(pubkey)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %v", err)
+	}
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+
+	if !bytes.Equal(txsigner.PublicKeyHash(pub), info.Hash) {
+		return fmt.Errorf("public key does not belong to address %s", address)
+	}
+
+	return txsigner.Default.VerifyMessage([]byte(message), sig, pub)
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/assetsadapterstore/velas-adapter/keystore"
+	"github.com/assetsadapterstore/velas-adapter/txsigner"
+	"github.com/blocktree/openwallet/openwallet"
+)
+
+func TestWalletManager_SignMessage(t *testing.T) {
+
+	key, _ := keystore.NewKey(addrdec.Default)
+	other, _ := keystore.NewKey(addrdec.Default)
+
+	wm := NewWalletManager()
+	wm.Signer = txsigner.NewStaticSigner(map[string][]byte{"": key.PrivateKey})
+
+	wrapper := &testWalletDAI{addresses: map[string]*openwallet.Address{
+		key.Address:   {AccountID: "account", Address: key.Address, PublicKey: hex.EncodeToString(key.PublicKey)},
+		other.Address: {AccountID: "account", Address: other.Address, PublicKey: hex.EncodeToString(other.PublicKey)},
+	}}
+
+	message := "proof of ownership 2026-10-19"
+	signed, err := wm.SignMessage(wrapper, key.Address, message)
+	if err != nil {
+		t.Fatalf("SignMessage() unexpected error = %v", err)
+	}
+
+	if err := wm.VerifyMessage(key.Address, message, signed.Signature, signed.PublicKey); err != nil {
+		t.Errorf("VerifyMessage() unexpected error = %v", err)
+	}
+	if err := wm.VerifyMessage(key.Address, message+".", signed.Signature, signed.PublicKey); err == nil {
+		t.Errorf("VerifyMessage() of tampered message want error")
+	}
+	//签名有效，但公钥不属于该地址
+	if err := wm.VerifyMessage(other.Address, message, signed.Signature, signed.PublicKey); err == nil {
+		t.Errorf("VerifyMessage() with public key of another address want error")
+	}
+
+	//签名器的密钥与地址不一致
+	if _, err := wm.SignMessage(wrapper, other.Address, message); err == nil {
+		t.Errorf("SignMessage() with mismatched key want error")
+	}
+	if _, err := wm.SignMessage(wrapper, "VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty", message); err == nil {
+		t.Errorf("SignMessage() of address not in wallet want error")
+	}
+}
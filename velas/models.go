@@ -16,8 +16,11 @@
 package velas
 
 import (
+	"encoding/hex"
 	"fmt"
+	"time"
 
+	vlxcrypto "github.com/assetsadapterstore/velas-adapter/crypto"
 	"github.com/blocktree/openwallet/crypto"
 	"github.com/blocktree/openwallet/openwallet"
 	"github.com/ethereum/go-ethereum/common"
@@ -67,3 +70,39 @@ func NewUnscanRecord(height uint64, txID, reason string) *UnscanRecord {
 	obj.ID = common.Bytes2Hex(crypto.SHA256([]byte(fmt.Sprintf("%d_%s", height, txID))))
 	return &obj
 }
+
+const (
+	UTXOStatusReserved = "reserved" //已被构建的交易单锁定
+	UTXOStatusSpent    = "spent"    //区块扫描已确认花费
+)
+
+//UTXOReservation 构建交易单时锁定的utxo，防止并发构建的交易单使用相同的utxo
+type UTXOReservation struct {
+	ID        string `storm:"id"` // 格式：{hash}:{index}
+	AccountID string
+	Address   string
+	Status    string
+	CreateAt  int64
+	ExpireAt  int64
+}
+
+func NewUTXOReservation(utxo *vlxcrypto.TransactionInputOutpoint, accountID string, timeout time.Duration) *UTXOReservation {
+	now := time.Now()
+	obj := UTXOReservation{}
+	obj.ID = outpointID(utxo.Hash, utxo.Index)
+	obj.AccountID = accountID
+	obj.Address = utxo.Address
+	obj.Status = UTXOStatusReserved
+	obj.CreateAt = now.Unix()
+	obj.ExpireAt = now.Add(timeout).Unix()
+	return &obj
+}
+
+//IsExpired 锁定是否已超时
+func (r *UTXOReservation) IsExpired(now int64) bool {
+	return r.ExpireAt <= now
+}
+
+func outpointID(hash [32]byte, index uint32) string {
+	return fmt.Sprintf("%s:%d", hex.EncodeToString(hash[:]), index)
+}
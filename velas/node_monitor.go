@@ -0,0 +1,326 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/assetsadapterstore/velas-adapter/rpc"
+	"github.com/blocktree/openwallet/timer"
+)
+
+const (
+	NodeStatusHealthy     = "healthy"     //与严格多数节点一致
+	NodeStatusLagging     = "lagging"     //高度落后超过MaxLag
+	NodeStatusStuck       = "stuck"       //高度超过StuckTimeout未增长
+	NodeStatusForked      = "forked"      //区块哈希一致的节点（含自身）不超过半数
+	NodeStatusUnreachable = "unreachable" //查询失败
+
+	periodOfMonitorTask = 60 * time.Second //节点检查任务执行间隔
+)
+
+//NodeHealth 单个节点的检查结果
+type NodeHealth struct {
+	Endpoint     string `json:"endpoint"`
+	Status       string `json:"status"`
+	Height       uint64 `json:"height"`
+	Lag          uint64 `json:"lag"`          //与最高节点的高度差
+	BlockHash    string `json:"blockHash"`    //节点最新高度的区块哈希
+	Agree        int    `json:"agree"`        //共同高度区块哈希一致的节点数
+	Disagree     int    `json:"disagree"`     //共同高度区块哈希不同的节点数
+	IsSync       bool   `json:"isSync"`       //节点报告的同步状态
+	LastProgress int64  `json:"lastProgress"` //高度最后一次增长的时间
+	Reason       string `json:"reason"`
+}
+
+//NodeHealthReport 一次检查的结果，每两个节点在两者中较低的高度比对区块哈希
+type NodeHealthReport struct {
+	Time       int64         `json:"time"`
+	MaxHeight  uint64        `json:"maxHeight"`
+	Consistent bool          `json:"consistent"` //全部节点健康
+	Nodes      []*NodeHealth `json:"nodes"`
+}
+
+//NodeHealthObserver 节点检查结果的观测者
+type NodeHealthObserver interface {
+	//NodeHealthNotify 每次检查后通知
+	NodeHealthNotify(report *NodeHealthReport) error
+}
+
+//nodeProgress 节点高度最后一次增长的记录
+type nodeProgress struct {
+	height uint64
+	since  time.Time
+}
+
+//NodeMonitor 定期比对多个节点的高度及共同高度的区块哈希，发现分叉、停滞或落后的节点
+//配置了monitorNodes时，LoadAssetsConfig会启动检查任务，否则需调用Run启动
+type NodeMonitor struct {
+	Mu           sync.RWMutex
+	Observers    map[NodeHealthObserver]bool //观察者
+	PeriodOfTask time.Duration
+	StuckTimeout time.Duration //高度超过该时长未增长，标记为停滞
+	MaxLag       uint64        //高度落后超过该值，标记为落后
+	endpoints    []string
+	clients      map[string]*rpc.Client
+	progress     map[string]*nodeProgress
+	lastReport   *NodeHealthReport
+	monitorTask  *timer.TaskTimer
+	now          func() time.Time
+	wm           *WalletManager
+}
+
+//NewNodeMonitor 创建节点检查器
+func NewNodeMonitor(wm *WalletManager) *NodeMonitor {
+	monitor := NodeMonitor{}
+	monitor.wm = wm
+	monitor.Observers = make(map[NodeHealthObserver]bool)
+	monitor.PeriodOfTask = periodOfMonitorTask
+	monitor.StuckTimeout = wm.Config.NodeStuckTimeout
+	monitor.MaxLag = wm.Config.NodeMaxLag
+	monitor.clients = make(map[string]*rpc.Client)
+	monitor.progress = make(map[string]*nodeProgress)
+	monitor.now = time.Now
+	return &monitor
+}
+
+//SetEndpoints 设置比对的节点地址，重复的地址只保留一个
+func (monitor *NodeMonitor) SetEndpoints(endpoints ...string) {
+	monitor.Mu.Lock()
+	defer monitor.Mu.Unlock()
+
+	monitor.endpoints = make([]string, 0, len(endpoints))
+	clients := make(map[string]*rpc.Client)
+	for _, endpoint := range endpoints {
+		endpoint = strings.TrimSuffix(strings.TrimSpace(endpoint), "/")
+		if len(endpoint) == 0 || clients[endpoint] != nil {
+			continue
+		}
+		monitor.endpoints = append(monitor.endpoints, endpoint)
+		clients[endpoint] = rpc.NewClient(endpoint)
+	}
+	monitor.clients = clients
+	monitor.progress = make(map[string]*nodeProgress)
+}
+
+//Endpoints 比对的节点地址
+func (monitor *NodeMonitor) Endpoints() []string {
+	monitor.Mu.RLock()
+	defer monitor.Mu.RUnlock()
+	return append([]string{}, monitor.endpoints...)
+}
+
+//AddObserver 添加观测者
+func (monitor *NodeMonitor) AddObserver(obj NodeHealthObserver) {
+	monitor.Mu.Lock()
+	defer monitor.Mu.Unlock()
+	if obj == nil {
+		return
+	}
+	monitor.Observers[obj] = true
+}
+
+//RemoveObserver 移除观测者
+func (monitor *NodeMonitor) RemoveObserver(obj NodeHealthObserver) {
+	monitor.Mu.Lock()
+	defer monitor.Mu.Unlock()
+	delete(monitor.Observers, obj)
+}
+
+//Run 运行检查任务，少于两个节点时无法比对
+func (monitor *NodeMonitor) Run() error {
+	if len(monitor.Endpoints()) < 2 {
+		return fmt.Errorf("node monitor needs at least 2 endpoints")
+	}
+	if monitor.monitorTask != nil && monitor.monitorTask.Running() {
+		return nil
+	}
+	monitor.monitorTask = timer.NewTask(monitor.PeriodOfTask, monitor.MonitorTask)
+	monitor.monitorTask.Start()
+	return nil
+}
+
+//Stop 停止检查任务
+func (monitor *NodeMonitor) Stop() {
+	if monitor.monitorTask != nil {
+		monitor.monitorTask.Stop()
+	}
+}
+
+//LastReport 最近一次检查的结果，未检查时返回nil
+func (monitor *NodeMonitor) LastReport() *NodeHealthReport {
+	monitor.Mu.RLock()
+	defer monitor.Mu.RUnlock()
+	return monitor.lastReport
+}
+
+//MonitorTask 检查任务，记录告警日志并通知观测者
+func (monitor *NodeMonitor) MonitorTask() {
+
+	report := monitor.Check()
+
+	for _, node := range report.Nodes {
+		if node.Status != NodeStatusHealthy {
+			monitor.wm.Log.Std.Error("node %s is %s at height %d: %s", node.Endpoint, node.Status, node.Height, node.Reason)
+		}
+	}
+
+	monitor.Mu.RLock()
+	observers := make([]NodeHealthObserver, 0, len(monitor.Observers))
+	for o := range monitor.Observers {
+		observers = append(observers, o)
+	}
+	monitor.Mu.RUnlock()
+
+	for _, o := range observers {
+		if err := o.NodeHealthNotify(report); err != nil {
+			monitor.wm.Log.Std.Error("node health notify failed; unexpected error: %v", err)
+		}
+	}
+}
+
+//Check 查询全部节点的高度，每两个节点在两者中较低的高度比对区块哈希，
+//区块哈希一致的节点（含自身）超过半数时为健康，否则标记为分叉，少数节点分叉时其余节点保持健康
+//停滞在分叉点之前的节点与两条链都一致，不影响其他节点之间的比对
+func (monitor *NodeMonitor) Check() *NodeHealthReport {
+
+	endpoints := monitor.Endpoints()
+	monitor.Mu.RLock()
+	clients := monitor.clients
+	monitor.Mu.RUnlock()
+
+	now := monitor.now()
+	report := &NodeHealthReport{Time: now.Unix(), Nodes: make([]*NodeHealth, 0, len(endpoints))}
+
+	//查询高度
+	reachable := make([]*NodeHealth, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		node := &NodeHealth{Endpoint: endpoint, Status: NodeStatusHealthy}
+		report.Nodes = append(report.Nodes, node)
+
+		info, err := clients[endpoint].NodeInfo()
+		if err != nil || info == nil || info.Blockchain == nil {
+			node.Status = NodeStatusUnreachable
+			node.Reason = fmt.Sprintf("get node info failed: %v", err)
+			continue
+		}
+		node.Height = uint64(info.Blockchain.Height)
+		node.IsSync = info.IsSync
+		node.LastProgress = monitor.updateProgress(endpoint, node.Height, now).Unix()
+		reachable = append(reachable, node)
+
+		if report.MaxHeight < node.Height {
+			report.MaxHeight = node.Height
+		}
+	}
+
+	//查询各节点在需要比对的高度的区块哈希：自身高度及其他较低节点的高度
+	hashes := make(map[*NodeHealth]map[uint64]string)
+	for _, node := range reachable {
+		hashes[node] = make(map[uint64]string)
+		for _, other := range reachable {
+			height := other.Height
+			if height > node.Height {
+				continue
+			}
+			if _, ok := hashes[node][height]; ok {
+				continue
+			}
+			block, err := clients[node.Endpoint].Block.GetByHeight(uint32(height))
+			if err != nil || block == nil || block.Header == nil {
+				node.Status = NodeStatusUnreachable
+				node.Reason = fmt.Sprintf("get block %d failed: %v", height, err)
+				break
+			}
+			hashes[node][height] = block.Header.Hash
+		}
+		node.BlockHash = hashes[node][node.Height]
+	}
+
+	//两两比对，记录不一致的节点
+	disagreed := make(map[*NodeHealth][]string)
+	for i, node := range reachable {
+		if node.Status != NodeStatusHealthy {
+			continue
+		}
+		for _, other := range reachable[i+1:] {
+			if other.Status != NodeStatusHealthy {
+				continue
+			}
+			height := node.Height
+			if other.Height < height {
+				height = other.Height
+			}
+			if hashes[node][height] == hashes[other][height] {
+				node.Agree++
+				other.Agree++
+				continue
+			}
+			node.Disagree++
+			other.Disagree++
+			disagreed[node] = append(disagreed[node], fmt.Sprintf("%s at %d", other.Endpoint, height))
+			disagreed[other] = append(disagreed[other], fmt.Sprintf("%s at %d", node.Endpoint, height))
+		}
+	}
+
+	for _, node := range reachable {
+		if node.Status != NodeStatusHealthy {
+			continue
+		}
+		node.Lag = report.MaxHeight - node.Height
+		switch {
+		//一致的节点（含自身）不是严格多数，无法确认在多数节点的链上
+		case node.Disagree > 0 && (node.Agree+1)*2 <= node.Agree+node.Disagree+1:
+			node.Status = NodeStatusForked
+			node.Reason = fmt.Sprintf("block hash differs from %d of %d nodes: %s", node.Disagree, node.Agree+node.Disagree, strings.Join(disagreed[node], ", "))
+		case monitor.StuckTimeout > 0 && now.Unix()-node.LastProgress >= int64(monitor.StuckTimeout/time.Second):
+			node.Status = NodeStatusStuck
+			node.Reason = fmt.Sprintf("height has not grown since %s", time.Unix(node.LastProgress, 0).Format(time.RFC3339))
+		case monitor.MaxLag > 0 && node.Lag > monitor.MaxLag:
+			node.Status = NodeStatusLagging
+			node.Reason = fmt.Sprintf("%d blocks behind height %d", node.Lag, report.MaxHeight)
+		}
+	}
+
+	report.Consistent = len(report.Nodes) > 0
+	for _, node := range report.Nodes {
+		if node.Status != NodeStatusHealthy {
+			report.Consistent = false
+		}
+	}
+
+	monitor.Mu.Lock()
+	monitor.lastReport = report
+	monitor.Mu.Unlock()
+
+	return report
+}
+
+//updateProgress 记录节点高度，返回高度最后一次增长的时间
+func (monitor *NodeMonitor) updateProgress(endpoint string, height uint64, now time.Time) time.Time {
+	monitor.Mu.Lock()
+	defer monitor.Mu.Unlock()
+
+	p := monitor.progress[endpoint]
+	if p == nil || height > p.height {
+		p = &nodeProgress{height: height, since: now}
+		monitor.progress[endpoint] = p
+	}
+	return p.since
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astaxie/beego/config"
+)
+
+//testMonitorNode 模拟节点，高度超过forkAt的区块哈希以prefix开头，之前的区块与主链相同
+type testMonitorNode struct {
+	height int
+	prefix string
+	forkAt int
+}
+
+func (node *testMonitorNode) hash(height int) string {
+	if height > node.forkAt {
+		return fmt.Sprintf("%s%d", node.prefix, height)
+	}
+	return fmt.Sprintf("main%d", height)
+}
+
+func (node *testMonitorNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case path == "/api/v1/info":
+		fmt.Fprintf(w, `{"blockchain":{"height":%d},"is_sync":true}`, node.height)
+	case strings.HasPrefix(path, "/api/v1/headers/height/"):
+		height, _ := strconv.Atoi(strings.TrimPrefix(path, "/api/v1/headers/height/"))
+		fmt.Fprintf(w, `{"hash":"%s"}`, node.hash(height))
+	case strings.HasPrefix(path, "/api/v1/blocks/"):
+		hash := strings.TrimPrefix(path, "/api/v1/blocks/")
+		height, _ := strconv.Atoi(strings.TrimLeft(hash, "abcdefghijklmnopqrstuvwxyz"))
+		fmt.Fprintf(w, `{"header":{"hash":"%s","height":%d}}`, hash, height)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"not found"}`)
+	}
+}
+
+type testHealthObserver struct {
+	reports []*NodeHealthReport
+}
+
+func (o *testHealthObserver) NodeHealthNotify(report *NodeHealthReport) error {
+	o.reports = append(o.reports, report)
+	return nil
+}
+
+func TestNodeMonitor_Check(t *testing.T) {
+
+	nodes := []*testMonitorNode{
+		{height: 100, prefix: "main"},
+		{height: 100, prefix: "main"},
+		{height: 99, prefix: "main"},
+		{height: 100, prefix: "fork"},
+	}
+	endpoints := make([]string, 0)
+	for _, node := range nodes {
+		server := httptest.NewServer(node)
+		defer server.Close()
+		endpoints = append(endpoints, server.URL)
+	}
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	endpoints = append(endpoints, down.URL, endpoints[0]+"/")
+
+	now := time.Unix(1700000000, 0)
+	wm := NewWalletManager()
+	monitor := NewNodeMonitor(wm)
+	monitor.now = func() time.Time { return now }
+	monitor.SetEndpoints(endpoints...)
+	if len(monitor.Endpoints()) != 5 {
+		t.Fatalf("Endpoints() = %v, want duplicated endpoint removed", monitor.Endpoints())
+	}
+
+	observer := &testHealthObserver{}
+	monitor.AddObserver(observer)
+
+	status := func(report *NodeHealthReport) []string {
+		list := make([]string, 0)
+		for _, node := range report.Nodes {
+			list = append(list, node.Status)
+		}
+		return list
+	}
+
+	monitor.MonitorTask()
+	if len(observer.reports) != 1 || monitor.LastReport() != observer.reports[0] {
+		t.Fatalf("MonitorTask() notified %d reports", len(observer.reports))
+	}
+	report := observer.reports[0]
+	want := []string{NodeStatusHealthy, NodeStatusHealthy, NodeStatusHealthy, NodeStatusForked, NodeStatusUnreachable}
+	if strings.Join(status(report), ",") != strings.Join(want, ",") || report.MaxHeight != 100 || report.Consistent {
+		t.Errorf("Check() = %v at %d, want %v at 100", status(report), report.MaxHeight, want)
+	}
+	if report.Nodes[0].BlockHash != "main100" || report.Nodes[2].Lag != 1 || report.Nodes[3].Disagree != 3 {
+		t.Errorf("Check() node = %+v, %+v, %+v", report.Nodes[0], report.Nodes[2], report.Nodes[3])
+	}
+
+	//只有第一个节点继续出块
+	nodes[0].height = 120
+	now = now.Add(monitor.StuckTimeout)
+	monitor.SetEndpoints(endpoints[:3]...)
+	monitor.Check()
+	nodes[0].height = 121
+	now = now.Add(monitor.StuckTimeout)
+	report = monitor.Check()
+	want = []string{NodeStatusHealthy, NodeStatusStuck, NodeStatusStuck}
+	if strings.Join(status(report), ",") != strings.Join(want, ",") {
+		t.Errorf("Check() = %v, want %v", status(report), want)
+	}
+
+	//停滞超时未到，但落后太多
+	monitor.StuckTimeout = 0
+	report = monitor.Check()
+	want = []string{NodeStatusHealthy, NodeStatusLagging, NodeStatusLagging}
+	if strings.Join(status(report), ",") != strings.Join(want, ",") || report.Nodes[1].Lag != 21 {
+		t.Errorf("Check() = %v, want %v", status(report), want)
+	}
+
+	//三个节点中一个分叉，另两个保持健康
+	nodes[0].height = 100
+	monitor.SetEndpoints(endpoints[0], endpoints[1], endpoints[3])
+	report = monitor.Check()
+	want = []string{NodeStatusHealthy, NodeStatusHealthy, NodeStatusForked}
+	if strings.Join(status(report), ",") != strings.Join(want, ",") {
+		t.Errorf("Check() = %v, want %v", status(report), want)
+	}
+	if report.Nodes[0].Agree != 1 || report.Nodes[0].Disagree != 1 || report.Nodes[2].Disagree != 2 {
+		t.Errorf("Check() node = %+v, %+v", report.Nodes[0], report.Nodes[2])
+	}
+
+	//两个节点无法判断哪个在分叉上
+	monitor.SetEndpoints(endpoints[1], endpoints[3])
+	report = monitor.Check()
+	want = []string{NodeStatusForked, NodeStatusForked}
+	if strings.Join(status(report), ",") != strings.Join(want, ",") {
+		t.Errorf("Check() = %v, want %v", status(report), want)
+	}
+
+	monitor.SetEndpoints(endpoints[0])
+	if err := monitor.Run(); err == nil {
+		t.Errorf("Run() with one endpoint want error")
+	}
+
+	//配置了其他节点时由LoadAssetsConfig启动
+	c, _ := config.NewConfigData("ini", []byte("serverAPI = "+endpoints[0]+"\nmonitorNodes = "+endpoints[1]))
+	if err := wm.LoadAssetsConfig(c); err != nil {
+		t.Fatalf("LoadAssetsConfig() unexpected error = %v", err)
+	}
+	defer wm.NodeMonitor.Stop()
+	if task := wm.NodeMonitor.monitorTask; task == nil || !task.Running() {
+		t.Errorf("LoadAssetsConfig() with monitorNodes did not start node monitor")
+	}
+}
+
+func TestNodeMonitor_CheckForkAboveStuckNode(t *testing.T) {
+
+	//第三个节点停滞在分叉点之前，与两条链都一致
+	nodes := []*testMonitorNode{
+		{height: 100, prefix: "main"},
+		{height: 100, prefix: "main"},
+		{height: 50, prefix: "main"},
+		{height: 101, prefix: "fork", forkAt: 80},
+	}
+	endpoints := make([]string, 0)
+	for _, node := range nodes {
+		server := httptest.NewServer(node)
+		defer server.Close()
+		endpoints = append(endpoints, server.URL)
+	}
+
+	monitor := NewNodeMonitor(NewWalletManager())
+	monitor.StuckTimeout = 0
+	monitor.MaxLag = 0
+	monitor.SetEndpoints(endpoints...)
+
+	report := monitor.Check()
+	got := make([]string, 0)
+	for _, node := range report.Nodes {
+		got = append(got, node.Status)
+	}
+	want := []string{NodeStatusHealthy, NodeStatusHealthy, NodeStatusHealthy, NodeStatusForked}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Check() = %v, want %v", got, want)
+	}
+	if report.Nodes[3].Agree != 1 || report.Nodes[3].Disagree != 2 || !strings.Contains(report.Nodes[3].Reason, "at 100") {
+		t.Errorf("Check() forked node = %+v", report.Nodes[3])
+	}
+}
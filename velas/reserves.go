@@ -0,0 +1,289 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/blocktree/openwallet/common"
+	"github.com/blocktree/openwallet/openwallet"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+//ReserveReport 储备证明报告，可脱离钱包由VerifyReserveReport验证
+type ReserveReport struct {
+	Symbol      string            `json:"symbol"`
+	Network     string            `json:"network"`
+	BlockHeight uint64            `json:"blockHeight"`
+	BlockHash   string            `json:"blockHash"`
+	Challenge   string            `json:"challenge"` //审计方提供的随机挑战
+	Message     string            `json:"message"`   //每个地址签名的消息，见ReserveMessage
+	Total       string            `json:"total"`
+	CreatedAt   int64             `json:"createdAt"`
+	Addresses   []*ReserveAddress `json:"addresses"`
+}
+
+//ReserveAddress 地址的余额、未花及所有权签名
+type ReserveAddress struct {
+	Address   string             `json:"address"`
+	PublicKey string             `json:"publicKey"`
+	Signature string             `json:"signature"`
+	Balance   string             `json:"balance"`
+	Outpoints []*ReserveOutpoint `json:"outpoints"`
+}
+
+//ReserveOutpoint 地址的未花，Value为最小单位
+type ReserveOutpoint struct {
+	TxID        string `json:"txid"`
+	Index       uint32 `json:"index"`
+	Value       uint64 `json:"value"`
+	BlockHash   string `json:"blockHash"`
+	BlockHeight uint64 `json:"blockHeight"`
+}
+
+//ReserveMessage 储备证明的签名消息，绑定网络、区块及挑战，签名不能用于其他报告
+func ReserveMessage(network string, height uint64, blockHash, challenge string) string {
+	return fmt.Sprintf("Velas proof of reserves\nnetwork: %s\nheight: %d\nblock: %s\nchallenge: %s", network, height, blockHash, challenge)
+}
+
+//CreateReserveReport 查询地址在最新高度的未花，并用每个地址的密钥签名挑战
+//节点只能查询当前的未花，报告固定在开始时的最新高度，生成期间出块时无法确认未花在该高度的状态，返回错误，可重试
+func (wm *WalletManager) CreateReserveReport(wrapper openwallet.WalletDAI, addresses []string, challenge string) (*ReserveReport, error) {
+
+	if wm.IsEVM() {
+		return nil, fmt.Errorf("proof of reserves is not supported by evm backend")
+	}
+
+	if len(challenge) == 0 {
+		return nil, fmt.Errorf("challenge is empty")
+	}
+
+	height, err := wm.GetBlockHeight()
+	if err != nil {
+		return nil, err
+	}
+
+	blockHash, err := wm.reserveBlockHash(height)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReserveReport{
+		Symbol:      wm.Symbol(),
+		Network:     wm.Config.ChainParams.Name,
+		BlockHeight: height,
+		BlockHash:   blockHash,
+		Challenge:   challenge,
+		Message:     ReserveMessage(wm.Config.ChainParams.Name, height, blockHash, challenge),
+		CreatedAt:   time.Now().Unix(),
+		Addresses:   make([]*ReserveAddress, 0, len(addresses)),
+	}
+
+	//交易所在区块的高度，同一区块只查询一次
+	blockHeights := make(map[string]uint64)
+	seen := make(map[string]bool)
+	total := uint64(0)
+
+	for _, a := range addresses {
+		address := a
+		if _, err := wm.DecoderV2.ValidateAddress(address); err != nil {
+			return nil, openwallet.Errorf(openwallet.ErrAdressDecodeFailed, "address %v", err)
+		}
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+
+		unspents, err := wm.WalletClient.Wallet.GetUnspent(address)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := &ReserveAddress{Address: address, Outpoints: make([]*ReserveOutpoint, 0, len(unspents))}
+		balance := uint64(0)
+		for _, u := range unspents {
+			txid := hex.EncodeToString(u.Hash[:])
+			tx, err := wm.GetTransaction(txid)
+			if err != nil {
+				return nil, fmt.Errorf("get transaction %s failed, unexpected error: %v", txid, err)
+			}
+			if len(tx.Block) == 0 {
+				continue
+			}
+			txHeight, ok := blockHeights[tx.Block]
+			if !ok {
+				block, err := wm.GetBlockByHash(tx.Block)
+				if err != nil || block.Header == nil {
+					return nil, fmt.Errorf("get block %s failed, unexpected error: %v", tx.Block, err)
+				}
+				txHeight = uint64(block.Header.Height)
+				blockHeights[tx.Block] = txHeight
+			}
+			//生成期间确认的未花不计入
+			if txHeight > height {
+				continue
+			}
+			entry.Outpoints = append(entry.Outpoints, &ReserveOutpoint{
+				TxID:        txid,
+				Index:       u.Index,
+				Value:       u.Value,
+				BlockHash:   tx.Block,
+				BlockHeight: txHeight,
+			})
+			balance += u.Value
+		}
+		entry.Balance = common.IntToDecimals(int64(balance), wm.Decimal()).StringFixed(wm.Decimal())
+		total += balance
+
+		signed, err := wm.SignMessage(wrapper, address, report.Message)
+		if err != nil {
+			return nil, err
+		}
+		entry.PublicKey = signed.PublicKey
+		entry.Signature = signed.Signature
+
+		report.Addresses = append(report.Addresses, entry)
+	}
+
+	//查询期间出块时，已查询的地址可能在新区块中花费了未花；发生分叉时，报告的区块已不在主链上
+	tip, err := wm.GetBlockHeight()
+	if err != nil {
+		return nil, err
+	}
+	if tip != height {
+		return nil, fmt.Errorf("block height changed from %d to %d while creating report, retry", height, tip)
+	}
+	confirmHash, err := wm.reserveBlockHash(height)
+	if err != nil {
+		return nil, err
+	}
+	if confirmHash != blockHash {
+		return nil, fmt.Errorf("block %d changed from %s to %s while creating report", height, blockHash, confirmHash)
+	}
+
+	report.Total = common.IntToDecimals(int64(total), wm.Decimal()).StringFixed(wm.Decimal())
+	return report, nil
+}
+
+//CheckReserveReport 验证报告的签名及金额，并向节点核对区块哈希及每个未花所在的交易
+func (wm *WalletManager) CheckReserveReport(report *ReserveReport) error {
+
+	if err := VerifyReserveReport(report); err != nil {
+		return err
+	}
+
+	blockHash, err := wm.reserveBlockHash(report.BlockHeight)
+	if err != nil {
+		return err
+	}
+	if blockHash != report.BlockHash {
+		return fmt.Errorf("block %d is %s on chain, report has %s", report.BlockHeight, blockHash, report.BlockHash)
+	}
+
+	for _, entry := range report.Addresses {
+		for _, o := range entry.Outpoints {
+			tx, err := wm.GetTransaction(o.TxID)
+			if err != nil {
+				return fmt.Errorf("get transaction %s failed, unexpected error: %v", o.TxID, err)
+			}
+			if tx.Block != o.BlockHash {
+				return fmt.Errorf("transaction %s is in block %s on chain, report has %s", o.TxID, tx.Block, o.BlockHash)
+			}
+			if int(o.Index) >= len(tx.Outputs) {
+				return fmt.Errorf("transaction %s has no output %d", o.TxID, o.Index)
+			}
+			out := tx.Outputs[o.Index]
+			if out.Value != o.Value || base58.Encode(out.Script) != entry.Address {
+				return fmt.Errorf("output %s:%d does not pay %d to %s", o.TxID, o.Index, o.Value, entry.Address)
+			}
+		}
+	}
+
+	return nil
+}
+
+//VerifyReserveReport 不访问节点，验证报告的签名消息、每个地址的签名及公钥、未花高度及金额合计
+func VerifyReserveReport(report *ReserveReport) error {
+
+	if report == nil {
+		return fmt.Errorf("report is empty")
+	}
+
+	params, err := addrdec.ParamsByName(report.Network)
+	if err != nil {
+		return err
+	}
+	dec := addrdec.NewAddressDecoderV2(params)
+
+	message := ReserveMessage(report.Network, report.BlockHeight, report.BlockHash, report.Challenge)
+	if report.Message != message {
+		return fmt.Errorf("report message does not match the block and challenge")
+	}
+
+	seenAddress := make(map[string]bool)
+	seenOutpoint := make(map[string]bool)
+	total := uint64(0)
+
+	for _, entry := range report.Addresses {
+		if seenAddress[entry.Address] {
+			return fmt.Errorf("address %s is listed twice", entry.Address)
+		}
+		seenAddress[entry.Address] = true
+
+		if err := verifyMessage(dec, entry.Address, message, entry.Signature, entry.PublicKey); err != nil {
+			return fmt.Errorf("address %s: %v", entry.Address, err)
+		}
+
+		balance := uint64(0)
+		for _, o := range entry.Outpoints {
+			key := fmt.Sprintf("%s:%d", o.TxID, o.Index)
+			if seenOutpoint[key] {
+				return fmt.Errorf("outpoint %s is listed twice", key)
+			}
+			seenOutpoint[key] = true
+			if o.BlockHeight > report.BlockHeight {
+				return fmt.Errorf("outpoint %s is confirmed at %d, after the report height %d", key, o.BlockHeight, report.BlockHeight)
+			}
+			balance += o.Value
+		}
+
+		if entry.Balance != common.IntToDecimals(int64(balance), params.Decimals).StringFixed(params.Decimals) {
+			return fmt.Errorf("address %s balance %s does not equal the sum of outpoints", entry.Address, entry.Balance)
+		}
+		total += balance
+	}
+
+	if report.Total != common.IntToDecimals(int64(total), params.Decimals).StringFixed(params.Decimals) {
+		return fmt.Errorf("report total %s does not equal the sum of addresses", report.Total)
+	}
+
+	return nil
+}
+
+//reserveBlockHash 节点上高度height的区块哈希
+func (wm *WalletManager) reserveBlockHash(height uint64) (string, error) {
+	block, err := wm.GetBlock(height)
+	if err != nil {
+		return "", err
+	}
+	if block.Header == nil || len(block.Header.Hash) == 0 {
+		return "", fmt.Errorf("block %d not found", height)
+	}
+	return block.Header.Hash, nil
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/assetsadapterstore/velas-adapter/keystore"
+	"github.com/assetsadapterstore/velas-adapter/rpc"
+	"github.com/assetsadapterstore/velas-adapter/txsigner"
+	"github.com/blocktree/openwallet/openwallet"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+//testReserveNode 模拟节点，每个交易只有一个输出，区块哈希为"block<height>"，advance时每次查询高度后出块
+type testReserveNode struct {
+	tip      int
+	advance  bool
+	txs      map[string]*crypto.Tx
+	txBlock  map[string]int
+	unspents map[string][]*crypto.TransactionInputOutpoint
+}
+
+func (node *testReserveNode) addTx(address string, value uint64, height int) {
+	trx := &crypto.Tx{Version: 1, LockTime: uint32(len(node.txs))}
+	trx.Outputs = []crypto.TransactionOutput{{Value: value, Script: base58.Decode(address)}}
+	trx.Hash = trx.GenerateHash()
+	txid := hex.EncodeToString(trx.Hash[:])
+	node.txs[txid] = trx
+	node.txBlock[txid] = height
+	node.unspents[address] = append(node.unspents[address], &crypto.TransactionInputOutpoint{Hash: trx.Hash, Index: 0, Value: value})
+}
+
+func (node *testReserveNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case path == "/api/v1/info":
+		fmt.Fprintf(w, `{"blockchain":{"height":%d}}`, node.tip)
+		if node.advance {
+			node.tip++
+		}
+	case strings.HasPrefix(path, "/api/v1/headers/height/"):
+		fmt.Fprintf(w, `{"hash":"block%s"}`, strings.TrimPrefix(path, "/api/v1/headers/height/"))
+	case strings.HasPrefix(path, "/api/v1/blocks/block"):
+		fmt.Fprintf(w, `{"header":{"hash":"block%s","height":%[1]s}}`, strings.TrimPrefix(path, "/api/v1/blocks/block"))
+	case strings.HasPrefix(path, "/api/v1/wallet/unspent/"):
+		json.NewEncoder(w).Encode(node.unspents[strings.TrimPrefix(path, "/api/v1/wallet/unspent/")])
+	case path == "/api/v1/txs":
+		var arg struct {
+			Hashes []string `json:"hashes"`
+		}
+		json.NewDecoder(r.Body).Decode(&arg)
+		list := make([]map[string]interface{}, 0)
+		for _, hash := range arg.Hashes {
+			data, _ := node.txs[hash].MarshalJSON()
+			tx := make(map[string]interface{})
+			json.Unmarshal(data, &tx)
+			tx["block"] = fmt.Sprintf("block%d", node.txBlock[hash])
+			list = append(list, tx)
+		}
+		json.NewEncoder(w).Encode(list)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+	}
+}
+
+func TestWalletManager_CreateReserveReport(t *testing.T) {
+
+	key1, _ := keystore.NewKey(addrdec.Default)
+	key2, _ := keystore.NewKey(addrdec.Default)
+
+	node := &testReserveNode{
+		tip:      12,
+		txs:      make(map[string]*crypto.Tx),
+		txBlock:  make(map[string]int),
+		unspents: make(map[string][]*crypto.TransactionInputOutpoint),
+	}
+	node.addTx(key1.Address, 100000000, 5)
+	node.addTx(key1.Address, 50000000, 11)
+	node.addTx(key2.Address, 30000000, 8)
+	node.addTx(key2.Address, 20000000, 13) //生成期间确认
+
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	wm := NewWalletManager()
+	wm.WalletClient = rpc.NewClient(server.URL)
+	wm.Signer = txsigner.NewLocalSigner(func(keyID, hdPath string) ([]byte, error) {
+		if hdPath == "1" {
+			return key1.PrivateKey, nil
+		}
+		return key2.PrivateKey, nil
+	})
+
+	wrapper := &testWalletDAI{addresses: map[string]*openwallet.Address{
+		key1.Address: {Address: key1.Address, HDPath: "1", PublicKey: hex.EncodeToString(key1.PublicKey)},
+		key2.Address: {Address: key2.Address, HDPath: "2", PublicKey: hex.EncodeToString(key2.PublicKey)},
+	}}
+
+	report, err := wm.CreateReserveReport(wrapper, []string{key1.Address, key2.Address, key1.Address}, "audit 2026Q3")
+	if err != nil {
+		t.Fatalf("CreateReserveReport() unexpected error = %v", err)
+	}
+
+	//报告固定在最新高度，高度13的未花在报告高度之后，不计入
+	if report.BlockHeight != 12 || report.BlockHash != "block12" || len(report.Addresses) != 2 || report.Total != "1.80000000" {
+		t.Fatalf("CreateReserveReport() = block %d %s, %d addresses, total %s", report.BlockHeight, report.BlockHash, len(report.Addresses), report.Total)
+	}
+	if len(report.Addresses[0].Outpoints) != 2 || report.Addresses[0].Balance != "1.50000000" {
+		t.Errorf("address %s = %+v", key1.Address, report.Addresses[0])
+	}
+
+	//报告经JSON传递给审计方后验证
+	data, _ := json.Marshal(report)
+	var received ReserveReport
+	if err := json.Unmarshal(data, &received); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyReserveReport(&received); err != nil {
+		t.Errorf("VerifyReserveReport() unexpected error = %v", err)
+	}
+	if err := wm.CheckReserveReport(&received); err != nil {
+		t.Errorf("CheckReserveReport() unexpected error = %v", err)
+	}
+
+	tamper := func(name string, modify func(r *ReserveReport)) {
+		var r ReserveReport
+		json.Unmarshal(data, &r)
+		modify(&r)
+		if err := VerifyReserveReport(&r); err == nil {
+			t.Errorf("VerifyReserveReport() of %s want error", name)
+		}
+	}
+	tamper("another challenge", func(r *ReserveReport) { r.Challenge = "audit 2026Q2" })
+	tamper("another block", func(r *ReserveReport) {
+		r.BlockHash = "block9"
+		r.Message = ReserveMessage(r.Network, r.BlockHeight, r.BlockHash, r.Challenge)
+	})
+	tamper("inflated total", func(r *ReserveReport) { r.Total = "2.80000000" })
+	tamper("swapped public key", func(r *ReserveReport) { r.Addresses[0].PublicKey = r.Addresses[1].PublicKey })
+	tamper("duplicated address", func(r *ReserveReport) { r.Addresses = append(r.Addresses, r.Addresses[1]) })
+
+	//金额一致但与链上不符，需向节点核对
+	var inflated ReserveReport
+	json.Unmarshal(data, &inflated)
+	inflated.Addresses[0].Outpoints[0].Value *= 2
+	inflated.Addresses[0].Balance = "2.50000000"
+	inflated.Total = "2.80000000"
+	if err := VerifyReserveReport(&inflated); err != nil {
+		t.Errorf("VerifyReserveReport() of consistent report unexpected error = %v", err)
+	}
+	if err := wm.CheckReserveReport(&inflated); err == nil {
+		t.Errorf("CheckReserveReport() of inflated outpoint want error")
+	}
+
+	//输出不属于该地址
+	var moved ReserveReport
+	json.Unmarshal(data, &moved)
+	moved.Addresses[0].Outpoints[0].TxID = moved.Addresses[1].Outpoints[0].TxID
+	moved.Addresses[0].Outpoints[0].BlockHash = moved.Addresses[1].Outpoints[0].BlockHash
+	if err := wm.CheckReserveReport(&moved); err == nil {
+		t.Errorf("CheckReserveReport() of outpoint of another address want error")
+	}
+
+	//生成期间出块，已查询的未花可能已被花费
+	node.advance = true
+	if _, err := wm.CreateReserveReport(wrapper, []string{key1.Address}, "audit"); err == nil {
+		t.Errorf("CreateReserveReport() while chain advances want error")
+	}
+}
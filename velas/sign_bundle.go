@@ -0,0 +1,364 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/assetsadapterstore/velas-adapter/txsigner"
+	"github.com/blocktree/openwallet/common"
+	"github.com/blocktree/openwallet/openwallet"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+/*
+离线签名包格式（版本1）
+
+在线机器：CreateRawTransaction -> ExportSigningBundle -> 将SigningBundle的JSON拷贝到离线机器
+离线机器：SigningBundle.Verify核对内容 -> SignSigningBundle签名 -> 将SignedResult的JSON拷贝回在线机器
+在线机器：ImportSignedResult合并签名并验证 -> SubmitRawTransaction
+
+SigningBundle
+  version     格式版本，当前为1
+  bundleID    未签名交易单RawHex的双SHA256，签名结果通过该ID对应签名包
+  symbol      币种，取自钱包配置
+  decimals    金额精度，取自网络参数，inputs、outputs及summary的金额按该精度换算
+  accountID   创建交易单的账户
+  rawHex      未签名交易单，crypto.Tx的JSON再hex编码
+  inputs      每个输入的地址、HD路径、公钥、金额及待签消息（MsgForSign的hex）
+  outputs     按交易单解析的输出，kind为commission、receiver或change
+  summary     可读的交易摘要，离线机器上供人工核对
+  createTime  创建时间
+
+SignedResult
+  version     格式版本，当前为1
+  bundleID    对应的签名包ID
+  signatures  每个输入的序号、地址、公钥及签名（hex）
+*/
+
+const (
+	SigningBundleVersion = 1 //签名包格式版本
+
+	BundleOutputCommission = "commission" //手续费输出
+	BundleOutputReceiver   = "receiver"   //接收地址输出
+	BundleOutputChange     = "change"     //找零输出
+)
+
+//SigningBundleInput 签名包中的输入
+type SigningBundleInput struct {
+	Index     int    `json:"index"`
+	Address   string `json:"address"`
+	HDPath    string `json:"hdPath"`
+	PublicKey string `json:"publicKey"`
+	EccType   uint32 `json:"eccType"`
+	Amount    string `json:"amount"`
+	Message   string `json:"message"` //待签消息，MsgForSign的hex
+}
+
+//SigningBundleOutput 签名包中的输出
+type SigningBundleOutput struct {
+	Kind    string `json:"kind"`
+	Address string `json:"address,omitempty"`
+	Amount  string `json:"amount"`
+}
+
+//SigningBundle 离线签名包
+type SigningBundle struct {
+	Version    int                    `json:"version"`
+	BundleID   string                 `json:"bundleID"`
+	Symbol     string                 `json:"symbol"`
+	Decimals   int32                  `json:"decimals"`
+	AccountID  string                 `json:"accountID"`
+	RawHex     string                 `json:"rawHex"`
+	Inputs     []*SigningBundleInput  `json:"inputs"`
+	Outputs    []*SigningBundleOutput `json:"outputs"`
+	Summary    string                 `json:"summary"`
+	CreateTime int64                  `json:"createTime"`
+}
+
+//SignedInput 输入的签名
+type SignedInput struct {
+	Index     int    `json:"index"`
+	Address   string `json:"address"`
+	PublicKey string `json:"publicKey"`
+	Signature string `json:"signature"`
+}
+
+//SignedResult 离线签名结果
+type SignedResult struct {
+	Version    int            `json:"version"`
+	BundleID   string         `json:"bundleID"`
+	Signatures []*SignedInput `json:"signatures"`
+}
+
+//ExportSigningBundle 导出已构建交易单的离线签名包
+func (decoder *TransactionDecoder) ExportSigningBundle(rawTx *openwallet.RawTransaction) (*SigningBundle, error) {
+
+	if decoder.wm.IsEVM() {
+		return nil, fmt.Errorf("signing bundle is not supported by evm backend")
+	}
+
+	if !rawTx.IsBuilt {
+		return nil, fmt.Errorf("transaction is not built")
+	}
+
+	keySignatures := make(map[string]*openwallet.KeySignature)
+	for _, keySignature := range rawTx.Signatures[rawTx.Account.AccountID] {
+		keySignatures[keySignature.Message] = keySignature
+	}
+
+	bundle, err := NewSigningBundle(rawTx.RawHex, decoder.wm.Symbol(), decoder.wm.Decimal(), func(index int, address, message string) (*SigningBundleInput, error) {
+		keySignature, ok := keySignatures[message]
+		if !ok || keySignature.Address == nil || keySignature.Address.Address != address {
+			return nil, fmt.Errorf("input %d of %s has no key signature", index, address)
+		}
+		return &SigningBundleInput{
+			HDPath:    keySignature.Address.HDPath,
+			PublicKey: keySignature.Address.PublicKey,
+			EccType:   keySignature.EccType,
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bundle.AccountID = rawTx.Account.AccountID
+	return bundle, nil
+}
+
+//NewSigningBundle 根据未签名交易单创建签名包，symbol及decimals为币种及金额精度，inputFunc返回输入的HD路径、公钥及曲线类型
+func NewSigningBundle(rawHex, symbol string, decimals int32, inputFunc func(index int, address, message string) (*SigningBundleInput, error)) (*SigningBundle, error) {
+
+	trx, err := decodeRawHex(rawHex)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &SigningBundle{
+		Version:    SigningBundleVersion,
+		BundleID:   bundleID(rawHex),
+		Symbol:     symbol,
+		Decimals:   decimals,
+		RawHex:     rawHex,
+		CreateTime: time.Now().Unix(),
+	}
+
+	for i, in := range trx.Inputs {
+		address := base58.Encode(in.WalletAddress)
+		message := hex.EncodeToString(trx.MsgForSign(in.PreviousOutput.Hash, in.PreviousOutput.Index))
+		input, err := inputFunc(i, address, message)
+		if err != nil {
+			return nil, err
+		}
+		input.Index = i
+		input.Address = address
+		input.Amount = common.IntToDecimals(int64(in.PreviousOutput.Value), decimals).String()
+		input.Message = message
+		bundle.Inputs = append(bundle.Inputs, input)
+	}
+
+	bundle.Outputs = bundleOutputs(trx, decimals)
+	bundle.Summary = bundle.summary()
+	return bundle, nil
+}
+
+//Verify 离线机器签名前核对签名包：重新解析交易单，核对待签消息、输出及摘要
+func (bundle *SigningBundle) Verify() error {
+
+	if bundle.Version != SigningBundleVersion {
+		return fmt.Errorf("signing bundle version %d is not supported", bundle.Version)
+	}
+
+	if bundle.BundleID != bundleID(bundle.RawHex) {
+		return fmt.Errorf("signing bundle id mismatch")
+	}
+
+	trx, err := decodeRawHex(bundle.RawHex)
+	if err != nil {
+		return err
+	}
+
+	if len(trx.Inputs) != len(bundle.Inputs) {
+		return fmt.Errorf("signing bundle has %d inputs, transaction has %d", len(bundle.Inputs), len(trx.Inputs))
+	}
+
+	for i, in := range trx.Inputs {
+		input := bundle.Inputs[i]
+		message := hex.EncodeToString(trx.MsgForSign(in.PreviousOutput.Hash, in.PreviousOutput.Index))
+		amount := common.IntToDecimals(int64(in.PreviousOutput.Value), bundle.Decimals).String()
+		if input.Index != i || input.Address != base58.Encode(in.WalletAddress) || input.Amount != amount || input.Message != message {
+			return fmt.Errorf("signing bundle input %d does not match transaction", i)
+		}
+	}
+
+	outputs := bundleOutputs(trx, bundle.Decimals)
+	if len(outputs) != len(bundle.Outputs) {
+		return fmt.Errorf("signing bundle outputs do not match transaction")
+	}
+	for i, out := range outputs {
+		if *out != *bundle.Outputs[i] {
+			return fmt.Errorf("signing bundle output %d does not match transaction", i)
+		}
+	}
+
+	if bundle.Summary != bundle.summary() {
+		return fmt.Errorf("signing bundle summary does not match transaction")
+	}
+
+	return nil
+}
+
+//SignSigningBundle 离线机器核对并签名签名包，keyID为签名器中钱包密钥的标识
+func SignSigningBundle(bundle *SigningBundle, signer txsigner.Signer, keyID string) (*SignedResult, error) {
+
+	err := bundle.Verify()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SignedResult{
+		Version:  SigningBundleVersion,
+		BundleID: bundle.BundleID,
+	}
+
+	for _, input := range bundle.Inputs {
+		message, err := hex.DecodeString(input.Message)
+		if err != nil {
+			return nil, err
+		}
+		signature, err := signer.Sign(&txsigner.SignRequest{
+			KeyID:   keyID,
+			HDPath:  input.HDPath,
+			EccType: input.EccType,
+			Message: message,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("sign input %d failed, unexpected error: %v", input.Index, err)
+		}
+		result.Signatures = append(result.Signatures, &SignedInput{
+			Index:     input.Index,
+			Address:   input.Address,
+			PublicKey: input.PublicKey,
+			Signature: hex.EncodeToString(signature),
+		})
+	}
+
+	return result, nil
+}
+
+//ImportSignedResult 导入离线签名结果，合并签名并验证交易单，成功后可直接广播
+func (decoder *TransactionDecoder) ImportSignedResult(wrapper openwallet.WalletDAI, rawTx *openwallet.RawTransaction, result *SignedResult) error {
+
+	if result.Version != SigningBundleVersion {
+		return fmt.Errorf("signed result version %d is not supported", result.Version)
+	}
+
+	if result.BundleID != bundleID(rawTx.RawHex) {
+		return fmt.Errorf("signed result does not belong to transaction")
+	}
+
+	trx, err := decodeRawHex(rawTx.RawHex)
+	if err != nil {
+		return err
+	}
+
+	//按输入序号找到待签消息，再对应到交易单的签名
+	keySignatures := make(map[string]*openwallet.KeySignature)
+	for _, keySignature := range rawTx.Signatures[rawTx.Account.AccountID] {
+		keySignatures[keySignature.Message] = keySignature
+	}
+
+	for _, s := range result.Signatures {
+		if s.Index < 0 || s.Index >= len(trx.Inputs) {
+			return fmt.Errorf("signature of input %d is out of range", s.Index)
+		}
+		in := trx.Inputs[s.Index]
+		message := hex.EncodeToString(trx.MsgForSign(in.PreviousOutput.Hash, in.PreviousOutput.Index))
+		keySignature, ok := keySignatures[message]
+		if !ok || keySignature.Address == nil || keySignature.Address.Address != s.Address {
+			return fmt.Errorf("signature of input %d matches no key signature", s.Index)
+		}
+		keySignature.Signature = s.Signature
+	}
+
+	return decoder.VerifyVLXRawTransaction(wrapper, rawTx)
+}
+
+//summary 可读的交易摘要
+func (bundle *SigningBundle) summary() string {
+	lines := make([]string, 0)
+	for _, input := range bundle.Inputs {
+		lines = append(lines, fmt.Sprintf("spend %s %s from %s", input.Amount, bundle.Symbol, input.Address))
+	}
+	for _, out := range bundle.Outputs {
+		switch out.Kind {
+		case BundleOutputCommission:
+			lines = append(lines, fmt.Sprintf("pay fees %s %s", out.Amount, bundle.Symbol))
+		case BundleOutputChange:
+			lines = append(lines, fmt.Sprintf("change %s %s to %s", out.Amount, bundle.Symbol, out.Address))
+		default:
+			lines = append(lines, fmt.Sprintf("send %s %s to %s", out.Amount, bundle.Symbol, out.Address))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+//bundleOutputs 解析交易单输出，按decimals换算金额，回到输入地址的输出视为找零
+func bundleOutputs(trx *crypto.Tx, decimals int32) []*SigningBundleOutput {
+	inputAddrs := make(map[string]bool)
+	for _, in := range trx.Inputs {
+		inputAddrs[base58.Encode(in.WalletAddress)] = true
+	}
+
+	outputs := make([]*SigningBundleOutput, 0, len(trx.Outputs))
+	for _, out := range trx.Outputs {
+		amount := common.IntToDecimals(int64(out.Value), decimals).String()
+		if len(out.Script) == 0 {
+			outputs = append(outputs, &SigningBundleOutput{Kind: BundleOutputCommission, Amount: amount})
+			continue
+		}
+		address := base58.Encode(out.Script)
+		kind := BundleOutputReceiver
+		if inputAddrs[address] {
+			kind = BundleOutputChange
+		}
+		outputs = append(outputs, &SigningBundleOutput{Kind: kind, Address: address, Amount: amount})
+	}
+	return outputs
+}
+
+func decodeRawHex(rawHex string) (*crypto.Tx, error) {
+	txJSON, err := hex.DecodeString(rawHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid raw hex: %v", err)
+	}
+	var trx crypto.Tx
+	err = json.Unmarshal(txJSON, &trx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid raw transaction: %v", err)
+	}
+	return &trx, nil
+}
+
+func bundleID(rawHex string) string {
+	id := crypto.DHASH([]byte(rawHex))
+	return hex.EncodeToString(id[:])
+}
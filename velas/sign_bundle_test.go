@@ -0,0 +1,129 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/assetsadapterstore/velas-adapter/txsigner"
+	"github.com/blocktree/go-owcrypt"
+	"github.com/blocktree/openwallet/openwallet"
+)
+
+func TestSigningBundle_RoundTrip(t *testing.T) {
+
+	priv := make([]byte, 32)
+	for i := range priv {
+		priv[i] = 7
+	}
+	priv[0] &= 248
+	priv[31] &= 63
+	priv[31] |= 64
+	pub, _ := owcrypt.GenPubkey(priv, owcrypt.ECC_CURVE_ED25519)
+	owned, _ := addrdec.Default.AddressEncode(pub)
+	receiver, _ := addrdec.Default.AddressEncode([]byte{2})
+
+	address := &openwallet.Address{AccountID: "account", Address: owned, PublicKey: hex.EncodeToString(pub), HDPath: "m/44'/5655640'/0'/0/0"}
+
+	utxos := []*crypto.TransactionInputOutpoint{
+		{Hash: [32]byte{1}, Index: 0, Value: 60000000, Address: owned},
+		{Hash: [32]byte{2}, Index: 1, Value: 40000000, Address: owned},
+	}
+	trx, _ := crypto.NewTransaction(utxos, map[string]uint64{receiver: 50000000}, owned, 1000)
+	txJSON, _ := trx.MarshalJSON()
+
+	keySigs := make([]*openwallet.KeySignature, 0)
+	for _, u := range utxos {
+		keySigs = append(keySigs, &openwallet.KeySignature{
+			EccType: owcrypt.ECC_CURVE_ED25519,
+			Address: address,
+			Message: hex.EncodeToString(trx.MsgForSign(u.Hash, u.Index)),
+		})
+	}
+
+	rawTx := &openwallet.RawTransaction{
+		RawHex:     hex.EncodeToString(txJSON),
+		To:         map[string]string{receiver: "0.5"},
+		Fees:       "0.00001",
+		Account:    &openwallet.AssetsAccount{AccountID: "account"},
+		Signatures: map[string][]*openwallet.KeySignature{"account": keySigs},
+		IsBuilt:    true,
+	}
+
+	decoder := NewTransactionDecoder(NewWalletManager())
+
+	bundle, err := decoder.ExportSigningBundle(rawTx)
+	if err != nil {
+		t.Fatalf("ExportSigningBundle() unexpected error = %v", err)
+	}
+	t.Logf("summary:\n%s", bundle.Summary)
+
+	//模拟拷贝到离线机器
+	bundleJSON, _ := json.Marshal(bundle)
+	var offline SigningBundle
+	json.Unmarshal(bundleJSON, &offline)
+
+	signer := txsigner.NewStaticSigner(map[string][]byte{"wallet": priv})
+	result, err := SignSigningBundle(&offline, signer, "wallet")
+	if err != nil {
+		t.Fatalf("SignSigningBundle() unexpected error = %v", err)
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	var online SignedResult
+	json.Unmarshal(resultJSON, &online)
+
+	err = decoder.ImportSignedResult(&testWalletDAI{}, rawTx, &online)
+	if err != nil {
+		t.Fatalf("ImportSignedResult() unexpected error = %v", err)
+	}
+	if !rawTx.IsCompleted {
+		t.Errorf("ImportSignedResult() transaction is not completed")
+	}
+
+	//币种及精度取自钱包配置
+	params := addrdec.MainNetParams
+	params.Decimals = 6
+	wm := NewWalletManager()
+	wm.Config.Symbol = "VLXT"
+	wm.Config.ChainParams = &params
+	bundle, err = NewTransactionDecoder(wm).ExportSigningBundle(rawTx)
+	if err != nil {
+		t.Fatalf("ExportSigningBundle() unexpected error = %v", err)
+	}
+	if bundle.Symbol != "VLXT" || bundle.Decimals != 6 || bundle.Inputs[0].Amount != "60" || !strings.Contains(bundle.Summary, "spend 60 VLXT") {
+		t.Errorf("ExportSigningBundle() symbol = %s, decimals = %d, input amount = %s, summary = %s", bundle.Symbol, bundle.Decimals, bundle.Inputs[0].Amount, bundle.Summary)
+	}
+	if err := bundle.Verify(); err != nil {
+		t.Errorf("Verify() of bundle with network decimals unexpected error = %v", err)
+	}
+	wm.Config.Backend = BackendEVM
+	if _, err := NewTransactionDecoder(wm).ExportSigningBundle(rawTx); err == nil {
+		t.Errorf("ExportSigningBundle() of evm backend want error")
+	}
+
+	tampered := offline
+	tampered.Outputs = append([]*SigningBundleOutput{}, offline.Outputs...)
+	tampered.Outputs[1] = &SigningBundleOutput{Kind: BundleOutputReceiver, Address: receiver, Amount: "0.05"}
+	if _, err := SignSigningBundle(&tampered, signer, "wallet"); err == nil {
+		t.Errorf("SignSigningBundle() with tampered outputs want error")
+	}
+}
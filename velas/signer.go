@@ -0,0 +1,87 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/assetsadapterstore/velas-adapter/txsigner"
+	"github.com/blocktree/openwallet/hdkeystore"
+	"github.com/blocktree/openwallet/openwallet"
+)
+
+const (
+	SignerModeLocal  = "local"  //使用钱包HD密钥在进程内签名
+	SignerModeRemote = "remote" //通过远程签名服务签名，私钥不进入钱包进程
+)
+
+//NewSigner 根据配置创建签名器，本地模式返回nil，使用钱包HD密钥签名
+//PKCS#11签名器依赖硬件厂商的实现，需直接设置WalletManager.Signer
+func NewSigner(c *WalletConfig) (txsigner.Signer, error) {
+	switch strings.ToLower(c.SignerMode) {
+	case "", SignerModeLocal:
+		return nil, nil
+	case SignerModeRemote:
+		if len(c.RemoteSignerURL) == 0 {
+			return nil, fmt.Errorf("remote signer url is empty")
+		}
+		return txsigner.NewRemoteSigner(c.RemoteSignerURL, c.RemoteSignerToken, c.RemoteSignerTimeout), nil
+	default:
+		return nil, fmt.Errorf("unknown signer mode: %s", c.SignerMode)
+	}
+}
+
+//signerKeyID 签名器中钱包密钥的标识，使用钱包ID
+func signerKeyID(wrapper openwallet.WalletDAI) string {
+	if w := wrapper.GetWallet(); w != nil {
+		return w.WalletID
+	}
+	return ""
+}
+
+//walletSigner 交易单签名器，未配置签名器时，使用钱包HD密钥在进程内签名
+func (wm *WalletManager) walletSigner(wrapper openwallet.WalletDAI) (txsigner.Signer, error) {
+	if wm.Signer != nil {
+		return wm.Signer, nil
+	}
+	key, err := wrapper.HDKey()
+	if err != nil {
+		return nil, err
+	}
+	return &hdKeySigner{key: key}, nil
+}
+
+//hdKeySigner 钱包HD密钥签名器，按签名请求的曲线类型派生子密钥，与签名使用的曲线一致
+type hdKeySigner struct {
+	key *hdkeystore.HDKey
+}
+
+//Sign 实现txsigner.Signer
+func (signer *hdKeySigner) Sign(req *txsigner.SignRequest) ([]byte, error) {
+	if req == nil {
+		return nil, fmt.Errorf("sign request is empty")
+	}
+	childKey, err := signer.key.DerivedKeyWithPath(req.HDPath, req.EccType)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := childKey.GetPrivateKeyBytes()
+	if err != nil {
+		return nil, err
+	}
+	return txsigner.Default.SignTransactionHash(req.Message, privateKey, req.EccType)
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/txsigner"
+	"github.com/blocktree/go-owcrypt"
+	"github.com/blocktree/openwallet/hdkeystore"
+	"github.com/blocktree/openwallet/openwallet"
+)
+
+type testHDKeyWalletDAI struct {
+	openwallet.WalletDAIBase
+	key *hdkeystore.HDKey
+}
+
+func (w *testHDKeyWalletDAI) HDKey(password ...string) (*hdkeystore.HDKey, error) {
+	return w.key, nil
+}
+
+func TestWalletManager_WalletSignerEccType(t *testing.T) {
+
+	seed := make([]byte, 32)
+	seed[0] = 1
+	key, err := hdkeystore.NewHDKey(seed, "test", "m/44'/88'")
+	if err != nil {
+		t.Fatalf("NewHDKey() unexpected error = %v", err)
+	}
+	hdPath := "m/44'/88'/1'/0/0"
+	message := owcrypt.Hash([]byte("velas"), 0, owcrypt.HASH_ALG_SHA256)
+
+	wm := NewWalletManager()
+	//钱包配置的曲线与签名请求不同时，按签名请求的曲线派生子密钥
+	wm.Config.CurveType = owcrypt.ECC_CURVE_SECP256K1
+	signer, err := wm.walletSigner(&testHDKeyWalletDAI{key: key})
+	if err != nil {
+		t.Fatalf("walletSigner() unexpected error = %v", err)
+	}
+	signature, err := signer.Sign(&txsigner.SignRequest{
+		HDPath:  hdPath,
+		EccType: owcrypt.ECC_CURVE_ED25519,
+		Message: message,
+	})
+	if err != nil {
+		t.Fatalf("Sign() unexpected error = %v", err)
+	}
+
+	childKey, _ := key.DerivedKeyWithPath(hdPath, owcrypt.ECC_CURVE_ED25519)
+	if ret := owcrypt.Verify(childKey.GetPublicKeyBytes(), nil, message, signature, owcrypt.ECC_CURVE_ED25519); ret != owcrypt.SUCCESS {
+		t.Errorf("Verify() of signature with key derived by request curve = %d, want success", ret)
+	}
+}
@@ -125,16 +125,34 @@ func (decoder *TransactionDecoder) SubmitRawTransaction(wrapper openwallet.Walle
 		return nil, openwallet.ConvertError(err)
 	}
 
+	//检查输入是否仍未花费，已花费时返回InputsSpentError，调用方可重建交易单
+	err = decoder.PreflightVLXTransaction(&trx)
+	if err != nil {
+		decoder.releaseUTXO(trx)
+		return nil, err
+	}
+
 	err = decoder.wm.WalletClient.Tx.Validate(trx)
+	if feedback, ok := decoder.wm.FeeModel.(FeeFeedback); ok {
+		feedback.ValidateFeedback(err)
+	}
 	if err != nil {
+		decoder.releaseUTXO(trx)
 		return nil, err
 	}
 
 	_, err = decoder.wm.WalletClient.Tx.Publish(trx)
 	if err != nil {
+		decoder.releaseUTXO(trx)
 		return nil, err
 	}
 
+	//跟踪交易单是否上链
+	err = decoder.wm.TxTracker.Track(rawTx.Account.AccountID, &trx)
+	if err != nil {
+		decoder.wm.Log.Errorf("track transaction failed, unexpected error: %v", err)
+	}
+
 	rawTx.TxID = hex.EncodeToString(trx.Hash[:])
 	rawTx.IsSubmit = true
 
@@ -187,6 +205,11 @@ func (decoder *TransactionDecoder) CreateVLXRawTransaction(wrapper openwallet.Wa
 		return errors.New("Receiver address is empty")
 	}
 
+	//0x格式的EVM地址不能作为接收地址
+	if err := decoder.validateReceivers(rawTx); err != nil {
+		return err
+	}
+
 	address, err := wrapper.GetAddressList(0, limit, "AccountID", rawTx.Account.AccountID)
 	if err != nil {
 		return err
@@ -206,6 +229,12 @@ func (decoder *TransactionDecoder) CreateVLXRawTransaction(wrapper openwallet.Wa
 		unspents = append(unspents, outputs...)
 	}
 
+	//排除已被其他交易单锁定的utxo
+	unspents, err = decoder.wm.FilterReservedUTXO(unspents)
+	if err != nil {
+		return err
+	}
+
 	if len(unspents) == 0 {
 		return fmt.Errorf("[%s] balance is not enough", accountID)
 	}
@@ -222,23 +251,19 @@ func (decoder *TransactionDecoder) CreateVLXRawTransaction(wrapper openwallet.Wa
 		return unspents[a].Value < unspents[b].Value
 	})
 
-	if len(rawTx.FeeRate) == 0 {
-		fixFees, err = decimal.NewFromString(decoder.wm.Config.FixFees)
-		if err != nil {
-			return err
-		}
-	} else {
-		fixFees, _ = decimal.NewFromString(rawTx.FeeRate)
-	}
-
 	decoder.wm.Log.Info("Calculating wallet unspent record to build transaction...")
-	computeTotalSend := totalSend.Add(fixFees)
+	computeTotalSend := totalSend
 
-	//计算一个可用于支付的余额
+	//计算一个可用于支付的余额，手续费按已选输入数量及输出数量（含找零）估算
 	for _, u := range unspents {
 		v := common.IntToDecimals(int64(u.Value), decoder.wm.Decimal())
 		balance = balance.Add(v)
 		affordUTXO = append(affordUTXO, u)
+		fixFees, err = decoder.wm.FeeModel.EstimateFees(len(affordUTXO), len(rawTx.To)+1, rawTx.FeeRate)
+		if err != nil {
+			return err
+		}
+		computeTotalSend = totalSend.Add(fixFees)
 		if balance.GreaterThanOrEqual(computeTotalSend) {
 			break
 		}
@@ -253,7 +278,9 @@ func (decoder *TransactionDecoder) CreateVLXRawTransaction(wrapper openwallet.Wa
 	changeAddress := affordUTXO[0].Address
 
 	changeAmount := balance.Sub(computeTotalSend)
-	rawTx.FeeRate = fixFees.StringFixed(decoder.wm.Decimal())
+	if len(rawTx.FeeRate) == 0 {
+		rawTx.FeeRate, _ = decoder.wm.FeeModel.FeeRate()
+	}
 	rawTx.Fees = fixFees.StringFixed(decoder.wm.Decimal())
 
 	decoder.wm.Log.Std.Notice("-----------------------------------------------")
@@ -284,6 +311,16 @@ func (decoder *TransactionDecoder) CreateVLXRawTransaction(wrapper openwallet.Wa
 	return nil
 }
 
+//validateReceivers 校验接收地址，0x格式的EVM地址没有对应的ed25519密钥，转入后无法花费，不能作为接收地址
+func (decoder *TransactionDecoder) validateReceivers(rawTx *openwallet.RawTransaction) error {
+	for addr := range rawTx.To {
+		if _, err := decoder.wm.Decoder.ValidateAddress(addr); err != nil {
+			return openwallet.Errorf(openwallet.ErrAdressDecodeFailed, "receiver %v", err)
+		}
+	}
+	return nil
+}
+
 //SignVLXRawTransaction 签名交易单
 func (decoder *TransactionDecoder) SignVLXRawTransaction(wrapper openwallet.WalletDAI, rawTx *openwallet.RawTransaction) error {
 
@@ -291,7 +328,13 @@ func (decoder *TransactionDecoder) SignVLXRawTransaction(wrapper openwallet.Wall
 		return fmt.Errorf("transaction signature is empty")
 	}
 
-	key, err := wrapper.HDKey()
+	//签名前核对交易单内容与声明的接收地址、金额及手续费一致
+	err := decoder.VerifyVLXSignIntent(wrapper, rawTx)
+	if err != nil {
+		return err
+	}
+
+	signer, err := decoder.wm.walletSigner(wrapper)
 	if err != nil {
 		return err
 	}
@@ -300,11 +343,6 @@ func (decoder *TransactionDecoder) SignVLXRawTransaction(wrapper openwallet.Wall
 	if keySignatures != nil {
 		for _, keySignature := range keySignatures {
 
-			childKey, err := key.DerivedKeyWithPath(keySignature.Address.HDPath, keySignature.EccType)
-			keyBytes, err := childKey.GetPrivateKeyBytes()
-			if err != nil {
-				return err
-			}
 			txHash := keySignature.Message
 			decoder.wm.Log.Debug("hash:", txHash)
 
@@ -315,7 +353,12 @@ func (decoder *TransactionDecoder) SignVLXRawTransaction(wrapper openwallet.Wall
 
 			//签名交易
 			/////////交易单哈希签名
-			signature, err := txsigner.Default.SignTransactionHash(data, keyBytes, keySignature.EccType)
+			signature, err := signer.Sign(&txsigner.SignRequest{
+				KeyID:   signerKeyID(wrapper),
+				HDPath:  keySignature.Address.HDPath,
+				EccType: keySignature.EccType,
+				Message: data,
+			})
 			if err != nil {
 				return fmt.Errorf("transaction hash sign failed, unexpected error: %v", err)
 			}
@@ -369,7 +412,7 @@ func (decoder *TransactionDecoder) VerifyVLXRawTransaction(wrapper openwallet.Wa
 	}
 
 	/////////验证交易单
-	//验证时，对于公钥哈希地址，需要将对应的锁定脚本传入TxUnlock结构体
+	//签名按公钥哈希与输入地址匹配，与签名顺序无关
 	pass, signedTrans, err := txsigner.Default.VerifyAndCombineTransaction(emptyTrans, sigPub)
 	if pass {
 		decoder.wm.Log.Debug("transaction verify passed")
@@ -378,6 +421,7 @@ func (decoder *TransactionDecoder) VerifyVLXRawTransaction(wrapper openwallet.Wa
 	} else {
 		decoder.wm.Log.Errorf("transaction verify failed, unexpected error: %v", err)
 		rawTx.IsCompleted = false
+		return openwallet.Errorf(openwallet.ErrVerifyRawTransactionFailed, "transaction verify failed, unexpected error: %v", err)
 	}
 
 	return nil
@@ -385,7 +429,8 @@ func (decoder *TransactionDecoder) VerifyVLXRawTransaction(wrapper openwallet.Wa
 
 //GetRawTransactionFeeRate 获取交易单的费率
 func (decoder *TransactionDecoder) GetRawTransactionFeeRate() (feeRate string, unit string, err error) {
-	return decoder.wm.Config.FixFees, "TX", nil
+	feeRate, unit = decoder.wm.FeeModel.FeeRate()
+	return feeRate, unit, nil
 }
 
 //CreateVLXSummaryRawTransaction 创建BTC汇总交易
@@ -407,6 +452,11 @@ func (decoder *TransactionDecoder) CreateVLXSummaryRawTransaction(wrapper openwa
 		return nil, fmt.Errorf("mini transfer amount must be greater than address retained balance")
 	}
 
+	summaryAddress := sumRawTx.SummaryAddress
+	if _, err := decoder.wm.Decoder.ValidateAddress(summaryAddress); err != nil {
+		return nil, openwallet.Errorf(openwallet.ErrAdressDecodeFailed, "summary %v", err)
+	}
+
 	address, err := wrapper.GetAddressList(sumRawTx.AddressStartIndex, sumRawTx.AddressLimit, "AccountID", sumRawTx.Account.AccountID)
 	if err != nil {
 		return nil, err
@@ -440,16 +490,6 @@ func (decoder *TransactionDecoder) CreateVLXSummaryRawTransaction(wrapper openwa
 		return nil, nil
 	}
 
-	//取得费率
-	if len(sumRawTx.FeeRate) == 0 {
-		fixFees, err = decimal.NewFromString(decoder.wm.Config.FixFees)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		fixFees, _ = decimal.NewFromString(sumRawTx.FeeRate)
-	}
-
 	sumUnspents = make([]*crypto.TransactionInputOutpoint, 0)
 	outputAddrs = make(map[string]decimal.Decimal, 0)
 	totalInputAmount = decimal.Zero
@@ -461,6 +501,11 @@ func (decoder *TransactionDecoder) CreateVLXSummaryRawTransaction(wrapper openwa
 			return nil, err
 		}
 
+		outputs, err = decoder.wm.FilterReservedUTXO(outputs)
+		if err != nil {
+			return nil, err
+		}
+
 		sumUnspents = append(sumUnspents, outputs...)
 		if retainedBalance.GreaterThan(decimal.Zero) {
 			outputAddrs = appendOutput(outputAddrs, addr, retainedBalance)
@@ -469,6 +514,30 @@ func (decoder *TransactionDecoder) CreateVLXSummaryRawTransaction(wrapper openwa
 		//如果遍历地址完结，就可以进行构建交易单
 		if i == len(sumAddresses)-1 {
 
+			var (
+				feesSupportUTXO   []*crypto.TransactionInputOutpoint
+				feesSupportChange string
+				sumOutputs        = len(outputAddrs) + 1 //保留余额输出 + 汇总地址输出
+			)
+
+			//按输入输出数量估算手续费
+			fixFees, err = decoder.wm.FeeModel.EstimateFees(len(sumUnspents), sumOutputs, sumRawTx.FeeRate)
+			if err != nil {
+				return nil, err
+			}
+
+			//手续费由支持账户的utxo支付，汇总地址的余额可全部转出
+			if sumRawTx.FeesSupportAccount != nil {
+				estimateFees := func(supportInputs int) (decimal.Decimal, error) {
+					//支持账户的找零作为额外输出
+					return decoder.wm.FeeModel.EstimateFees(len(sumUnspents)+supportInputs, sumOutputs+1, sumRawTx.FeeRate)
+				}
+				feesSupportUTXO, feesSupportChange, fixFees, err = decoder.getFeesSupportUTXO(wrapper, sumRawTx.FeesSupportAccount, estimateFees)
+				if err != nil {
+					return nil, err
+				}
+			}
+
 			//计算这笔交易单的汇总数量
 			for _, u := range sumUnspents {
 				ua := common.IntToDecimals(int64(u.Value), decoder.wm.Decimal())
@@ -482,9 +551,13 @@ func (decoder *TransactionDecoder) CreateVLXSummaryRawTransaction(wrapper openwa
 				1. 输入总数量 = 合计账户地址的所有utxo
 				2. 账户地址输出总数量 = 账户地址保留余额 * 地址数
 				3. 汇总数量 = 输入总数量 - 账户地址输出总数量 - 手续费
+				4. 有手续费支持账户时，汇总数量 = 输入总数量 - 账户地址输出总数量
 			*/
 			retainedBalanceTotal := retainedBalance.Mul(decimal.New(int64(len(outputAddrs)), 0))
-			sumAmount := totalInputAmount.Sub(retainedBalanceTotal).Sub(fixFees)
+			sumAmount := totalInputAmount.Sub(retainedBalanceTotal)
+			if len(feesSupportUTXO) == 0 {
+				sumAmount = sumAmount.Sub(fixFees)
+			}
 
 			decoder.wm.Log.Debugf("totalInputAmount: %v", totalInputAmount)
 			decoder.wm.Log.Debugf("retainedBalanceTotal: %v", retainedBalanceTotal)
@@ -492,7 +565,7 @@ func (decoder *TransactionDecoder) CreateVLXSummaryRawTransaction(wrapper openwa
 			decoder.wm.Log.Debugf("sumAmount: %v", sumAmount)
 
 			//最后填充汇总地址及汇总数量
-			outputAddrs = appendOutput(outputAddrs, sumRawTx.SummaryAddress, sumAmount)
+			outputAddrs = appendOutput(outputAddrs, summaryAddress, sumAmount)
 
 			raxTxTo := make(map[string]string, 0)
 			for a, m := range outputAddrs {
@@ -509,7 +582,10 @@ func (decoder *TransactionDecoder) CreateVLXSummaryRawTransaction(wrapper openwa
 				Required: 1,
 			}
 
-			createErr := decoder.createVLXRawTransaction(wrapper, rawTx, sumUnspents, outputAddrs, "", fixFees)
+			//追加手续费支持账户的utxo作为输入，找零返回支持账户
+			sumUnspents = append(sumUnspents, feesSupportUTXO...)
+
+			createErr := decoder.createVLXRawTransaction(wrapper, rawTx, sumUnspents, outputAddrs, feesSupportChange, fixFees)
 			rawTxWithErr := &openwallet.RawTransactionWithError{
 				RawTx: rawTx,
 				Error: openwallet.ConvertError(createErr),
@@ -529,6 +605,74 @@ func (decoder *TransactionDecoder) CreateVLXSummaryRawTransaction(wrapper openwa
 	return rawTxArray, nil
 }
 
+//getFeesSupportUTXO 获取手续费支持账户中足够支付手续费的utxo，返回utxo、找零地址及手续费
+//支持账户需与汇总账户在同一钱包下，以便使用同一个HDKey签名
+//estimateFees 按支持账户的输入数量估算手续费
+func (decoder *TransactionDecoder) getFeesSupportUTXO(
+	wrapper openwallet.WalletDAI,
+	feesSupport *openwallet.FeesSupportAccount,
+	estimateFees func(supportInputs int) (decimal.Decimal, error),
+) ([]*crypto.TransactionInputOutpoint, string, decimal.Decimal, error) {
+
+	var (
+		unspents   []*crypto.TransactionInputOutpoint
+		affordUTXO []*crypto.TransactionInputOutpoint
+		balance    = decimal.Zero
+		fees       = decimal.Zero
+		limit      = 2000
+	)
+
+	address, err := wrapper.GetAddressList(0, limit, "AccountID", feesSupport.AccountID)
+	if err != nil {
+		return nil, "", fees, err
+	}
+
+	if len(address) == 0 {
+		return nil, "", fees, openwallet.Errorf(openwallet.ErrAccountNotAddress, "fees support account [%s] have not address", feesSupport.AccountID)
+	}
+
+	for _, address := range address {
+		outputs, err := decoder.wm.WalletClient.Wallet.GetUnspent(address.Address)
+		if err != nil {
+			return nil, "", fees, err
+		}
+		unspents = append(unspents, outputs...)
+	}
+
+	unspents, err = decoder.wm.FilterReservedUTXO(unspents)
+	if err != nil {
+		return nil, "", fees, err
+	}
+
+	//获取utxo，按小到大排序
+	sort.Slice(unspents, func(a, b int) bool {
+		return unspents[a].Value < unspents[b].Value
+	})
+
+	for _, u := range unspents {
+		v := common.IntToDecimals(int64(u.Value), decoder.wm.Decimal())
+		balance = balance.Add(v)
+		affordUTXO = append(affordUTXO, u)
+		fees, err = estimateFees(len(affordUTXO))
+		if err != nil {
+			return nil, "", fees, err
+		}
+		if balance.GreaterThanOrEqual(fees) {
+			break
+		}
+	}
+
+	if len(affordUTXO) == 0 || balance.LessThan(fees) {
+		return nil, "", fees, openwallet.Errorf(openwallet.ErrInsufficientFees, "fees support account [%s] balance: %s is not enough to pay fees: %s", feesSupport.AccountID, balance.StringFixed(decoder.wm.Decimal()), fees.StringFixed(decoder.wm.Decimal()))
+	}
+
+	decoder.wm.Log.Std.Notice("Fees Support Account: %s", feesSupport.AccountID)
+	decoder.wm.Log.Std.Notice("Fees Support Balance: %v", balance.String())
+	decoder.wm.Log.Std.Notice("Fees Support Change: %v", balance.Sub(fees).String())
+
+	return affordUTXO, affordUTXO[0].Address, fees, nil
+}
+
 //createVLXRawTransaction 创建VLX原始交易单
 func (decoder *TransactionDecoder) createVLXRawTransaction(
 	wrapper openwallet.WalletDAI,
@@ -558,6 +702,19 @@ func (decoder *TransactionDecoder) createVLXRawTransaction(
 		return fmt.Errorf("Receiver addresses is empty! ")
 	}
 
+	//拒绝其他网络或无效的地址
+	for addr := range to {
+		if _, err := decoder.wm.Decoder.ValidateAddress(addr); err != nil {
+			return openwallet.Errorf(openwallet.ErrAdressDecodeFailed, "receiver %v", err)
+		}
+	}
+	//汇总交易没有手续费支持账户时无找零
+	if len(changeAddress) > 0 {
+		if _, err := decoder.wm.Decoder.ValidateAddress(changeAddress); err != nil {
+			return openwallet.Errorf(openwallet.ErrAdressDecodeFailed, "change %v", err)
+		}
+	}
+
 	//计算总发送金额
 	for addr, amount := range to {
 		//deamount, _ := decimal.NewFromString(amount)
@@ -609,6 +766,8 @@ func (decoder *TransactionDecoder) createVLXRawTransaction(
 
 	//装配签名
 	keySigs := make([]*openwallet.KeySignature, 0)
+	//其他账户（手续费支持账户）的输入
+	foreignInputs := uint64(0)
 
 	for i, utxo := range affordUTXO {
 
@@ -621,6 +780,9 @@ func (decoder *TransactionDecoder) createVLXRawTransaction(
 		if err != nil {
 			return err
 		}
+		if addr.AccountID != accountID {
+			foreignInputs += utxo.Value
+		}
 
 		signature := openwallet.KeySignature{
 			EccType: decoder.wm.Config.CurveType,
@@ -633,12 +795,34 @@ func (decoder *TransactionDecoder) createVLXRawTransaction(
 
 	}
 
+	//手续费由其他账户的输入支付时（扣除找回该账户的找零），不计入本账户的支出
 	feesDec, _ := decimal.NewFromString(rawTx.Fees)
+	if foreignInputs > 0 {
+		foreignPaid := foreignInputs
+		if addr, err := wrapper.GetAddress(changeAddress); err == nil && addr.AccountID != accountID {
+			//找零 = 输入 - 输出 - 手续费
+			change := uint64(0)
+			for _, utxo := range affordUTXO {
+				change += utxo.Value
+			}
+			for _, amount := range vouts {
+				change -= amount
+			}
+			foreignPaid -= change - commission
+		}
+		feesDec = feesDec.Sub(common.IntToDecimals(int64(foreignPaid), decoder.wm.Decimal()))
+	}
 	accountTotalSent = accountTotalSent.Add(feesDec)
 	accountTotalSent = decimal.Zero.Sub(accountTotalSent)
 
 	//TODO:多重签名要使用owner的公钥填充
 
+	//锁定utxo，直到交易单广播失败、锁定超时或区块扫描确认花费
+	err = decoder.wm.ReserveUTXO(accountID, affordUTXO)
+	if err != nil {
+		return openwallet.Errorf(openwallet.ErrCreateRawTransactionFailed, "reserve utxo failed, unexpected error: %v", err)
+	}
+
 	rawTx.Signatures[rawTx.Account.AccountID] = keySigs
 	rawTx.IsBuilt = true
 	rawTx.TxAmount = accountTotalSent.StringFixed(decoder.wm.Decimal())
@@ -648,6 +832,14 @@ func (decoder *TransactionDecoder) createVLXRawTransaction(
 	return nil
 }
 
+//releaseUTXO 释放交易单锁定的utxo
+func (decoder *TransactionDecoder) releaseUTXO(trx crypto.Tx) {
+	err := decoder.wm.ReleaseUTXO(trx.Inputs)
+	if err != nil {
+		decoder.wm.Log.Errorf("release utxo failed, unexpected error: %v", err)
+	}
+}
+
 func appendOutput(output map[string]decimal.Decimal, address string, amount decimal.Decimal) map[string]decimal.Decimal {
 	if origin, ok := output[address]; ok {
 		origin = origin.Add(amount)
@@ -0,0 +1,172 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/assetsadapterstore/velas-adapter/rpc"
+	"github.com/blocktree/openwallet/openwallet"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+//testAccountWalletDAI 按账户返回地址列表
+type testAccountWalletDAI struct {
+	testWalletDAI
+}
+
+func (w *testAccountWalletDAI) GetAddressList(offset, limit int, cols ...interface{}) ([]*openwallet.Address, error) {
+	list := make([]*openwallet.Address, 0)
+	for _, a := range w.addresses {
+		if len(cols) == 2 && cols[0] == "AccountID" && a.AccountID == cols[1] {
+			list = append(list, a)
+		}
+	}
+	return list, nil
+}
+
+func TestTransactionDecoder_CreateVLXSummaryRawTransaction(t *testing.T) {
+
+	wm, cleanup := testReservationWalletManager(t)
+	defer cleanup()
+
+	owned, _ := addrdec.Default.AddressEncode([]byte{1})
+	summary, _ := addrdec.Default.AddressEncode([]byte{2})
+
+	wrapper := &testAccountWalletDAI{testWalletDAI{addresses: map[string]*openwallet.Address{
+		owned: {AccountID: "account", Address: owned},
+	}}}
+
+	unspents := []*crypto.TransactionInputOutpoint{
+		{Hash: [32]byte{1}, Index: 0, Value: 300000000},
+		{Hash: [32]byte{2}, Index: 1, Value: 200000000},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/wallet/balance/" + owned:
+			fmt.Fprint(w, `{"amount":500000000}`)
+		case "/api/v1/wallet/unspent/" + owned:
+			json.NewEncoder(w).Encode(unspents)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"not found"}`)
+		}
+	}))
+	defer server.Close()
+
+	wm.WalletClient = rpc.NewClient(server.URL)
+	decoder := NewTransactionDecoder(wm)
+
+	//没有手续费支持账户，手续费从汇总金额扣除，交易无找零
+	rawTxs, err := decoder.CreateVLXSummaryRawTransaction(wrapper, &openwallet.SummaryRawTransaction{
+		Coin:            openwallet.Coin{Symbol: wm.Symbol()},
+		Account:         &openwallet.AssetsAccount{AccountID: "account"},
+		SummaryAddress:  summary,
+		MinTransfer:     "1",
+		RetainedBalance: "0",
+		FeeRate:         "0.001",
+	})
+	if err != nil {
+		t.Fatalf("CreateVLXSummaryRawTransaction() unexpected error = %v", err)
+	}
+	if len(rawTxs) != 1 || rawTxs[0].Error != nil || !rawTxs[0].RawTx.IsBuilt {
+		t.Fatalf("CreateVLXSummaryRawTransaction() = %+v, want one built transaction", rawTxs)
+	}
+
+	data, _ := hex.DecodeString(rawTxs[0].RawTx.RawHex)
+	var trx crypto.Tx
+	if err := json.Unmarshal(data, &trx); err != nil {
+		t.Fatalf("RawHex is not a transaction: %v", err)
+	}
+	//第一个输出为手续费，其余均为汇总地址
+	for _, out := range trx.Outputs[1:] {
+		if base58.Encode(out.Script) != summary {
+			t.Errorf("output %d pays %s, want only summary address %s", out.Index, base58.Encode(out.Script), summary)
+		}
+	}
+	if total := trx.Outputs[0].Value + trx.Outputs[1].Value; len(trx.Outputs) != 2 || total != 500000000 {
+		t.Errorf("outputs = %+v, want commission and summary amount of 500000000", trx.Outputs)
+	}
+	//手续费从汇总金额扣除，账户支出为全部输入
+	if rawTxs[0].RawTx.TxAmount != "-5.00000000" {
+		t.Errorf("TxAmount = %s, want -5.00000000", rawTxs[0].RawTx.TxAmount)
+	}
+}
+
+func TestTransactionDecoder_CreateVLXSummaryRawTransactionFeesSupport(t *testing.T) {
+
+	wm, cleanup := testReservationWalletManager(t)
+	defer cleanup()
+
+	owned, _ := addrdec.Default.AddressEncode([]byte{1})
+	summary, _ := addrdec.Default.AddressEncode([]byte{2})
+	support, _ := addrdec.Default.AddressEncode([]byte{3})
+
+	wrapper := &testAccountWalletDAI{testWalletDAI{addresses: map[string]*openwallet.Address{
+		owned:   {AccountID: "account", Address: owned},
+		support: {AccountID: "support", Address: support},
+	}}}
+
+	unspents := map[string][]*crypto.TransactionInputOutpoint{
+		owned:   {{Hash: [32]byte{1}, Index: 0, Value: 500000000, Address: owned}},
+		support: {{Hash: [32]byte{3}, Index: 0, Value: 100000000, Address: support}},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/wallet/balance/" + owned:
+			fmt.Fprint(w, `{"amount":500000000}`)
+		case "/api/v1/wallet/unspent/" + owned:
+			json.NewEncoder(w).Encode(unspents[owned])
+		case "/api/v1/wallet/unspent/" + support:
+			json.NewEncoder(w).Encode(unspents[support])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"not found"}`)
+		}
+	}))
+	defer server.Close()
+
+	wm.WalletClient = rpc.NewClient(server.URL)
+	decoder := NewTransactionDecoder(wm)
+
+	rawTxs, err := decoder.CreateVLXSummaryRawTransaction(wrapper, &openwallet.SummaryRawTransaction{
+		Coin:               openwallet.Coin{Symbol: wm.Symbol()},
+		Account:            &openwallet.AssetsAccount{AccountID: "account"},
+		SummaryAddress:     summary,
+		MinTransfer:        "1",
+		RetainedBalance:    "0",
+		FeeRate:            "0.001",
+		FeesSupportAccount: &openwallet.FeesSupportAccount{AccountID: "support"},
+	})
+	if err != nil || len(rawTxs) != 1 || rawTxs[0].Error != nil {
+		t.Fatalf("CreateVLXSummaryRawTransaction() = %+v, error = %v", rawTxs, err)
+	}
+
+	//手续费由支持账户支付，汇总账户只支出汇总金额
+	rawTx := rawTxs[0].RawTx
+	if rawTx.Fees != "0.00100000" || rawTx.TxAmount != "-5.00000000" {
+		t.Errorf("Fees = %s, TxAmount = %s, want fees 0.00100000 charged to support account and -5.00000000", rawTx.Fees, rawTx.TxAmount)
+	}
+}
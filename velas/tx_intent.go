@@ -0,0 +1,148 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/blocktree/openwallet/openwallet"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/shopspring/decimal"
+)
+
+//VerifyVLXSignIntent 签名前重新解析RawHex，核对待签消息、接收地址、金额、手续费及找零，不一致时拒绝签名
+func (decoder *TransactionDecoder) VerifyVLXSignIntent(wrapper openwallet.WalletDAI, rawTx *openwallet.RawTransaction) error {
+
+	rawHex, err := hex.DecodeString(rawTx.RawHex)
+	if err != nil {
+		return openwallet.Errorf(openwallet.ErrSignRawTransactionFailed, "invalid raw hex, unexpected error: %v", err)
+	}
+
+	var trx crypto.Tx
+	err = json.Unmarshal(rawHex, &trx)
+	if err != nil {
+		return openwallet.Errorf(openwallet.ErrSignRawTransactionFailed, "invalid raw transaction, unexpected error: %v", err)
+	}
+
+	if len(trx.Inputs) == 0 || len(trx.Outputs) == 0 {
+		return openwallet.Errorf(openwallet.ErrSignRawTransactionFailed, "raw transaction has no inputs or outputs")
+	}
+
+	//待签消息必须是对应输入的MsgForSign
+	err = decoder.verifySignMessages(&trx, rawTx.Signatures[rawTx.Account.AccountID])
+	if err != nil {
+		return openwallet.Errorf(openwallet.ErrSignRawTransactionFailed, "%v", err)
+	}
+
+	//输入地址所属账户，找零只能回到这些账户
+	owners := make(map[string]bool)
+	totalIn := uint64(0)
+	for _, in := range trx.Inputs {
+		addr := base58.Encode(in.WalletAddress)
+		a, err := wrapper.GetAddress(addr)
+		if err != nil || a == nil {
+			return openwallet.Errorf(openwallet.ErrSignRawTransactionFailed, "input address %s is not owned by wallet", addr)
+		}
+		owners[a.AccountID] = true
+		totalIn += in.PreviousOutput.Value
+	}
+
+	//第一个输出为手续费
+	commission := trx.Outputs[0]
+	if len(commission.Script) != 0 {
+		return openwallet.Errorf(openwallet.ErrSignRawTransactionFailed, "first output is not commission")
+	}
+	fees, err := decimal.NewFromString(rawTx.Fees)
+	if err != nil {
+		return openwallet.Errorf(openwallet.ErrSignRawTransactionFailed, "invalid fees: %s", rawTx.Fees)
+	}
+	if commission.Value != decoder.amountToUnits(fees) {
+		return openwallet.Errorf(openwallet.ErrSignRawTransactionFailed, "commission %d does not match fees %s", commission.Value, rawTx.Fees)
+	}
+
+	expected := make(map[string]uint64)
+	for addr, amount := range rawTx.To {
+		amt, err := decimal.NewFromString(amount)
+		if err != nil {
+			return openwallet.Errorf(openwallet.ErrSignRawTransactionFailed, "invalid amount of %s: %s", addr, amount)
+		}
+		expected[addr] += decoder.amountToUnits(amt)
+	}
+
+	totalOut := commission.Value
+	for _, out := range trx.Outputs[1:] {
+		addr := base58.Encode(out.Script)
+		totalOut += out.Value
+
+		want := expected[addr]
+		delete(expected, addr)
+		if out.Value < want {
+			return openwallet.Errorf(openwallet.ErrSignRawTransactionFailed, "output %s amount %d is less than declared %d", addr, out.Value, want)
+		}
+		if out.Value == want {
+			continue
+		}
+
+		//超出声明金额的部分视为找零
+		a, err := wrapper.GetAddress(addr)
+		if err != nil || a == nil || !owners[a.AccountID] {
+			return openwallet.Errorf(openwallet.ErrSignRawTransactionFailed, "output %s is neither declared receiver nor change of inputs owner", addr)
+		}
+	}
+
+	for addr := range expected {
+		return openwallet.Errorf(openwallet.ErrSignRawTransactionFailed, "declared receiver %s is missing in outputs", addr)
+	}
+
+	if totalIn != totalOut {
+		return openwallet.Errorf(openwallet.ErrSignRawTransactionFailed, "total input %d does not equal total output %d", totalIn, totalOut)
+	}
+
+	return nil
+}
+
+//verifySignMessages 每个待签消息必须与其签名地址的某个输入的MsgForSign一致
+func (decoder *TransactionDecoder) verifySignMessages(trx *crypto.Tx, keySignatures []*openwallet.KeySignature) error {
+
+	messages := make(map[string]string)
+	for _, in := range trx.Inputs {
+		msg := trx.MsgForSign(in.PreviousOutput.Hash, in.PreviousOutput.Index)
+		messages[hex.EncodeToString(msg)] = base58.Encode(in.WalletAddress)
+	}
+
+	for _, keySignature := range keySignatures {
+		if keySignature.Address == nil {
+			return fmt.Errorf("key signature address is empty")
+		}
+		addr, ok := messages[keySignature.Message]
+		if !ok {
+			return fmt.Errorf("message of %s does not match any input", keySignature.Address.Address)
+		}
+		if addr != keySignature.Address.Address {
+			return fmt.Errorf("message of %s belongs to input of %s", keySignature.Address.Address, addr)
+		}
+	}
+
+	return nil
+}
+
+//amountToUnits 金额转为最小单位
+func (decoder *TransactionDecoder) amountToUnits(amount decimal.Decimal) uint64 {
+	return uint64(amount.Shift(decoder.wm.Decimal()).IntPart())
+}
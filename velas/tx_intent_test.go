@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/blocktree/openwallet/openwallet"
+)
+
+type testWalletDAI struct {
+	openwallet.WalletDAIBase
+	addresses map[string]*openwallet.Address
+}
+
+func (w *testWalletDAI) GetAddress(address string) (*openwallet.Address, error) {
+	a, ok := w.addresses[address]
+	if !ok {
+		return nil, fmt.Errorf("address %s not found", address)
+	}
+	return a, nil
+}
+
+func TestTransactionDecoder_VerifyVLXSignIntent(t *testing.T) {
+
+	owned, _ := addrdec.Default.AddressEncode([]byte{1})
+	receiver, _ := addrdec.Default.AddressEncode([]byte{2})
+	stranger, _ := addrdec.Default.AddressEncode([]byte{3})
+
+	wrapper := &testWalletDAI{addresses: map[string]*openwallet.Address{
+		owned: {AccountID: "account", Address: owned},
+	}}
+
+	decoder := NewTransactionDecoder(NewWalletManager())
+
+	newRawTx := func(changeAddress string, to map[string]string, fees string) *openwallet.RawTransaction {
+		utxo := &crypto.TransactionInputOutpoint{Hash: [32]byte{1}, Index: 0, Value: 100000000, Address: owned}
+		trx, err := crypto.NewTransaction([]*crypto.TransactionInputOutpoint{utxo}, map[string]uint64{receiver: 50000000}, changeAddress, 1000)
+		if err != nil {
+			t.Fatalf("NewTransaction() unexpected error = %v", err)
+		}
+		txJSON, _ := trx.MarshalJSON()
+		return &openwallet.RawTransaction{
+			RawHex:  hex.EncodeToString(txJSON),
+			To:      to,
+			Fees:    fees,
+			Account: &openwallet.AssetsAccount{AccountID: "account"},
+			Signatures: map[string][]*openwallet.KeySignature{
+				"account": {{
+					Address: wrapper.addresses[owned],
+					Message: hex.EncodeToString(trx.MsgForSign(utxo.Hash, utxo.Index)),
+				}},
+			},
+		}
+	}
+
+	declared := map[string]string{receiver: "0.5"}
+
+	tests := []struct {
+		name    string
+		rawTx   *openwallet.RawTransaction
+		wantErr bool
+	}{
+		{"match", newRawTx(owned, declared, "0.00001"), false},
+		{"change to stranger", newRawTx(stranger, declared, "0.00001"), true},
+		{"declared amount differs", newRawTx(owned, map[string]string{receiver: "0.6"}, "0.00001"), true},
+		{"declared receiver missing", newRawTx(owned, map[string]string{receiver: "0.5", stranger: "0.1"}, "0.00001"), true},
+		{"fees differ", newRawTx(owned, declared, "0.00002"), true},
+	}
+
+	tampered := newRawTx(owned, declared, "0.00001")
+	tampered.Signatures["account"][0].Message = hex.EncodeToString([]byte("arbitrary message"))
+	tests = append(tests, struct {
+		name    string
+		rawTx   *openwallet.RawTransaction
+		wantErr bool
+	}{"tampered message", tampered, true})
+
+	for _, tt := range tests {
+		err := decoder.VerifyVLXSignIntent(wrapper, tt.rawTx)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: VerifyVLXSignIntent() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
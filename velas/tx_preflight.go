@@ -0,0 +1,129 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/blocktree/openwallet/openwallet"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+//InputsSpentError 交易单的输入已不在节点的未花列表中，需要重新构建交易单
+type InputsSpentError struct {
+	TxID      string
+	Outpoints []string //格式：{hash}:{index}
+}
+
+func (e *InputsSpentError) Error() string {
+	return fmt.Sprintf("transaction %s inputs have been spent: %s", e.TxID, strings.Join(e.Outpoints, ", "))
+}
+
+//IsInputsSpentError 判断错误是否因交易单输入已被花费
+func IsInputsSpentError(err error) bool {
+	_, ok := err.(*InputsSpentError)
+	return ok
+}
+
+//PreflightVLXTransaction 广播前检查交易哈希是否正确，以及每个输入是否仍未花费
+func (decoder *TransactionDecoder) PreflightVLXTransaction(trx *crypto.Tx) error {
+
+	txid := hex.EncodeToString(trx.Hash[:])
+
+	if trx.GenerateHash() != trx.Hash {
+		return openwallet.Errorf(openwallet.ErrSubmitRawTransactionFailed, "transaction %s hash mismatch", txid)
+	}
+
+	//按地址查询一次未花列表
+	unspentsByAddress := make(map[string]map[string]bool)
+	spent := make([]string, 0)
+
+	for _, in := range trx.Inputs {
+		addr := base58.Encode(in.WalletAddress)
+		unspentSet, ok := unspentsByAddress[addr]
+		if !ok {
+			unspents, err := decoder.wm.WalletClient.Wallet.GetUnspent(addr)
+			if err != nil {
+				return openwallet.Errorf(openwallet.ErrCallFullNodeAPIFailed, "get unspent of %s failed, unexpected error: %v", addr, err)
+			}
+			unspentSet = make(map[string]bool)
+			for _, u := range unspents {
+				unspentSet[outpointID(u.Hash, u.Index)] = true
+			}
+			unspentsByAddress[addr] = unspentSet
+		}
+
+		id := outpointID(in.PreviousOutput.Hash, in.PreviousOutput.Index)
+		if !unspentSet[id] {
+			spent = append(spent, id)
+		}
+	}
+
+	if len(spent) > 0 {
+		return &InputsSpentError{TxID: txid, Outpoints: spent}
+	}
+
+	return nil
+}
+
+//RebuildVLXRawTransaction 使用原交易单的账户、接收地址及费率重新构建、签名及验证交易单
+//签名需要钱包已解锁
+func (decoder *TransactionDecoder) RebuildVLXRawTransaction(wrapper openwallet.WalletDAI, rawTx *openwallet.RawTransaction) error {
+
+	rawTx.TxID = ""
+	rawTx.RawHex = ""
+	rawTx.Signatures = nil
+	rawTx.IsBuilt = false
+	rawTx.IsCompleted = false
+	rawTx.IsSubmit = false
+	rawTx.Fees = ""
+	rawTx.TxAmount = ""
+	rawTx.TxFrom = nil
+	rawTx.TxTo = nil
+
+	err := decoder.CreateVLXRawTransaction(wrapper, rawTx)
+	if err != nil {
+		return err
+	}
+
+	err = decoder.SignVLXRawTransaction(wrapper, rawTx)
+	if err != nil {
+		return err
+	}
+
+	return decoder.VerifyVLXRawTransaction(wrapper, rawTx)
+}
+
+//SubmitVLXRawTransactionWithRebuild 广播交易单，输入已被花费时自动重建交易单并重试，最多重建maxRebuild次
+func (decoder *TransactionDecoder) SubmitVLXRawTransactionWithRebuild(wrapper openwallet.WalletDAI, rawTx *openwallet.RawTransaction, maxRebuild int) (*openwallet.Transaction, error) {
+
+	for i := 0; ; i++ {
+		tx, err := decoder.SubmitRawTransaction(wrapper, rawTx)
+		if err == nil || !IsInputsSpentError(err) || i >= maxRebuild {
+			return tx, err
+		}
+
+		decoder.wm.Log.Std.Warning("%v, rebuild transaction [%d/%d]", err, i+1, maxRebuild)
+
+		err = decoder.RebuildVLXRawTransaction(wrapper, rawTx)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
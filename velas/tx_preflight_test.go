@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/assetsadapterstore/velas-adapter/rpc"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+func TestTransactionDecoder_PreflightVLXTransaction(t *testing.T) {
+
+	addr := "VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty"
+	unspents := []*crypto.TransactionInputOutpoint{
+		{Hash: [32]byte{1}, Index: 0, Value: 100},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/wallet/unspent/"+addr {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(unspents)
+	}))
+	defer server.Close()
+
+	wm := NewWalletManager()
+	wm.WalletClient = rpc.NewClient(server.URL)
+	decoder := NewTransactionDecoder(wm)
+
+	newTx := func(outpoints ...crypto.TransactionInputOutpoint) *crypto.Tx {
+		trx := &crypto.Tx{Version: 1}
+		for _, o := range outpoints {
+			trx.Inputs = append(trx.Inputs, crypto.TransactionInput{
+				PreviousOutput: o,
+				WalletAddress:  base58.Decode(addr),
+			})
+		}
+		trx.Hash = trx.GenerateHash()
+		return trx
+	}
+
+	if err := decoder.PreflightVLXTransaction(newTx(*unspents[0])); err != nil {
+		t.Errorf("PreflightVLXTransaction() unexpected error = %v", err)
+	}
+
+	spent := newTx(*unspents[0], crypto.TransactionInputOutpoint{Hash: [32]byte{2}, Index: 1})
+	err := decoder.PreflightVLXTransaction(spent)
+	if !IsInputsSpentError(err) {
+		t.Fatalf("PreflightVLXTransaction() error = %v, want InputsSpentError", err)
+	}
+	if outpoints := err.(*InputsSpentError).Outpoints; len(outpoints) != 1 {
+		t.Errorf("InputsSpentError.Outpoints = %v, want 1 outpoint", outpoints)
+	}
+
+	tampered := newTx(*unspents[0])
+	tampered.LockTime = 1
+	err = decoder.PreflightVLXTransaction(tampered)
+	if err == nil || IsInputsSpentError(err) {
+		t.Errorf("PreflightVLXTransaction() error = %v, want hash mismatch", err)
+	}
+}
@@ -0,0 +1,249 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/assetsadapterstore/velas-adapter/txsigner"
+	"github.com/blocktree/go-owcrypt"
+	"github.com/blocktree/openwallet/common"
+	"github.com/blocktree/openwallet/openwallet"
+)
+
+//SweepRequest 从私钥归集资金的参数
+type SweepRequest struct {
+	PrivateKey string                    //WIF或hex格式的私钥，只在内存中使用，不会保存
+	To         string                    //目标地址，支持0x格式
+	FeeRate    string                    //为空时使用手续费模型的默认费率
+	Account    *openwallet.AssetsAccount //交易单记录所属的账户，可为空
+}
+
+//CreateSweepRawTransaction 查询私钥地址的全部未花，扣除手续费后转到目标地址
+//每笔交易单的输入数量不超过MaxTxInputs，超出时拆分为多笔；返回的交易单已签名，可直接SubmitRawTransaction
+func (decoder *TransactionDecoder) CreateSweepRawTransaction(req *SweepRequest) ([]*openwallet.RawTransaction, error) {
+
+	priv, err := decoder.decodeSweepKey(req.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	//签名完成后清除私钥
+	defer func() {
+		for i := range priv {
+			priv[i] = 0
+		}
+	}()
+
+	pub, ret := owcrypt.GenPubkey(priv, owcrypt.ECC_CURVE_ED25519)
+	if ret != owcrypt.SUCCESS {
+		return nil, fmt.Errorf("generate public key failed")
+	}
+	from, err := decoder.wm.Decoder.PublicKeyToAddress(pub, decoder.wm.Config.IsTestNet)
+	if err != nil {
+		return nil, err
+	}
+
+	to := req.To
+	if _, err := decoder.wm.Decoder.ValidateAddress(to); err != nil {
+		return nil, openwallet.Errorf(openwallet.ErrAdressDecodeFailed, "receiver %v", err)
+	}
+
+	account := req.Account
+	if account == nil {
+		account = &openwallet.AssetsAccount{}
+	}
+
+	unspents, err := decoder.wm.WalletClient.Wallet.GetUnspent(from)
+	if err != nil {
+		return nil, err
+	}
+
+	//排除已被其他交易单锁定的utxo
+	unspents, err = decoder.wm.FilterReservedUTXO(unspents)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(unspents) == 0 {
+		return nil, openwallet.Errorf(openwallet.ErrInsufficientBalanceOfAddress, "address %s has no unspent", from)
+	}
+
+	for _, u := range unspents {
+		if len(u.Address) == 0 {
+			u.Address = from
+		}
+	}
+
+	//金额大的utxo优先，零碎的utxo集中在最后一笔
+	sort.Slice(unspents, func(a, b int) bool {
+		return unspents[a].Value > unspents[b].Value
+	})
+
+	maxInputs := decoder.wm.Config.MaxTxInputs
+	if maxInputs <= 0 {
+		maxInputs = len(unspents)
+	}
+
+	rawTxArray := make([]*openwallet.RawTransaction, 0)
+	//已构建的交易单锁定的utxo，后续交易单失败时释放
+	reserved := crypto.Tx{}
+	for start := 0; start < len(unspents); start += maxInputs {
+		end := start + maxInputs
+		if end > len(unspents) {
+			end = len(unspents)
+		}
+
+		rawTx, err := decoder.createSweepRawTransaction(account, unspents[start:end], from, to, req.FeeRate, priv, pub)
+		if err != nil {
+			decoder.releaseUTXO(reserved)
+			return nil, err
+		}
+		if rawTx == nil {
+			decoder.wm.Log.Std.Warning("sweep %d utxo of %s are not enough to pay fees, skipped", end-start, from)
+			continue
+		}
+		for _, u := range unspents[start:end] {
+			reserved.Inputs = append(reserved.Inputs, crypto.TransactionInput{PreviousOutput: *u})
+		}
+		rawTxArray = append(rawTxArray, rawTx)
+	}
+
+	if len(rawTxArray) == 0 {
+		return nil, openwallet.Errorf(openwallet.ErrInsufficientFees, "balance of %s is not enough to pay fees", from)
+	}
+
+	return rawTxArray, nil
+}
+
+//createSweepRawTransaction 构建并签名一笔归集交易单，余额不足支付手续费时返回nil
+func (decoder *TransactionDecoder) createSweepRawTransaction(
+	account *openwallet.AssetsAccount,
+	unspents []*crypto.TransactionInputOutpoint,
+	from, to, feeRate string,
+	priv, pub []byte,
+) (*openwallet.RawTransaction, error) {
+
+	totalIn := uint64(0)
+	for _, u := range unspents {
+		totalIn += u.Value
+	}
+
+	fees, err := decoder.wm.FeeModel.EstimateFees(len(unspents), 1, feeRate)
+	if err != nil {
+		return nil, err
+	}
+	commission := uint64(fees.Shift(decoder.wm.Decimal()).IntPart())
+	if totalIn <= commission {
+		return nil, nil
+	}
+	sendAmount := totalIn - commission
+
+	trx, err := crypto.NewTransaction(unspents, map[string]uint64{to: sendAmount}, from, commission)
+	if err != nil {
+		return nil, openwallet.Errorf(openwallet.ErrCreateRawTransactionFailed, "create transaction failed, unexpected error: %v", err)
+	}
+
+	emptyTrans, err := trx.MarshalJSON()
+	if err != nil {
+		return nil, openwallet.Errorf(openwallet.ErrCreateRawTransactionFailed, "marshal transaction failed, unexpected error: %v", err)
+	}
+
+	//签名每个输入，地址的公钥只有一个
+	address := &openwallet.Address{AccountID: account.AccountID, Address: from, PublicKey: hex.EncodeToString(pub)}
+	keySigs := make([]*openwallet.KeySignature, 0, len(trx.Inputs))
+	sigPub := make([]txsigner.SigPub, 0, len(trx.Inputs))
+	for _, in := range trx.Inputs {
+		msg := trx.MsgForSign(in.PreviousOutput.Hash, in.PreviousOutput.Index)
+		signature, err := txsigner.Default.SignTransactionHash(msg, priv, owcrypt.ECC_CURVE_ED25519)
+		if err != nil {
+			return nil, openwallet.Errorf(openwallet.ErrSignRawTransactionFailed, "sign transaction failed, unexpected error: %v", err)
+		}
+		sigPub = append(sigPub, txsigner.SigPub{Signature: signature, Pubkey: pub})
+		keySigs = append(keySigs, &openwallet.KeySignature{
+			EccType:   owcrypt.ECC_CURVE_ED25519,
+			Address:   address,
+			Message:   hex.EncodeToString(msg),
+			Signature: hex.EncodeToString(signature),
+		})
+	}
+
+	pass, signedTrans, err := txsigner.Default.VerifyAndCombineTransaction(string(emptyTrans), sigPub)
+	if !pass {
+		return nil, openwallet.Errorf(openwallet.ErrVerifyRawTransactionFailed, "transaction verify failed, unexpected error: %v", err)
+	}
+
+	//锁定utxo，直到交易单广播失败、锁定超时或区块扫描确认花费
+	err = decoder.wm.ReserveUTXO(account.AccountID, unspents)
+	if err != nil {
+		return nil, openwallet.Errorf(openwallet.ErrCreateRawTransactionFailed, "reserve utxo failed, unexpected error: %v", err)
+	}
+
+	txFrom := make([]string, 0, len(unspents))
+	for _, u := range unspents {
+		txFrom = append(txFrom, fmt.Sprintf("%s:%s", from, common.IntToDecimals(int64(u.Value), decoder.wm.Decimal()).String()))
+	}
+	amount := common.IntToDecimals(int64(sendAmount), decoder.wm.Decimal())
+
+	rawTx := &openwallet.RawTransaction{
+		Coin:        openwallet.Coin{Symbol: decoder.wm.Symbol()},
+		Account:     account,
+		To:          map[string]string{to: amount.String()},
+		Fees:        fees.StringFixed(decoder.wm.Decimal()),
+		FeeRate:     feeRate,
+		RawHex:      signedTrans,
+		Signatures:  map[string][]*openwallet.KeySignature{account.AccountID: keySigs},
+		Required:    1,
+		IsBuilt:     true,
+		IsCompleted: true,
+		TxFrom:      txFrom,
+		TxTo:        []string{fmt.Sprintf("%s:%s", to, amount.String())},
+		//归集到账户的金额为正数
+		TxAmount: amount.StringFixed(decoder.wm.Decimal()),
+	}
+
+	return rawTx, nil
+}
+
+//decodeSweepKey 解析WIF或hex格式的私钥，私钥须为owcrypt使用的clamped标量
+func (decoder *TransactionDecoder) decodeSweepKey(key string) ([]byte, error) {
+	key = strings.TrimSpace(key)
+
+	var (
+		priv []byte
+		err  error
+	)
+	if raw, decodeErr := hex.DecodeString(key); decodeErr == nil && len(raw) == 32 {
+		priv = raw
+	} else {
+		priv, err = decoder.wm.Decoder.WIFToPrivateKey(key, decoder.wm.Config.IsTestNet)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %v", err)
+		}
+	}
+
+	if len(priv) != 32 {
+		return nil, fmt.Errorf("invalid private key length %d", len(priv))
+	}
+	//owcrypt签名时会clamp私钥，未clamp的私钥签名对应的是另一个公钥
+	if priv[0]&7 != 0 || priv[31]&0xC0 != 0x40 {
+		return nil, fmt.Errorf("private key is not a clamped ed25519 scalar")
+	}
+	return priv, nil
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/assetsadapterstore/velas-adapter/keystore"
+	"github.com/assetsadapterstore/velas-adapter/rpc"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/shopspring/decimal"
+)
+
+func TestTransactionDecoder_CreateSweepRawTransaction(t *testing.T) {
+
+	wm, cleanup := testReservationWalletManager(t)
+	defer cleanup()
+
+	key, err := keystore.NewKey(addrdec.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := "VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty"
+
+	unspents := []*crypto.TransactionInputOutpoint{
+		{Hash: [32]byte{1}, Index: 0, Value: 300000000},
+		{Hash: [32]byte{2}, Index: 1, Value: 200000000},
+		{Hash: [32]byte{3}, Index: 0, Value: 100000000},
+		{Hash: [32]byte{4}, Index: 2, Value: 10},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/wallet/unspent/"+key.Address {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(unspents)
+	}))
+	defer server.Close()
+
+	wm.WalletClient = rpc.NewClient(server.URL)
+	wm.Config.MaxTxInputs = 3
+	decoder := NewTransactionDecoder(wm)
+
+	wif, err := wm.Decoder.PrivateKeyToWIF(key.PrivateKey, wm.Config.IsTestNet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeRate := "0.001"
+	fees, _ := decimal.NewFromString(feeRate)
+	commission := uint64(fees.Shift(wm.Decimal()).IntPart())
+
+	//最后一笔只有零碎的utxo，不足以支付手续费，被跳过
+	rawTxs, err := decoder.CreateSweepRawTransaction(&SweepRequest{PrivateKey: wif, To: to, FeeRate: feeRate})
+	if err != nil {
+		t.Fatalf("CreateSweepRawTransaction() unexpected error = %v", err)
+	}
+	if len(rawTxs) != 1 {
+		t.Fatalf("CreateSweepRawTransaction() returned %d transactions, want 1", len(rawTxs))
+	}
+
+	rawTx := rawTxs[0]
+	if !rawTx.IsCompleted || len(rawTx.RawHex) == 0 || len(rawTx.TxFrom) != 3 {
+		t.Fatalf("CreateSweepRawTransaction() = %+v, want a signed transaction with 3 inputs", rawTx)
+	}
+
+	signed, err := hex.DecodeString(rawTx.RawHex)
+	if err != nil {
+		t.Fatalf("RawHex is not hex encoded: %v", err)
+	}
+	var trx crypto.Tx
+	if err := json.Unmarshal(signed, &trx); err != nil {
+		t.Fatalf("RawHex is not a transaction: %v", err)
+	}
+	sent := uint64(0)
+	for _, out := range trx.Outputs {
+		if base58.Encode(out.Script) == to {
+			sent += out.Value
+		}
+	}
+	if want := uint64(600000000) - commission; sent != want {
+		t.Errorf("sweep amount = %d, want %d", sent, want)
+	}
+	for _, in := range trx.Inputs {
+		if len(in.Script) == 0 || hex.EncodeToString(in.PublicKey) != hex.EncodeToString(key.PublicKey) {
+			t.Errorf("input %x:%d is not signed by the swept key", in.PreviousOutput.Hash, in.PreviousOutput.Index)
+		}
+	}
+
+	//已锁定的utxo不会被再次归集
+	if _, err := decoder.CreateSweepRawTransaction(&SweepRequest{PrivateKey: hex.EncodeToString(key.PrivateKey), To: to, FeeRate: feeRate}); err == nil {
+		t.Errorf("CreateSweepRawTransaction() of reserved utxo want error")
+	}
+
+	if _, err := decoder.CreateSweepRawTransaction(&SweepRequest{PrivateKey: "invalid", To: to}); err == nil {
+		t.Errorf("CreateSweepRawTransaction() with invalid key want error")
+	}
+
+	//后续交易单失败时，释放已构建的交易单锁定的utxo
+	if err := wm.ReleaseUTXO(trx.Inputs); err != nil {
+		t.Fatal(err)
+	}
+	unspents = []*crypto.TransactionInputOutpoint{
+		{Hash: [32]byte{5}, Index: 0, Value: 300000000},
+		{Hash: [32]byte{6}, Index: 0, Value: 200000000},
+		{Hash: [32]byte{5}, Index: 0, Value: 100000000}, //节点重复返回的utxo，锁定失败
+	}
+	wm.Config.MaxTxInputs = 1
+	if _, err := decoder.CreateSweepRawTransaction(&SweepRequest{PrivateKey: wif, To: to, FeeRate: feeRate}); err == nil {
+		t.Fatalf("CreateSweepRawTransaction() with duplicated utxo want error")
+	}
+	available, err := wm.FilterReservedUTXO(unspents[:2])
+	if err != nil || len(available) != 2 {
+		t.Errorf("FilterReservedUTXO() after failed sweep = %d utxo, error = %v, want all released", len(available), err)
+	}
+}
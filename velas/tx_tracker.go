@@ -0,0 +1,383 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/assetsadapterstore/velas-adapter/rpc"
+	"github.com/blocktree/openwallet/timer"
+)
+
+const (
+	TxStatusPending   = "pending"   //已广播，未上链
+	TxStatusConfirmed = "confirmed" //已上链
+	TxStatusDropped   = "dropped"   //广播后超时未上链，包括仍在节点交易池中的交易单
+	TxStatusFailed    = "failed"    //从节点消失超过MissingTimeout，输入已被其他交易花费
+
+	periodOfTrackTask = 30 * time.Second //跟踪任务执行间隔
+)
+
+//TrackedTransaction 已广播交易单的跟踪记录
+type TrackedTransaction struct {
+	TxID              string `storm:"id"`
+	AccountID         string
+	RawHex            string //签名后的交易单，用于重新广播
+	Status            string `storm:"index"`
+	BlockHash         string
+	Confirmed         uint32
+	Rebroadcasts      int
+	Reason            string
+	SubmitTime        int64
+	LastSeenTime      int64 //最后一次在节点查询到交易单的时间
+	LastBroadcastTime int64 //最后一次广播交易单的时间
+	UpdateTime        int64
+}
+
+//TxTrackerObserver 交易单状态变化的观测者
+type TxTrackerObserver interface {
+	//TxStatusNotify 交易单状态发生变化时通知
+	TxStatusNotify(tx *TrackedTransaction) error
+}
+
+//TxTracker 跟踪已广播的交易单是否上链，交易单从节点消失时重新广播，超时标记为丢弃或失败
+//LoadAssetsConfig会启动跟踪任务，未加载配置时需调用Run启动
+type TxTracker struct {
+	Mu                  sync.RWMutex
+	Observers           map[TxTrackerObserver]bool //观察者
+	PeriodOfTask        time.Duration
+	DropTimeout         time.Duration //广播后超过该时长仍在交易池中未上链，标记为丢弃
+	MissingTimeout      time.Duration //从节点查询不到超过该时长，标记为丢弃或失败，应长于DropTimeout
+	RebroadcastInterval time.Duration //重新广播的最小间隔
+	trackTask           *timer.TaskTimer
+	wm                  *WalletManager
+}
+
+//NewTxTracker 创建交易单跟踪器
+func NewTxTracker(wm *WalletManager) *TxTracker {
+	tracker := TxTracker{}
+	tracker.wm = wm
+	tracker.Observers = make(map[TxTrackerObserver]bool)
+	tracker.PeriodOfTask = periodOfTrackTask
+	tracker.DropTimeout = wm.Config.TxDropTimeout
+	tracker.MissingTimeout = wm.Config.TxMissingTimeout
+	tracker.RebroadcastInterval = wm.Config.TxRebroadcastInterval
+	return &tracker
+}
+
+//AddObserver 添加观测者
+func (tracker *TxTracker) AddObserver(obj TxTrackerObserver) {
+	tracker.Mu.Lock()
+	defer tracker.Mu.Unlock()
+	if obj == nil {
+		return
+	}
+	tracker.Observers[obj] = true
+}
+
+//RemoveObserver 移除观测者
+func (tracker *TxTracker) RemoveObserver(obj TxTrackerObserver) {
+	tracker.Mu.Lock()
+	defer tracker.Mu.Unlock()
+	delete(tracker.Observers, obj)
+}
+
+//Run 运行跟踪任务
+func (tracker *TxTracker) Run() {
+	if tracker.trackTask != nil && tracker.trackTask.Running() {
+		return
+	}
+	tracker.trackTask = timer.NewTask(tracker.PeriodOfTask, tracker.TrackTask)
+	tracker.trackTask.Start()
+}
+
+//Stop 停止跟踪任务
+func (tracker *TxTracker) Stop() {
+	if tracker.trackTask != nil {
+		tracker.trackTask.Stop()
+	}
+}
+
+//Track 记录已广播的交易单
+func (tracker *TxTracker) Track(accountID string, trx *crypto.Tx) error {
+
+	txJSON, err := trx.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	tx := &TrackedTransaction{
+		TxID:              hex.EncodeToString(trx.Hash[:]),
+		AccountID:         accountID,
+		RawHex:            hex.EncodeToString(txJSON),
+		Status:            TxStatusPending,
+		SubmitTime:        now,
+		LastSeenTime:      now,
+		LastBroadcastTime: now,
+		UpdateTime:        now,
+	}
+
+	db, err := storm.Open(filepath.Join(tracker.wm.Config.dbPath, tracker.wm.Config.BlockchainFile))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Save(tx)
+}
+
+//GetTrackedTransactions 获取指定状态的跟踪记录，status为空时返回全部
+func (tracker *TxTracker) GetTrackedTransactions(status string) ([]*TrackedTransaction, error) {
+
+	db, err := storm.Open(filepath.Join(tracker.wm.Config.dbPath, tracker.wm.Config.BlockchainFile))
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var list []*TrackedTransaction
+	if len(status) == 0 {
+		err = db.All(&list)
+	} else {
+		err = db.Find("Status", status, &list)
+	}
+	if err != nil && err != storm.ErrNotFound {
+		return nil, err
+	}
+	return list, nil
+}
+
+//GetStuckTransactions 广播后超过olderThan仍未上链的交易单
+func (tracker *TxTracker) GetStuckTransactions(olderThan time.Duration) ([]*TrackedTransaction, error) {
+
+	pending, err := tracker.GetTrackedTransactions(TxStatusPending)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(-olderThan).Unix()
+	stuck := make([]*TrackedTransaction, 0)
+	for _, tx := range pending {
+		if tx.SubmitTime <= deadline {
+			stuck = append(stuck, tx)
+		}
+	}
+	return stuck, nil
+}
+
+//DeleteTrackedTransaction 删除跟踪记录
+func (tracker *TxTracker) DeleteTrackedTransaction(txid string) error {
+
+	db, err := storm.Open(filepath.Join(tracker.wm.Config.dbPath, tracker.wm.Config.BlockchainFile))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.DeleteStruct(&TrackedTransaction{TxID: txid})
+}
+
+//TrackTask 跟踪任务，查询未上链交易单的最新状态
+func (tracker *TxTracker) TrackTask() {
+
+	pending, err := tracker.GetTrackedTransactions(TxStatusPending)
+	if err != nil {
+		tracker.wm.Log.Std.Error("tx tracker can not get pending transactions; unexpected error: %v", err)
+		return
+	}
+
+	if len(pending) == 0 {
+		return
+	}
+
+	txids := make([]string, 0, len(pending))
+	for _, tx := range pending {
+		txids = append(txids, tx.TxID)
+	}
+
+	result, err := tracker.wm.WalletClient.Tx.GetByHashList(txids...)
+	if err != nil {
+		tracker.wm.Log.Std.Info("tx tracker can not get transactions; unexpected error: %v", err)
+		return
+	}
+
+	found := make(map[string]int)
+	for i, r := range result {
+		if r.Tx == nil {
+			continue
+		}
+		found[hex.EncodeToString(r.Hash[:])] = i
+	}
+
+	now := time.Now()
+	for _, tx := range pending {
+		status := tx.Status
+		if i, ok := found[tx.TxID]; ok {
+			tracker.handleFound(tx, &result[i], now)
+		} else {
+			tracker.handleMissing(tx, now)
+		}
+		tx.UpdateTime = now.Unix()
+
+		err = tracker.save(tx)
+		if err != nil {
+			tracker.wm.Log.Std.Error("tx tracker can not save transaction: %s; unexpected error: %v", tx.TxID, err)
+			continue
+		}
+
+		if status != tx.Status {
+			tracker.wm.Log.Std.Info("tracked transaction: %s status changed: %s -> %s", tx.TxID, status, tx.Status)
+			tracker.statusNotify(tx)
+		}
+	}
+}
+
+//handleFound 节点查询到交易单，已打包时标记为已上链，仍在交易池中超时同样标记为丢弃
+func (tracker *TxTracker) handleFound(tx *TrackedTransaction, r *rpc.TxResponse, now time.Time) {
+	tx.LastSeenTime = now.Unix()
+	tx.Confirmed = r.Confirmed
+	tx.BlockHash = r.Block
+	if len(r.Block) > 0 || r.Confirmed > 0 {
+		tx.Status = TxStatusConfirmed
+		return
+	}
+	tracker.handleTimeout(tx, now)
+}
+
+//handleMissing 交易单从节点消失时按哈希再次查询，仍查询不到时重新广播
+//输入已被花费可能是交易单在两次查询之间上链或节点落后，查询不到超过MissingTimeout才标记为失败，之前不释放utxo
+func (tracker *TxTracker) handleMissing(tx *TrackedTransaction, now time.Time) {
+
+	trx, err := tx.Tx()
+	if err != nil {
+		tx.Status = TxStatusFailed
+		tx.Reason = err.Error()
+		return
+	}
+
+	result, err := tracker.wm.WalletClient.Tx.GetByHashList(tx.TxID)
+	if err != nil {
+		tracker.wm.Log.Std.Info("tx tracker can not get transaction: %s; unexpected error: %v", tx.TxID, err)
+		return
+	}
+	for i, r := range result {
+		if r.Tx != nil && hex.EncodeToString(r.Hash[:]) == tx.TxID {
+			tracker.handleFound(tx, &result[i], now)
+			return
+		}
+	}
+
+	decoder := NewTransactionDecoder(tracker.wm)
+	spentErr := decoder.PreflightVLXTransaction(trx)
+
+	if now.Unix()-tx.LastSeenTime >= int64(tracker.MissingTimeout/time.Second) {
+		if IsInputsSpentError(spentErr) {
+			tx.Status = TxStatusFailed
+			tx.Reason = spentErr.Error()
+		} else {
+			tx.Status = TxStatusDropped
+			tx.Reason = fmt.Sprintf("transaction is missing from node for %v", tracker.MissingTimeout)
+		}
+		tracker.releaseUTXO(trx)
+		return
+	}
+
+	//输入已被花费时不重新广播，等待节点返回交易单或超时
+	if IsInputsSpentError(spentErr) {
+		tx.Reason = spentErr.Error()
+		return
+	}
+
+	if now.Unix()-tx.LastBroadcastTime < int64(tracker.RebroadcastInterval/time.Second) {
+		return
+	}
+
+	tx.Rebroadcasts++
+	tx.LastBroadcastTime = now.Unix()
+	_, err = tracker.wm.WalletClient.Tx.Publish(*trx)
+	if err != nil {
+		tx.Reason = err.Error()
+		tracker.wm.Log.Std.Info("tx tracker rebroadcast transaction: %s failed; unexpected error: %v", tx.TxID, err)
+	}
+}
+
+//handleTimeout 广播后超过DropTimeout仍在交易池中未上链时标记为丢弃并释放utxo
+func (tracker *TxTracker) handleTimeout(tx *TrackedTransaction, now time.Time) {
+
+	if now.Unix()-tx.SubmitTime < int64(tracker.DropTimeout/time.Second) {
+		return
+	}
+
+	tx.Status = TxStatusDropped
+	tx.Reason = fmt.Sprintf("transaction is not confirmed after %v", tracker.DropTimeout)
+	trx, err := tx.Tx()
+	if err == nil {
+		tracker.releaseUTXO(trx)
+	}
+}
+
+func (tracker *TxTracker) releaseUTXO(trx *crypto.Tx) {
+	err := tracker.wm.ReleaseUTXO(trx.Inputs)
+	if err != nil {
+		tracker.wm.Log.Std.Error("tx tracker can not release utxo; unexpected error: %v", err)
+	}
+}
+
+func (tracker *TxTracker) save(tx *TrackedTransaction) error {
+
+	db, err := storm.Open(filepath.Join(tracker.wm.Config.dbPath, tracker.wm.Config.BlockchainFile))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Save(tx)
+}
+
+//statusNotify 通知观测者交易单状态变化
+func (tracker *TxTracker) statusNotify(tx *TrackedTransaction) {
+	tracker.Mu.RLock()
+	defer tracker.Mu.RUnlock()
+
+	for o := range tracker.Observers {
+		err := o.TxStatusNotify(tx)
+		if err != nil {
+			tracker.wm.Log.Error("TxStatusNotify unexpected error:", err)
+		}
+	}
+}
+
+//Tx 解析签名后的交易单
+func (tx *TrackedTransaction) Tx() (*crypto.Tx, error) {
+	rawHex, err := hex.DecodeString(tx.RawHex)
+	if err != nil {
+		return nil, err
+	}
+	var trx crypto.Tx
+	err = json.Unmarshal(rawHex, &trx)
+	if err != nil {
+		return nil, err
+	}
+	return &trx, nil
+}
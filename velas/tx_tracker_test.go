@@ -0,0 +1,208 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+	"github.com/assetsadapterstore/velas-adapter/rpc"
+	"github.com/astaxie/beego/config"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+type testTxObserver struct {
+	notified []string
+}
+
+func (o *testTxObserver) TxStatusNotify(tx *TrackedTransaction) error {
+	o.notified = append(o.notified, tx.Status)
+	return nil
+}
+
+func TestTxTracker_TrackTask(t *testing.T) {
+
+	addr := "VLbnXBwLzMREw5NUmm1HcrEDinUiXKMgZty"
+	outpoint := crypto.TransactionInputOutpoint{Hash: [32]byte{1}, Index: 0, Value: 100}
+	trx := &crypto.Tx{Version: 1, Inputs: []crypto.TransactionInput{
+		{PreviousOutput: outpoint, WalletAddress: base58.Decode(addr)},
+	}}
+	trx.Hash = trx.GenerateHash()
+	txid := hex.EncodeToString(trx.Hash[:])
+
+	var (
+		mined     bool
+		inPool    bool
+		spent     bool
+		lagging   int
+		published int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/txs":
+			txs := make([]map[string]interface{}, 0)
+			if lagging > 0 {
+				lagging--
+			} else if mined {
+				txs = append(txs, map[string]interface{}{"hash": txid, "block": "blockhash", "confirmed": 1})
+			} else if inPool {
+				txs = append(txs, map[string]interface{}{"hash": txid})
+			}
+			json.NewEncoder(w).Encode(txs)
+		case "/api/v1/txs/publish":
+			published++
+			json.NewEncoder(w).Encode(rpc.TxPublishResponse{Result: "ok"})
+		case "/api/v1/wallet/unspent/" + addr:
+			unspent := []*crypto.TransactionInputOutpoint{&outpoint}
+			if spent {
+				unspent = unspent[:0]
+			}
+			json.NewEncoder(w).Encode(unspent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	wm, cleanup := testReservationWalletManager(t)
+	defer cleanup()
+	wm.WalletClient = rpc.NewClient(server.URL)
+
+	observer := &testTxObserver{}
+	tracker := wm.TxTracker
+	tracker.AddObserver(observer)
+	tracker.RebroadcastInterval = 0
+
+	if err := tracker.Track("account", trx); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+
+	//交易单从节点消失，重新广播
+	tracker.TrackTask()
+	if published != 1 {
+		t.Errorf("rebroadcast count = %d, want 1", published)
+	}
+	stuck, _ := tracker.GetStuckTransactions(0)
+	if len(stuck) != 1 || stuck[0].Rebroadcasts != 1 {
+		t.Fatalf("GetStuckTransactions() = %v, want 1 pending transaction", stuck)
+	}
+
+	//交易单上链
+	mined = true
+	tracker.TrackTask()
+	confirmed, _ := tracker.GetTrackedTransactions(TxStatusConfirmed)
+	if len(confirmed) != 1 || confirmed[0].BlockHash != "blockhash" {
+		t.Fatalf("GetTrackedTransactions(confirmed) = %v", confirmed)
+	}
+	if len(observer.notified) != 1 || observer.notified[0] != TxStatusConfirmed {
+		t.Errorf("observer notified = %v", observer.notified)
+	}
+
+	//批量查询漏掉交易单，按哈希再次查询到已上链
+	lagging = 1
+	published = 0
+	tracker.DeleteTrackedTransaction(txid)
+	tracker.Track("account", trx)
+	tracker.TrackTask()
+	confirmed, _ = tracker.GetTrackedTransactions(TxStatusConfirmed)
+	if len(confirmed) != 1 || published != 0 {
+		t.Fatalf("GetTrackedTransactions(confirmed) of lagging node = %v, rebroadcast %d", confirmed, published)
+	}
+
+	//查询不到且输入已被花费，未超过MissingTimeout时保持待确认，不释放utxo
+	mined = false
+	spent = true
+	if err := wm.ReserveUTXO("account", []*crypto.TransactionInputOutpoint{&outpoint}); err != nil {
+		t.Fatalf("ReserveUTXO() error = %v", err)
+	}
+	tracker.DeleteTrackedTransaction(txid)
+	tracker.Track("account", trx)
+	tracker.TrackTask()
+	if stuck, _ := tracker.GetStuckTransactions(0); len(stuck) != 1 || published != 0 {
+		t.Fatalf("GetStuckTransactions() of spent transaction = %v, rebroadcast %d", stuck, published)
+	}
+	if err := wm.ReserveUTXO("account", []*crypto.TransactionInputOutpoint{&outpoint}); err == nil {
+		t.Errorf("ReserveUTXO() of spent transaction error = nil, want utxo still reserved")
+	}
+
+	//查询不到超过MissingTimeout且输入已被花费，标记为失败
+	tracker.MissingTimeout = -time.Second
+	tracker.TrackTask()
+	failed, _ := tracker.GetTrackedTransactions(TxStatusFailed)
+	if len(failed) != 1 {
+		t.Fatalf("GetTrackedTransactions(failed) = %v", failed)
+	}
+
+	//查询不到超过MissingTimeout，标记为丢弃
+	spent = false
+	tracker.DeleteTrackedTransaction(txid)
+	tracker.Track("account", trx)
+	tracker.TrackTask()
+	dropped, _ := tracker.GetTrackedTransactions(TxStatusDropped)
+	if len(dropped) != 1 {
+		t.Fatalf("GetTrackedTransactions(dropped) = %v", dropped)
+	}
+
+	//仍在交易池中，超时同样标记为丢弃，不再重新广播
+	inPool = true
+	published = 0
+	tracker.DropTimeout = -time.Second
+	tracker.DeleteTrackedTransaction(txid)
+	tracker.Track("account", trx)
+	tracker.TrackTask()
+	dropped, _ = tracker.GetTrackedTransactions(TxStatusDropped)
+	if len(dropped) != 1 || published != 0 {
+		t.Fatalf("GetTrackedTransactions(dropped) of transaction in pool = %v, rebroadcast %d", dropped, published)
+	}
+	if err := wm.ReserveUTXO("account", []*crypto.TransactionInputOutpoint{&outpoint}); err != nil {
+		t.Errorf("ReserveUTXO() after drop error = %v, want utxo released", err)
+	}
+
+	//未超时，交易池中的交易单保持待确认
+	tracker.DropTimeout = time.Hour
+	tracker.DeleteTrackedTransaction(txid)
+	tracker.Track("account", trx)
+	tracker.TrackTask()
+	if stuck, _ := tracker.GetStuckTransactions(0); len(stuck) != 1 || published != 0 {
+		t.Errorf("GetStuckTransactions() of transaction in pool = %v, rebroadcast %d", stuck, published)
+	}
+}
+
+func TestTxTracker_RunByConfig(t *testing.T) {
+
+	dataDir, err := ioutil.TempDir("", "velas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+
+	wm := NewWalletManager()
+	c, _ := config.NewConfigData("ini", []byte("dataDir = "+dataDir))
+	if err := wm.LoadAssetsConfig(c); err != nil {
+		t.Fatalf("LoadAssetsConfig() unexpected error = %v", err)
+	}
+	defer wm.TxTracker.Stop()
+	if task := wm.TxTracker.trackTask; task == nil || !task.Running() {
+		t.Errorf("LoadAssetsConfig() did not start tx tracker")
+	}
+}
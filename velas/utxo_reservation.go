@@ -0,0 +1,164 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+)
+
+//ReserveUTXO 锁定交易单使用的utxo，任一utxo已被其他交易单锁定时，全部不锁定并返回错误
+func (wm *WalletManager) ReserveUTXO(accountID string, utxos []*crypto.TransactionInputOutpoint) error {
+
+	wm.reserveMu.Lock()
+	defer wm.reserveMu.Unlock()
+
+	db, err := storm.Open(filepath.Join(wm.Config.dbPath, wm.Config.BlockchainFile))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+	for _, utxo := range utxos {
+		var reservation UTXOReservation
+		id := outpointID(utxo.Hash, utxo.Index)
+		err = tx.One("ID", id, &reservation)
+		if err == nil && !reservation.IsExpired(now) {
+			return fmt.Errorf("utxo %s is %s by another transaction", id, reservation.Status)
+		}
+		if err != nil && err != storm.ErrNotFound {
+			return err
+		}
+	}
+
+	for _, utxo := range utxos {
+		err = tx.Save(NewUTXOReservation(utxo, accountID, wm.Config.UTXOReserveTimeout))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+//FilterReservedUTXO 过滤已被锁定或已确认花费的utxo，同时清理超时的锁定记录
+func (wm *WalletManager) FilterReservedUTXO(utxos []*crypto.TransactionInputOutpoint) ([]*crypto.TransactionInputOutpoint, error) {
+
+	wm.reserveMu.Lock()
+	defer wm.reserveMu.Unlock()
+
+	db, err := storm.Open(filepath.Join(wm.Config.dbPath, wm.Config.BlockchainFile))
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var list []*UTXOReservation
+	err = db.All(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	reserved := make(map[string]bool)
+	for _, r := range list {
+		if r.IsExpired(now) {
+			db.DeleteStruct(r)
+			continue
+		}
+		reserved[r.ID] = true
+	}
+
+	available := make([]*crypto.TransactionInputOutpoint, 0, len(utxos))
+	for _, utxo := range utxos {
+		if reserved[outpointID(utxo.Hash, utxo.Index)] {
+			continue
+		}
+		available = append(available, utxo)
+	}
+
+	return available, nil
+}
+
+//ReleaseUTXO 交易单广播失败后，释放锁定的utxo
+func (wm *WalletManager) ReleaseUTXO(inputs []crypto.TransactionInput) error {
+
+	wm.reserveMu.Lock()
+	defer wm.reserveMu.Unlock()
+
+	db, err := storm.Open(filepath.Join(wm.Config.dbPath, wm.Config.BlockchainFile))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, in := range inputs {
+		var reservation UTXOReservation
+		err = db.One("ID", outpointID(in.PreviousOutput.Hash, in.PreviousOutput.Index), &reservation)
+		if err != nil {
+			continue
+		}
+		if reservation.Status == UTXOStatusReserved {
+			db.DeleteStruct(&reservation)
+		}
+	}
+
+	return nil
+}
+
+//ConfirmSpentUTXO 区块扫描发现花费交易后，标记已锁定的utxo为已花费，超时后清理
+func (wm *WalletManager) ConfirmSpentUTXO(txs []*crypto.Tx) error {
+
+	wm.reserveMu.Lock()
+	defer wm.reserveMu.Unlock()
+
+	db, err := storm.Open(filepath.Join(wm.Config.dbPath, wm.Config.BlockchainFile))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, trx := range txs {
+		if trx == nil {
+			continue
+		}
+		for _, in := range trx.Inputs {
+			var reservation UTXOReservation
+			err = db.One("ID", outpointID(in.PreviousOutput.Hash, in.PreviousOutput.Index), &reservation)
+			if err != nil {
+				continue
+			}
+			reservation.Status = UTXOStatusSpent
+			err = db.Save(&reservation)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
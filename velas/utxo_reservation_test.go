@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018 The openwallet Authors
+ * This file is part of the openwallet library.
+ *
+ * The openwallet library is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The openwallet library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+ * GNU Lesser General Public License for more details.
+ */
+
+package velas
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/assetsadapterstore/velas-adapter/crypto"
+)
+
+func testReservationWalletManager(t *testing.T) (*WalletManager, func()) {
+	dir, err := ioutil.TempDir("", "vlx-reservation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wm := NewWalletManager()
+	wm.Config.DataDir = dir
+	wm.Config.makeDataDir()
+	return wm, func() { os.RemoveAll(dir) }
+}
+
+func TestWalletManager_ReserveUTXO(t *testing.T) {
+	wm, cleanup := testReservationWalletManager(t)
+	defer cleanup()
+
+	utxos := []*crypto.TransactionInputOutpoint{
+		{Hash: [32]byte{1}, Index: 0, Value: 100, Address: "a"},
+		{Hash: [32]byte{1}, Index: 1, Value: 200, Address: "a"},
+		{Hash: [32]byte{2}, Index: 0, Value: 300, Address: "b"},
+	}
+
+	if err := wm.ReserveUTXO("account", utxos[:2]); err != nil {
+		t.Fatalf("ReserveUTXO() error = %v", err)
+	}
+
+	if err := wm.ReserveUTXO("account", utxos[1:]); err == nil {
+		t.Fatalf("ReserveUTXO() reserved utxo twice")
+	}
+
+	available, err := wm.FilterReservedUTXO(utxos)
+	if err != nil {
+		t.Fatalf("FilterReservedUTXO() error = %v", err)
+	}
+	if len(available) != 1 || available[0] != utxos[2] {
+		t.Fatalf("FilterReservedUTXO() = %v, want only unreserved utxo", available)
+	}
+
+	trx := crypto.Tx{Inputs: []crypto.TransactionInput{
+		{PreviousOutput: *utxos[0]},
+		{PreviousOutput: *utxos[1]},
+	}}
+
+	//已花费的utxo不会被释放
+	if err := wm.ConfirmSpentUTXO([]*crypto.Tx{&crypto.Tx{Inputs: trx.Inputs[:1]}}); err != nil {
+		t.Fatalf("ConfirmSpentUTXO() error = %v", err)
+	}
+	if err := wm.ReleaseUTXO(trx.Inputs); err != nil {
+		t.Fatalf("ReleaseUTXO() error = %v", err)
+	}
+
+	available, _ = wm.FilterReservedUTXO(utxos)
+	if len(available) != 2 || available[0] != utxos[1] {
+		t.Fatalf("FilterReservedUTXO() = %v after release", available)
+	}
+}
+
+func TestWalletManager_ReserveUTXOTimeout(t *testing.T) {
+	wm, cleanup := testReservationWalletManager(t)
+	defer cleanup()
+
+	wm.Config.UTXOReserveTimeout = -time.Second
+
+	utxos := []*crypto.TransactionInputOutpoint{
+		{Hash: [32]byte{1}, Index: 0, Value: 100, Address: "a"},
+	}
+	if err := wm.ReserveUTXO("account", utxos); err != nil {
+		t.Fatalf("ReserveUTXO() error = %v", err)
+	}
+
+	available, err := wm.FilterReservedUTXO(utxos)
+	if err != nil {
+		t.Fatalf("FilterReservedUTXO() error = %v", err)
+	}
+	if len(available) != 1 {
+		t.Fatalf("expired reservation still locks utxo")
+	}
+}
@@ -16,6 +16,11 @@
 package velas
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/assetsadapterstore/velas-adapter/addrdec"
 	"github.com/assetsadapterstore/velas-adapter/rpc"
 	"github.com/astaxie/beego/config"
 	"github.com/blocktree/openwallet/log"
@@ -39,14 +44,28 @@ func (wm *WalletManager) Symbol() string {
 
 //小数位精度
 func (wm *WalletManager) Decimal() int32 {
-	return 8
+	if wm.IsEVM() {
+		return EVMDecimals
+	}
+	return wm.Config.ChainParams.Decimals
 }
 
 //AddressDecode 地址解析器
 func (wm *WalletManager) GetAddressDecode() openwallet.AddressDecoder {
+	if wm.IsEVM() {
+		return wm.EVMDecoder
+	}
 	return wm.Decoder
 }
 
+//GetAddressDecoderV2 地址解析器V2
+func (wm *WalletManager) GetAddressDecoderV2() openwallet.AddressDecoderV2 {
+	if wm.IsEVM() {
+		return wm.EVMDecoder
+	}
+	return wm.DecoderV2
+}
+
 //TransactionDecoder 交易单解析器
 func (wm *WalletManager) GetTransactionDecoder() openwallet.TransactionDecoder {
 	return wm.TxDecoder
@@ -54,20 +73,120 @@ func (wm *WalletManager) GetTransactionDecoder() openwallet.TransactionDecoder {
 
 //GetBlockScanner 获取区块链
 func (wm *WalletManager) GetBlockScanner() openwallet.BlockScanner {
+	if wm.IsEVM() {
+		return wm.EVMBlockscanner
+	}
 	return wm.Blockscanner
 }
 
 //LoadAssetsConfig 加载外部配置
 func (wm *WalletManager) LoadAssetsConfig(c config.Configer) error {
 
-	wm.Config.ServerAPI = c.String("serverAPI")
+	//网络参数，未配置network时兼容isTestNet
 	wm.Config.IsTestNet, _ = c.Bool("isTestNet")
+	network := c.String("network")
+	if len(network) == 0 {
+		network = addrdec.NetworkMainnet
+		if wm.Config.IsTestNet {
+			network = addrdec.NetworkTestnet
+		}
+	}
+	params, err := addrdec.ParamsByName(network)
+	if err != nil {
+		return err
+	}
+	//私有链可配置hex编码的前缀，默认与主网相同
+	params, err = params.WithPrefix(c.String("addressPrefix"), c.String("wifPrefix"))
+	if err != nil {
+		return err
+	}
+	wm.Config.Network = params.Name
+	wm.Config.ChainParams = params
+	wm.Config.IsTestNet = params.Name != addrdec.NetworkMainnet
+	wm.DecoderV2 = addrdec.NewAddressDecoderV2(params)
+
+	wm.Config.ServerAPI = c.DefaultString("serverAPI", params.NodeURL)
 	wm.WalletClient = rpc.NewClient(wm.Config.ServerAPI)
 	wm.Config.DataDir = c.String("dataDir")
-	wm.Config.FixFees = c.String("fixFees")
+	wm.Config.FixFees = c.DefaultString("fixFees", params.DefaultFee)
+	wm.Config.FeeMode = c.DefaultString("feeMode", FeeModeFlat)
+	wm.Config.FeeRate = c.DefaultString("feeRate", "0")
+	wm.Config.MinFees = c.DefaultString("minFees", "0")
+	wm.Config.MaxFeeRate = c.String("maxFeeRate")
+	wm.Config.FeeRejectionMessage = c.String("feeRejectionMessage")
+	wm.Config.VerifyBlock, _ = c.Bool("verifyBlock")
+	wm.Config.UTXOReserveTimeout = time.Duration(c.DefaultInt64("utxoReserveTimeout", 600)) * time.Second
+	wm.EVMNonce.Timeout = wm.Config.UTXOReserveTimeout
+	wm.Config.TxDropTimeout = time.Duration(c.DefaultInt64("txDropTimeout", 3600)) * time.Second
+	wm.Config.TxRebroadcastInterval = time.Duration(c.DefaultInt64("txRebroadcastInterval", 120)) * time.Second
+	wm.Config.TxMissingTimeout = time.Duration(c.DefaultInt64("txMissingTimeout", 7200)) * time.Second
+	wm.TxTracker.DropTimeout = wm.Config.TxDropTimeout
+	wm.TxTracker.MissingTimeout = wm.Config.TxMissingTimeout
+	wm.TxTracker.RebroadcastInterval = wm.Config.TxRebroadcastInterval
+	wm.Config.MonitorNodes = make([]string, 0)
+	for _, node := range strings.Split(c.String("monitorNodes"), ",") {
+		if node = strings.TrimSpace(node); len(node) > 0 {
+			wm.Config.MonitorNodes = append(wm.Config.MonitorNodes, node)
+		}
+	}
+	wm.Config.NodeStuckTimeout = time.Duration(c.DefaultInt64("nodeStuckTimeout", 600)) * time.Second
+	wm.Config.NodeMaxLag = uint64(c.DefaultInt64("nodeMaxLag", 10))
+	wm.NodeMonitor.StuckTimeout = wm.Config.NodeStuckTimeout
+	wm.NodeMonitor.MaxLag = wm.Config.NodeMaxLag
+	wm.NodeMonitor.SetEndpoints(append([]string{wm.Config.ServerAPI}, wm.Config.MonitorNodes...)...)
+	//配置了其他节点时启动检查任务
+	if len(wm.Config.MonitorNodes) > 0 {
+		if err := wm.NodeMonitor.Run(); err != nil {
+			return err
+		}
+	} else {
+		wm.NodeMonitor.Stop()
+	}
+
+	feeModel, err := NewFeeModel(wm.Config)
+	if err != nil {
+		return err
+	}
+	wm.FeeModel = feeModel
+
+	wm.Config.SignerMode = c.DefaultString("signerMode", SignerModeLocal)
+	wm.Config.RemoteSignerURL = c.String("remoteSignerURL")
+	wm.Config.RemoteSignerToken = c.String("remoteSignerToken")
+	wm.Config.RemoteSignerTimeout = time.Duration(c.DefaultInt64("remoteSignerTimeout", 30)) * time.Second
+	signer, err := NewSigner(wm.Config)
+	if err != nil {
+		return err
+	}
+	wm.Signer = signer
+
+	wm.Config.Backend = c.DefaultString("backend", BackendUTXO)
+	wm.Config.EVMServerAPI = c.String("evmServerAPI")
+	wm.Config.EVMChainID = c.DefaultInt64("evmChainID", 0)
+	wm.Config.EVMGasLimit = uint64(c.DefaultInt64("evmGasLimit", 21000))
+	wm.Config.EVMGasPrice = c.String("evmGasPrice")
+	wm.Config.EVMConfirmations = uint64(c.DefaultInt64("evmConfirmations", 1))
+	switch wm.Config.Backend {
+	case BackendUTXO:
+		//默认使用UTXO接口
+	case BackendEVM:
+		client, err := NewEVMClient(wm.Config)
+		if err != nil {
+			return err
+		}
+		wm.SetEVMBackend(client)
+	default:
+		return fmt.Errorf("unknown backend: %s", wm.Config.Backend)
+	}
 
 	//数据文件夹
 	wm.Config.makeDataDir()
+
+	//跟踪已广播的交易单，包括上次运行时未上链的交易单
+	if wm.IsEVM() {
+		wm.TxTracker.Stop()
+	} else {
+		wm.TxTracker.Run()
+	}
 	return nil
 }
 
@@ -81,7 +200,10 @@ func (wm *WalletManager) GetAssetsLogger() *log.OWLogger {
 	return wm.Log
 }
 
-//GetSmartContractDecoder 获取智能合约解析器
+//GetSmartContractDecoder 获取智能合约解析器，只有EVM后端支持代币合约
 func (wm *WalletManager) GetSmartContractDecoder() openwallet.SmartContractDecoder {
+	if wm.IsEVM() {
+		return wm.EVMContractDecoder
+	}
 	return nil
 }